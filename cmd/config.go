@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/Digni/ding-ding/internal/config"
 	"github.com/spf13/cobra"
@@ -48,8 +50,143 @@ var configPathCmd = &cobra.Command{
 	},
 }
 
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [path]",
+	Short: "Check the config file strictly and list every problem",
+	Long: `Check a config file and list every problem at once: YAML syntax errors,
+unknown fields (such as a misspelled key, which loading silently ignores),
+values of the wrong type, and every validation rule. Problems are printed as
+path:line: message. Without a path, the file ding-ding would load is checked,
+along with the project config (` + config.ProjectConfigName + `) found from the current
+directory. Project configs are also checked for sections and secret
+references they may not use.
+
+validate exits 1 when there are problems.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var paths []string
+		if len(args) == 1 {
+			paths = append(paths, args[0])
+		} else {
+			source, err := commandConfigSource()
+			if err != nil {
+				return err
+			}
+			if source.Path != "" {
+				paths = append(paths, source.Path)
+			}
+			cwd, _ := os.Getwd()
+			if path, ok := config.FindProjectConfig(cwd); ok {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "no config file found; the defaults are valid")
+			return nil
+		}
+
+		total := 0
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read config: %w", err)
+			}
+
+			check := config.Check
+			if filepath.Base(path) == config.ProjectConfigName {
+				check = config.CheckProject
+			}
+			problems := check(data)
+			if len(problems) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: ok\n", path)
+				continue
+			}
+			for _, problem := range problems {
+				if problem.Line > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s:%d: %s\n", path, problem.Line, problem.Message)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", path, problem.Message)
+				}
+			}
+			total += len(problems)
+		}
+		if total == 0 {
+			return nil
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%d problem(s) found\n", total)
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &notifyExitError{code: 1}
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective config with each value's source",
+	Long: `Print the config ding-ding runs with: the defaults merged with the config
+file, the project config (` + config.ProjectConfigName + `) and DING_DING_* environment
+overrides. Each value is commented with where it came from (default, file,
+project or env), and credentials such as tokens, passwords and webhook URLs
+are redacted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loadResult, err := loadConfigForCommand()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+
+		sources := map[string]string{}
+		for _, source := range loadResult.Sources {
+			if source.Path == "" {
+				fmt.Fprintln(cmd.OutOrStdout(), "# no config file: defaults only")
+				continue
+			}
+			data, err := os.ReadFile(source.Path)
+			if err != nil {
+				return fmt.Errorf("read config: %w", err)
+			}
+			fields, err := config.FileFields(data)
+			if err != nil {
+				return err
+			}
+			fieldSource := config.FieldFile
+			if source.Type == config.SourceProject {
+				fieldSource = config.FieldProject
+			}
+			for _, field := range fields {
+				sources[field] = fieldSource
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "# config file: %s (%s)\n", source.Path, source.Type)
+		}
+		for _, field := range loadResult.EnvFields {
+			sources[field] = config.FieldEnv
+		}
+		for _, field := range loadResult.DroppedFields {
+			sources[field] = config.FieldProject
+		}
+
+		out, err := config.Annotate(loadResult.Config, sources)
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(out)
+		return err
+	},
+}
+
+// commandConfigSource picks the config file the way loading does, without
+// parsing it.
+func commandConfigSource() (config.SourceSelection, error) {
+	if configPathOverride != "" {
+		return config.SourceSelection{Type: config.SourceExplicitFlag, Path: configPathOverride, Reason: "selected by --config flag"}, nil
+	}
+	return config.ResolveConfigSource(config.ResolveOptions{EnvPath: os.Getenv(config.EnvConfigPath)})
+}
+
 func init() {
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
 	rootCmd.AddCommand(configCmd)
 }
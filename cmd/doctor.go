@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+	"github.com/Digni/ding-ding/internal/focus"
+	"github.com/Digni/ding-ding/internal/idle"
+	"github.com/Digni/ding-ding/internal/notifier"
+	"github.com/spf13/cobra"
+)
+
+// doctorProbeTimeout bounds each probe so one hung tool or unreachable
+// backend cannot stall the whole report.
+var doctorProbeTimeout = 10 * time.Second
+
+var (
+	doctorJSON bool
+
+	doctorLoadConfig      = loadConfigForCommand
+	doctorFocusProviders  = focus.Providers
+	doctorIdleProviders   = idle.Providers
+	doctorSystemProviders = notifier.SystemProviders
+	doctorProbeBackends   = notifier.ProbeBackends
+)
+
+// doctorCheck is the outcome of one probe.
+type doctorCheck struct {
+	Name      string `json:"name"`
+	Note      string `json:"note,omitempty"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type doctorReport struct {
+	Platform      string        `json:"platform"`
+	Focus         []doctorCheck `json:"focus"`
+	Idle          []doctorCheck `json:"idle"`
+	Notifications []doctorCheck `json:"notifications"`
+	Backends      []doctorCheck `json:"backends"`
+	LogDir        doctorCheck   `json:"log_dir"`
+	Server        doctorCheck   `json:"server"`
+}
+
+// healthy reports whether detection and local notifications each have a
+// working provider, every enabled backend answered and logs can be
+// written. The server is informational: the CLI works without it.
+func (r doctorReport) healthy() bool {
+	for _, section := range [][]doctorCheck{r.Focus, r.Idle, r.Notifications} {
+		if len(section) > 0 && !anyCheckOK(section) {
+			return false
+		}
+	}
+	for _, check := range r.Backends {
+		if !check.OK {
+			return false
+		}
+	}
+	return r.LogDir.OK
+}
+
+func anyCheckOK(checks []doctorCheck) bool {
+	for _, check := range checks {
+		if check.OK {
+			return true
+		}
+	}
+	return false
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose detection providers, backends, logging and the server",
+	Long: `Probe everything ding-ding depends on and report what works.
+
+Each focus and idle detection provider for this platform (xdotool, kdotool,
+gdbus, xprintidle, osascript, tmux, zellij, ...) and each local notification
+provider is queried once and reported with its answer and latency. Enabled
+push backends are checked for reachability and credentials without sending a
+notification. The log directory is checked for write access, and the server
+is asked for /health on server.address.
+
+doctor exits 1 when a detection or notification category has no working
+provider, an enabled backend fails, or the log directory is not writable. A
+server that does not answer is reported but is not a failure.
+  ding-ding doctor
+  ding-ding doctor --json`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	loadResult, err := doctorLoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	printConfigLoadDetails(cmd, loadResult)
+	cfg := loadResult.Config
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := runDoctorChecks(ctx, cfg)
+	if doctorJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+	} else {
+		writeDoctorReport(cmd.OutOrStdout(), report)
+	}
+
+	if !report.healthy() {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &notifyExitError{code: 1}
+	}
+	return nil
+}
+
+func runDoctorChecks(ctx context.Context, cfg config.Config) doctorReport {
+	report := doctorReport{
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		Focus:         []doctorCheck{},
+		Idle:          []doctorCheck{},
+		Notifications: []doctorCheck{},
+		Backends:      []doctorCheck{},
+	}
+
+	for _, provider := range doctorFocusProviders() {
+		report.Focus = append(report.Focus, runDoctorProbe(ctx, provider.Name, provider.Note, provider.Probe))
+	}
+	for _, provider := range doctorIdleProviders() {
+		probe := func(ctx context.Context) (string, error) {
+			d, err := provider.Probe(ctx)
+			if err != nil {
+				return "", err
+			}
+			return "idle " + d.Round(time.Second).String(), nil
+		}
+		report.Idle = append(report.Idle, runDoctorProbe(ctx, provider.Name, provider.Note, probe))
+	}
+	for _, provider := range doctorSystemProviders() {
+		report.Notifications = append(report.Notifications, runDoctorProbe(ctx, provider.Name, provider.Note, provider.Probe))
+	}
+
+	backendCtx, cancel := context.WithTimeout(ctx, doctorProbeTimeout)
+	for _, result := range doctorProbeBackends(backendCtx, cfg) {
+		report.Backends = append(report.Backends, doctorCheck{
+			Name:      result.Backend,
+			OK:        result.OK,
+			LatencyMs: result.LatencyMs,
+			Error:     result.Error,
+		})
+	}
+	cancel()
+
+	report.LogDir = runDoctorProbe(ctx, "log dir", "", func(context.Context) (string, error) {
+		return checkLogDir(cfg.Logging)
+	})
+	report.Server = runDoctorProbe(ctx, "server", "", func(ctx context.Context) (string, error) {
+		return checkServer(ctx, cfg.Server.Address)
+	})
+
+	return report
+}
+
+func runDoctorProbe(ctx context.Context, name, note string, probe func(context.Context) (string, error)) doctorCheck {
+	ctx, cancel := context.WithTimeout(ctx, doctorProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := probe(ctx)
+	check := doctorCheck{
+		Name:      name,
+		Note:      note,
+		OK:        err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Detail:    detail,
+	}
+	if err != nil {
+		check.Error = doctorErrorText(err)
+	}
+	return check
+}
+
+// doctorErrorText adds the first line of a failed command's stderr, which
+// says far more than "exit status 1".
+func doctorErrorText(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n"); line != "" {
+			return err.Error() + ": " + line
+		}
+	}
+	return err.Error()
+}
+
+// checkLogDir creates and removes a file in the log directory, the same
+// way the file logger would first touch it.
+func checkLogDir(cfg config.LoggingConfig) (string, error) {
+	if !cfg.Enabled {
+		return "logging disabled", nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("create %s: %w", cfg.Dir, err)
+	}
+	f, err := os.CreateTemp(cfg.Dir, ".doctor-*")
+	if err != nil {
+		return "", fmt.Errorf("%s is not writable: %w", cfg.Dir, err)
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return "", fmt.Errorf("clean up %s: %w", f.Name(), err)
+	}
+	return cfg.Dir, nil
+}
+
+// checkServer asks a running server for /health. A wildcard listen address
+// is reached through loopback.
+func checkServer(ctx context.Context, address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid server.address %q: %w", address, err)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	url := "http://" + net.JoinHostPort(host, port) + "/health"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no answer from %s: %w", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return "answering on " + url, nil
+}
+
+func writeDoctorReport(w io.Writer, report doctorReport) {
+	fmt.Fprintf(w, "platform: %s\n", report.Platform)
+	writeDoctorSection(w, "focus detection", report.Focus, "no providers on this platform")
+	writeDoctorSection(w, "idle detection", report.Idle, "no providers on this platform")
+	writeDoctorSection(w, "local notifications", report.Notifications, "no providers on this platform")
+	writeDoctorSection(w, "push backends", report.Backends, "none enabled")
+	writeDoctorSection(w, "logging", []doctorCheck{report.LogDir}, "")
+	writeDoctorSection(w, "server", []doctorCheck{report.Server}, "")
+}
+
+func writeDoctorSection(w io.Writer, title string, checks []doctorCheck, empty string) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	if len(checks) == 0 {
+		fmt.Fprintf(w, "  %s\n", empty)
+		return
+	}
+	for _, check := range checks {
+		status, detail := "ok", check.Detail
+		if !check.OK {
+			status, detail = "FAIL", check.Error
+		}
+		// Joined errors span lines; keep each check on one.
+		detail = strings.ReplaceAll(detail, "\n", "; ")
+		if check.Note != "" {
+			detail += " (" + check.Note + ")"
+		}
+		fmt.Fprintf(w, "  %-4s  %-12s %6dms  %s\n", status, check.Name, check.LatencyMs, detail)
+	}
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
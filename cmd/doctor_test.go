@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+	"github.com/Digni/ding-ding/internal/focus"
+	"github.com/Digni/ding-ding/internal/idle"
+	"github.com/Digni/ding-ding/internal/notifier"
+	"github.com/spf13/cobra"
+)
+
+// stubDoctor replaces every doctor probe; the config points the server
+// check at a live /health endpoint and logging at a temp dir.
+func stubDoctor(t *testing.T, backends []notifier.BackendResult) {
+	t.Helper()
+	origLoadConfig := doctorLoadConfig
+	origFocus := doctorFocusProviders
+	origIdle := doctorIdleProviders
+	origSystem := doctorSystemProviders
+	origBackends := doctorProbeBackends
+	t.Cleanup(func() {
+		doctorLoadConfig = origLoadConfig
+		doctorFocusProviders = origFocus
+		doctorIdleProviders = origIdle
+		doctorSystemProviders = origSystem
+		doctorProbeBackends = origBackends
+		doctorJSON = false
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.Server.Address = strings.TrimPrefix(srv.URL, "http://")
+	cfg.Logging.Enabled = true
+	cfg.Logging.Dir = t.TempDir()
+	doctorLoadConfig = func() (config.LoadResult, error) {
+		return config.LoadResult{Config: cfg}, nil
+	}
+
+	doctorFocusProviders = func() []focus.Provider {
+		return []focus.Provider{
+			{Name: "xdotool", Note: "X11", Probe: func(context.Context) (string, error) {
+				return "", errors.New(`exec: "xdotool": executable file not found in $PATH`)
+			}},
+			{Name: "gdbus", Note: "GNOME Wayland", Probe: func(context.Context) (string, error) {
+				return "focused window pid 42", nil
+			}},
+		}
+	}
+	doctorIdleProviders = func() []idle.Provider {
+		return []idle.Provider{{Name: "xprintidle", Probe: func(context.Context) (time.Duration, error) {
+			return 90 * time.Second, nil
+		}}}
+	}
+	doctorSystemProviders = func() []notifier.SystemProvider {
+		return []notifier.SystemProvider{{Name: "dbus", Probe: func(context.Context) (string, error) {
+			return "mako 1.8 (emersion)", nil
+		}}}
+	}
+	doctorProbeBackends = func(context.Context, config.Config) []notifier.BackendResult {
+		return backends
+	}
+}
+
+func TestDoctor_ReportsEveryProvider(t *testing.T) {
+	stubDoctor(t, []notifier.BackendResult{{Backend: "ntfy", OK: true, Attempts: 1, LatencyMs: 12}})
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := runDoctor(cmd, nil); err != nil {
+		t.Fatalf("runDoctor returned error: %v\n%s", err, out.String())
+	}
+
+	for _, want := range []string{
+		"focus detection:",
+		`FAIL  xdotool`, `executable file not found in $PATH (X11)`,
+		"ok    gdbus", "focused window pid 42 (GNOME Wayland)",
+		"ok    xprintidle", "idle 1m30s",
+		"ok    dbus", "mako 1.8",
+		"ok    ntfy",
+		"ok    log dir",
+		"ok    server", "/health",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDoctor_FailingBackendExitsNonZero(t *testing.T) {
+	stubDoctor(t, []notifier.BackendResult{{Backend: "webhook", Error: "webhook returned status 401"}})
+	doctorJSON = true
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	err := runDoctor(cmd, nil)
+	var exitErr *notifyExitError
+	if !errors.As(err, &exitErr) || exitErr.code != 1 {
+		t.Fatalf("runDoctor error = %v, want exit status 1", err)
+	}
+
+	var report doctorReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("stdout is not a JSON report: %q (%v)", out.String(), err)
+	}
+	if len(report.Backends) != 1 || report.Backends[0].OK || !strings.Contains(report.Backends[0].Error, "401") {
+		t.Errorf("unexpected backends: %+v", report.Backends)
+	}
+	if len(report.Focus) != 2 || report.Focus[0].OK || !report.Focus[1].OK {
+		t.Errorf("unexpected focus checks: %+v", report.Focus)
+	}
+	if !report.LogDir.OK || !report.Server.OK {
+		t.Errorf("expected log dir and server ok, got %+v / %+v", report.LogDir, report.Server)
+	}
+}
+
+func TestCheckServer_WildcardAddressUsesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	_, port, _ := strings.Cut(strings.TrimPrefix(srv.URL, "http://"), ":")
+
+	detail, err := checkServer(context.Background(), "0.0.0.0:"+port)
+	if err != nil {
+		t.Fatalf("checkServer returned error: %v", err)
+	}
+	if !strings.Contains(detail, "127.0.0.1:"+port) {
+		t.Errorf("detail = %q, want loopback address", detail)
+	}
+}
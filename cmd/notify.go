@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Digni/ding-ding/internal/config"
 	"github.com/Digni/ding-ding/internal/logging"
 	"github.com/Digni/ding-ding/internal/notifier"
 	"github.com/spf13/cobra"
@@ -23,16 +31,96 @@ func (e *notifyDeliveryError) Unwrap() error {
 	return e.err
 }
 
+// Exit codes for `notify --wait-action`. The first action exits 0 so a hook
+// can test it with a plain `if`; the others count up from
+// waitActionExitCodeBase (second action 10, third 11, ...), past the codes of
+// ordinary failures. The rest are for when no action was chosen, with an
+// interrupted wait following the shell's 128+SIGINT convention.
+const (
+	waitActionExitCodeBase        = 10
+	waitActionTimeoutExitCode     = 124
+	waitActionDismissedExitCode   = 125
+	waitActionUnavailableExitCode = 126
+	waitActionInterruptedExitCode = 130
+)
+
+// notifyExitError ends the process with code, printing err (if any) to
+// stderr, without cobra's usage output.
+type notifyExitError struct {
+	code int
+	err  error
+}
+
+func (e *notifyExitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *notifyExitError) Unwrap() error {
+	return e.err
+}
+
 var (
 	notifyTitle   string
 	notifyMessage string
 	notifyAgent   string
+	notifySession string
+	notifyEvent   string
+	notifyExit    int
+	notifyElapsed time.Duration
+	notifyPrio    string
+	notifyURL     string
+	notifyActions []string
+	notifyWait    string
+	notifyWaitFor time.Duration
+	notifyNtfy    ntfyFlags
+	notifyJSON    bool
+	notifyDryRun  bool
+	notifyExplain bool
 	forcePush     bool
 	testLocal     bool
 )
 
-var notifyWithOptions = notifier.NotifyWithOptions
+// ntfyFlags hold the per-message ntfy options of the notify command.
+type ntfyFlags struct {
+	tags     []string
+	markdown bool
+	icon     string
+	attach   string
+	filename string
+	email    string
+	delay    string
+}
+
+// options returns the ntfy options for the message, or nil when no ntfy
+// flag was given so the config defaults apply unchanged.
+func (f ntfyFlags) options(cmd *cobra.Command) *notifier.NtfyOptions {
+	opts := &notifier.NtfyOptions{
+		Tags:     f.tags,
+		Icon:     f.icon,
+		Filename: f.filename,
+		Email:    f.email,
+		Delay:    f.delay,
+	}
+	if f.attach != "" {
+		opts.Attach, opts.File = notifier.ParseNtfyAttachment(f.attach)
+	}
+	if cmd.Flags().Changed("ntfy-markdown") {
+		markdown := f.markdown
+		opts.Markdown = &markdown
+	}
+	if len(opts.Tags) == 0 && opts.Markdown == nil && opts.Icon == "" && opts.Attach == "" &&
+		opts.File == "" && opts.Filename == "" && opts.Email == "" && opts.Delay == "" {
+		return nil
+	}
+	return opts
+}
+
+var notifyWithOptions = notifier.NotifyWithOptionsContext
 var notifyLoadConfig = loadConfigForCommand
+var notifyWaitForAction = notifier.WaitForAction
 
 var notifyCmd = &cobra.Command{
 	Use:   "notify [message]",
@@ -47,25 +135,92 @@ The message can be passed as a flag or as positional arguments:
 By default, focused terminals are quiet, active unfocused sends a system
 notification, and idle sends system + push notifications.
 
-Use --push to force remote push (ntfy/Discord/webhook) regardless of
+Use --push to force remote push (ntfy/Discord/webhook/email/Teams/MQTT) regardless of
 focus/idle, and --test-local to force a local/system notification even
-when focus suppression would normally silence it.`,
+when focus suppression would normally silence it.
+
+Use --wait-action to ask a question instead: the desktop notification gets one
+button per action and notify blocks until one is clicked, printing the chosen
+action. The first action exits 0 and the others 10, 11, ... in order
+(allow,deny: allow 0, deny 10), so they never collide with the exit code 1 of
+an ordinary failure. A timeout exits 124, dismissing the notification 125, a
+desktop without interactive notifications 126, and an interrupted wait 130:
+  ding-ding notify -m "Allow rm -rf build/?" --wait-action allow,deny
+
+Use --json to print the delivery report: the routing tier, idle and focus
+state, whether the local notification fired, and each push backend's result
+with attempts, latency and error.
+
+Use --dry-run --explain to find out why a notification did or did not arrive:
+idle and focus detection and every routing rule run as usual, and the tier,
+the channels that would fire, and the reasons are printed. Nothing is sent.
+  ding-ding notify --dry-run --explain -m "test"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if hasMistypedTestLocalArg(os.Args[1:]) {
 			return fmt.Errorf("invalid flag -test-local; use --test-local")
 		}
 
+		event, err := notifier.ParseEvent(notifyEvent)
+		if err != nil {
+			return err
+		}
+		priority, err := notifier.ParsePriority(notifyPrio)
+		if err != nil {
+			return err
+		}
+		var actions []notifier.Action
+		for _, value := range notifyActions {
+			action, err := notifier.ParseAction(value)
+			if err != nil {
+				return err
+			}
+			actions = append(actions, action)
+		}
+		if err := notifier.ValidateLinks(notifier.Message{URL: notifyURL, Actions: actions}); err != nil {
+			return err
+		}
+		ntfyOptions := notifyNtfy.options(cmd)
+		if err := notifier.ValidateNtfyOptions(ntfyOptions); err != nil {
+			return err
+		}
+		var waitActions []string
+		if notifyWait != "" {
+			waitActions, err = notifier.ParseWaitActions(notifyWait)
+			if err != nil {
+				return err
+			}
+			if notifyWaitFor <= 0 {
+				return fmt.Errorf("--wait-timeout must be positive")
+			}
+			if notifyJSON || notifyDryRun {
+				return fmt.Errorf("--json and --dry-run cannot be combined with --wait-action")
+			}
+		}
+
 		loadResult, err := notifyLoadConfig()
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
-		printConfigSourceDetails(cmd, loadResult.Source)
+		printConfigLoadDetails(cmd, loadResult)
 		cfg := loadResult.Config
 		initializeCommandLogging(cmd.ErrOrStderr(), cfg.Logging, logging.RoleCLI)
 
 		msg := notifier.Message{
-			Title: notifyTitle,
-			Agent: notifyAgent,
+			Title:    notifyTitle,
+			Agent:    notifyAgent,
+			Session:  notifySession,
+			Event:    event,
+			Priority: priority,
+			URL:      notifyURL,
+			Actions:  actions,
+			Ntfy:     ntfyOptions,
+		}
+		if cmd.Flags().Changed("exit-code") {
+			exitCode := notifyExit
+			msg.ExitCode = &exitCode
+		}
+		if notifyElapsed > 0 {
+			msg.DurationMs = notifyElapsed.Milliseconds()
 		}
 
 		// Message priority: -m flag > positional args > stdin
@@ -89,10 +244,32 @@ when focus suppression would normally silence it.`,
 			msg.Body = "Agent task completed"
 		}
 
-		err = notifyWithOptions(cfg, msg, notifier.NotifyOptions{
+		cwd, _ := os.Getwd()
+		msg = notifier.WithContext(msg, cwd)
+
+		// Ctrl-C or SIGTERM abandons idle detection and in-flight pushes.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if waitActions != nil {
+			return runWaitAction(ctx, cmd, cfg, msg, waitActions)
+		}
+
+		report, err := notifyWithOptions(ctx, cfg, msg, notifier.NotifyOptions{
 			ForcePush:  forcePush,
 			ForceLocal: testLocal,
+			DryRun:     notifyDryRun,
 		})
+		switch {
+		case notifyJSON:
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			if encErr := enc.Encode(report); encErr != nil {
+				return fmt.Errorf("write report: %w", encErr)
+			}
+		case notifyDryRun || notifyExplain:
+			writeDeliveryReport(cmd.OutOrStdout(), report, notifyExplain)
+		}
 		if err != nil {
 			return &notifyDeliveryError{err: err}
 		}
@@ -101,10 +278,113 @@ when focus suppression would normally silence it.`,
 	},
 }
 
+// writeDeliveryReport prints a report for humans: the tier and what each
+// channel did, or would do in a dry run, and with explain the reasons.
+func writeDeliveryReport(w io.Writer, report notifier.DeliveryReport, explain bool) {
+	if report.Plan != nil {
+		fmt.Fprintln(w, "dry run: nothing was sent")
+	}
+	tier := report.Tier
+	if report.Suppressed {
+		tier += " (suppressed)"
+	}
+	fmt.Fprintf(w, "tier: %s\n", tier)
+
+	switch {
+	case report.Plan != nil:
+		local := "no"
+		if report.Plan.Local {
+			local = "would send"
+		}
+		backends := "none"
+		if len(report.Plan.Backends) > 0 {
+			backends = strings.Join(report.Plan.Backends, ", ")
+		}
+		fmt.Fprintf(w, "local notification: %s\n", local)
+		fmt.Fprintf(w, "push backends: %s\n", backends)
+	default:
+		local := "not sent"
+		switch {
+		case report.LocalSent:
+			local = "sent"
+		case report.LocalError != "":
+			local = "failed: " + report.LocalError
+		}
+		fmt.Fprintf(w, "local notification: %s\n", local)
+		if len(report.Backends) == 0 {
+			fmt.Fprintln(w, "push backends: none")
+		}
+		for _, backend := range report.Backends {
+			result := "ok"
+			if !backend.OK {
+				result = "failed: " + backend.Error
+			}
+			fmt.Fprintf(w, "push %s: %s (%d attempt(s), %dms)\n", backend.Backend, result, backend.Attempts, backend.LatencyMs)
+		}
+	}
+
+	if explain {
+		fmt.Fprintln(w, "why:")
+		for _, reason := range report.Explain {
+			fmt.Fprintf(w, "  - %s\n", reason)
+		}
+	}
+}
+
+// runWaitAction shows an interactive notification and reports the answer on
+// stdout and through the exit code, for hook scripts to branch on.
+func runWaitAction(ctx context.Context, cmd *cobra.Command, cfg config.Config, msg notifier.Message, actions []string) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyWaitFor)
+	defer cancel()
+
+	action, err := notifyWaitForAction(ctx, cfg, msg, actions)
+	if err != nil {
+		exitErr := &notifyExitError{code: waitActionUnavailableExitCode, err: err}
+		switch {
+		case errors.Is(err, notifier.ErrActionTimeout):
+			exitErr = &notifyExitError{code: waitActionTimeoutExitCode}
+		case errors.Is(err, notifier.ErrActionDismissed):
+			exitErr = &notifyExitError{code: waitActionDismissedExitCode}
+		case errors.Is(err, context.Canceled):
+			exitErr = &notifyExitError{code: waitActionInterruptedExitCode}
+		}
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return exitErr
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), action)
+	if index := slices.Index(actions, action); index > 0 {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &notifyExitError{code: waitActionExitCodeBase + index - 1}
+	}
+	return nil
+}
+
 func init() {
 	notifyCmd.Flags().StringVarP(&notifyTitle, "title", "t", "ding ding!", "Notification title")
 	notifyCmd.Flags().StringVarP(&notifyMessage, "message", "m", "", "Notification message")
 	notifyCmd.Flags().StringVarP(&notifyAgent, "agent", "a", "", "Agent name (e.g. claude, opencode)")
+	notifyCmd.Flags().StringVar(&notifySession, "session", "", "Agent session key; later notifications with the same agent and session replace earlier ones")
+	notifyCmd.Flags().StringVarP(&notifyEvent, "event", "e", "", "Event type: completed, needs_input, error, progress, started (default from --exit-code: error if non-zero, completed if zero; otherwise none)")
+	notifyCmd.Flags().IntVar(&notifyExit, "exit-code", 0, "Exit status of the agent task")
+	notifyCmd.Flags().DurationVar(&notifyElapsed, "duration", 0, "How long the agent task ran (e.g. 3m12s)")
+	notifyCmd.Flags().StringVar(&notifyPrio, "priority", "", "Message priority: low, normal, high, urgent (mapped per backend; default follows backend config and event)")
+	notifyCmd.Flags().StringVar(&notifyURL, "url", "", "Link opened when the notification is clicked (http or https)")
+	notifyCmd.Flags().StringArrayVar(&notifyActions, "action", nil, "Action button as Label=URL (repeatable, up to 5)")
+	notifyCmd.Flags().StringSliceVar(&notifyNtfy.tags, "ntfy-tags", nil, "Extra ntfy tags or emoji shortcodes (comma-separated)")
+	notifyCmd.Flags().BoolVar(&notifyNtfy.markdown, "ntfy-markdown", false, "Render the ntfy message as Markdown (overrides ntfy.markdown)")
+	notifyCmd.Flags().StringVar(&notifyNtfy.icon, "ntfy-icon", "", "ntfy notification icon URL")
+	notifyCmd.Flags().StringVar(&notifyNtfy.attach, "ntfy-attach", "", "ntfy attachment: an http(s) URL, or a local file to upload")
+	notifyCmd.Flags().StringVar(&notifyNtfy.filename, "ntfy-filename", "", "Attachment name shown by ntfy")
+	notifyCmd.Flags().StringVar(&notifyNtfy.email, "ntfy-email", "", "Also forward the ntfy message to this email address")
+	notifyCmd.Flags().StringVar(&notifyNtfy.delay, "ntfy-delay", "", "Schedule ntfy delivery (e.g. 30m, \"tomorrow, 10am\")")
+	notifyCmd.Flags().StringVar(&notifyWait, "wait-action", "", "Comma-separated actions to offer as buttons (e.g. allow,deny); block until one is chosen. Exits 0 for the first action and 10, 11, ... for the others (deny: 10), 124 on timeout, 125 if dismissed, 126 if unsupported, 130 if interrupted")
+	notifyCmd.Flags().DurationVar(&notifyWaitFor, "wait-timeout", 5*time.Minute, "How long --wait-action waits for an answer")
+	notifyCmd.Flags().BoolVar(&notifyJSON, "json", false, "Print the delivery report (routing tier and per-backend results) as JSON")
+	notifyCmd.Flags().BoolVar(&notifyDryRun, "dry-run", false, "Run idle/focus detection and routing, print what would be sent, and send nothing")
+	notifyCmd.Flags().BoolVar(&notifyExplain, "explain", false, "Print why each routing decision was made")
 	notifyCmd.Flags().BoolVarP(&forcePush, "push", "p", false, "Always send push notifications (ignore idle/focus for remote backends)")
 	notifyCmd.Flags().BoolVar(&testLocal, "test-local", false, "Always send a local/system notification (ignore focused suppression)")
 
@@ -125,7 +405,8 @@ func hasMistypedTestLocalArg(argv []string) bool {
 		}
 
 		switch arg {
-		case "-m", "--message", "-t", "--title", "-a", "--agent":
+		case "-m", "--message", "-t", "--title", "-a", "--agent", "--session", "-e", "--event", "--exit-code", "--duration", "--priority", "--url", "--action", "--wait-action", "--wait-timeout",
+			"--ntfy-tags", "--ntfy-icon", "--ntfy-attach", "--ntfy-filename", "--ntfy-email", "--ntfy-delay":
 			expectsValue = true
 			continue
 		}
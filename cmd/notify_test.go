@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -110,12 +115,12 @@ func TestNotifyRunE_InitializesLoggingBeforeDispatch(t *testing.T) {
 		callOrder = append(callOrder, "bootstrap")
 		return nil
 	}
-	notifyWithOptions = func(cfg config.Config, msg notifier.Message, opts notifier.NotifyOptions) error {
+	notifyWithOptions = func(_ context.Context, cfg config.Config, msg notifier.Message, opts notifier.NotifyOptions) (notifier.DeliveryReport, error) {
 		callOrder = append(callOrder, "notify")
 		if msg.Body != "hello world" {
 			t.Fatalf("msg.Body = %q, want %q", msg.Body, "hello world")
 		}
-		return nil
+		return notifier.DeliveryReport{}, nil
 	}
 
 	origArgs := os.Args
@@ -160,9 +165,9 @@ func TestNotifyRunE_ContinuesWhenLoggingBootstrapFails(t *testing.T) {
 	}
 
 	notifyCalled := false
-	notifyWithOptions = func(_ config.Config, _ notifier.Message, _ notifier.NotifyOptions) error {
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
 		notifyCalled = true
-		return nil
+		return notifier.DeliveryReport{}, nil
 	}
 
 	origArgs := os.Args
@@ -206,9 +211,9 @@ func TestNotifyRunE_DisabledLoggingSuppressesStructuredOutput(t *testing.T) {
 		return config.LoadResult{Config: cfg}, nil
 	}
 
-	notifyWithOptions = func(_ config.Config, _ notifier.Message, _ notifier.NotifyOptions) error {
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
 		slog.Default().Info("probe")
-		return nil
+		return notifier.DeliveryReport{}, nil
 	}
 
 	origArgs := os.Args
@@ -227,3 +232,284 @@ func TestNotifyRunE_DisabledLoggingSuppressesStructuredOutput(t *testing.T) {
 		t.Fatalf("expected no structured log output when logging is disabled, got %q", got)
 	}
 }
+
+func TestNotifyRunE_RejectsInvalidEventBeforeDispatch(t *testing.T) {
+	origNotifyWithOptions := notifyWithOptions
+	origNotifyLoadConfig := notifyLoadConfig
+	defer func() {
+		notifyWithOptions = origNotifyWithOptions
+		notifyLoadConfig = origNotifyLoadConfig
+		notifyEvent = ""
+	}()
+
+	notifyEvent = "finished"
+	notifyLoadConfig = func() (config.LoadResult, error) {
+		t.Fatal("config should not be loaded for an invalid event")
+		return config.LoadResult{}, nil
+	}
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
+		t.Fatal("notification should not be dispatched for an invalid event")
+		return notifier.DeliveryReport{}, nil
+	}
+
+	origArgs := os.Args
+	os.Args = []string{"ding-ding", "notify"}
+	defer func() { os.Args = origArgs }()
+
+	err := notifyCmd.RunE(&cobra.Command{}, []string{"hello"})
+	if err == nil || !strings.Contains(err.Error(), `invalid event "finished"`) {
+		t.Fatalf("RunE error = %v, want invalid event error", err)
+	}
+	if isBestEffortNotifyError(err) {
+		t.Fatal("invalid event must not be treated as a best-effort delivery error")
+	}
+}
+
+func TestNotifyRunE_RejectsNonHTTPURLBeforeDispatch(t *testing.T) {
+	origNotifyWithOptions := notifyWithOptions
+	origNotifyLoadConfig := notifyLoadConfig
+	defer func() {
+		notifyWithOptions = origNotifyWithOptions
+		notifyLoadConfig = origNotifyLoadConfig
+		notifyURL = ""
+	}()
+
+	notifyURL = "file:///etc/passwd"
+	notifyLoadConfig = func() (config.LoadResult, error) {
+		t.Fatal("config should not be loaded for an invalid url")
+		return config.LoadResult{}, nil
+	}
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
+		t.Fatal("notification should not be dispatched for an invalid url")
+		return notifier.DeliveryReport{}, nil
+	}
+
+	origArgs := os.Args
+	os.Args = []string{"ding-ding", "notify"}
+	defer func() { os.Args = origArgs }()
+
+	err := notifyCmd.RunE(&cobra.Command{}, []string{"hello"})
+	if err == nil || !strings.Contains(err.Error(), "invalid url") {
+		t.Fatalf("RunE error = %v, want invalid url error", err)
+	}
+}
+
+func TestNotifyRunE_NtfyFlagsReachMessage(t *testing.T) {
+	origNotifyWithOptions := notifyWithOptions
+	origNotifyLoadConfig := notifyLoadConfig
+	defer func() {
+		notifyWithOptions = origNotifyWithOptions
+		notifyLoadConfig = origNotifyLoadConfig
+		notifyNtfy = ntfyFlags{}
+	}()
+
+	notifyNtfy = ntfyFlags{tags: []string{"warning"}, attach: "./build.log", delay: "30m"}
+	notifyLoadConfig = func() (config.LoadResult, error) {
+		return config.LoadResult{Config: config.DefaultConfig()}, nil
+	}
+	var got *notifier.NtfyOptions
+	notifyWithOptions = func(_ context.Context, _ config.Config, msg notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
+		got = msg.Ntfy
+		return notifier.DeliveryReport{}, nil
+	}
+
+	origArgs := os.Args
+	os.Args = []string{"ding-ding", "notify"}
+	defer func() { os.Args = origArgs }()
+
+	if err := notifyCmd.RunE(&cobra.Command{}, []string{"hello"}); err != nil {
+		t.Fatalf("RunE returned error: %v", err)
+	}
+	if got == nil || got.File != "./build.log" || got.Attach != "" || got.Delay != "30m" || len(got.Tags) != 1 {
+		t.Fatalf("msg.Ntfy = %+v, want file upload with tag and delay", got)
+	}
+	if got.Markdown != nil {
+		t.Fatalf("msg.Ntfy.Markdown = %v, want nil when --ntfy-markdown is not given", *got.Markdown)
+	}
+
+	notifyNtfy = ntfyFlags{icon: "javascript:alert(1)"}
+	err := notifyCmd.RunE(&cobra.Command{}, []string{"hello"})
+	if err == nil || !strings.Contains(err.Error(), "invalid ntfy icon") {
+		t.Fatalf("RunE error = %v, want invalid ntfy icon error", err)
+	}
+}
+
+func TestNotifyRunE_WaitActionReportsChoice(t *testing.T) {
+	origNotifyWithOptions := notifyWithOptions
+	origNotifyLoadConfig := notifyLoadConfig
+	origWaitForAction := notifyWaitForAction
+	defer func() {
+		notifyWithOptions = origNotifyWithOptions
+		notifyLoadConfig = origNotifyLoadConfig
+		notifyWaitForAction = origWaitForAction
+		notifyWait = ""
+	}()
+
+	notifyWait = "allow, deny, ask"
+	notifyLoadConfig = func() (config.LoadResult, error) {
+		cfg := config.DefaultConfig()
+		cfg.Logging.Enabled = false
+		return config.LoadResult{Config: cfg}, nil
+	}
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
+		t.Fatal("--wait-action must not go through normal routing")
+		return notifier.DeliveryReport{}, nil
+	}
+
+	origArgs := os.Args
+	os.Args = []string{"ding-ding", "notify"}
+	defer func() { os.Args = origArgs }()
+
+	tests := []struct {
+		name       string
+		action     string
+		err        error
+		wantStdout string
+		wantCode   int
+	}{
+		{name: "first action", action: "allow", wantStdout: "allow\n", wantCode: 0},
+		{name: "second action", action: "deny", wantStdout: "deny\n", wantCode: 10},
+		{name: "third action", action: "ask", wantStdout: "ask\n", wantCode: 11},
+		{name: "timeout", err: notifier.ErrActionTimeout, wantCode: waitActionTimeoutExitCode},
+		{name: "dismissed", err: notifier.ErrActionDismissed, wantCode: waitActionDismissedExitCode},
+		{name: "unavailable", err: notifier.ErrWaitActionUnsupported, wantCode: waitActionUnavailableExitCode},
+		{name: "interrupted", err: context.Canceled, wantCode: waitActionInterruptedExitCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotActions []string
+			notifyWaitForAction = func(_ context.Context, _ config.Config, _ notifier.Message, actions []string) (string, error) {
+				gotActions = actions
+				return tt.action, tt.err
+			}
+
+			var stdout bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&stdout)
+			cmd.SetErr(io.Discard)
+
+			err := notifyCmd.RunE(cmd, []string{"Allow rm?"})
+			code := 0
+			var exitErr *notifyExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.code
+			} else if err != nil {
+				t.Fatalf("RunE error = %v, want nil or exit error", err)
+			}
+
+			if strings.Join(gotActions, ",") != "allow,deny,ask" {
+				t.Errorf("actions = %v, want [allow deny ask]", gotActions)
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestNotifyHelp_WaitActionExitCodesMatchCode(t *testing.T) {
+	// allow,deny: deny is the second action, the first with a non-zero code.
+	deny := fmt.Sprintf("deny %d", waitActionExitCodeBase)
+	if !strings.Contains(notifyCmd.Long, "allow 0, "+deny) {
+		t.Errorf("notify help does not say %q:\n%s", deny, notifyCmd.Long)
+	}
+	usage := notifyCmd.Flags().Lookup("wait-action").Usage
+	if !strings.Contains(usage, fmt.Sprintf("(deny: %d)", waitActionExitCodeBase)) {
+		t.Errorf("--wait-action help = %q, want deny exit code %d", usage, waitActionExitCodeBase)
+	}
+	for _, code := range []int{waitActionTimeoutExitCode, waitActionDismissedExitCode, waitActionUnavailableExitCode, waitActionInterruptedExitCode} {
+		if !strings.Contains(notifyCmd.Long, strconv.Itoa(code)) || !strings.Contains(usage, strconv.Itoa(code)) {
+			t.Errorf("help text does not mention exit code %d", code)
+		}
+	}
+}
+
+func TestNotifyRunE_JSONPrintsReportOnFailure(t *testing.T) {
+	origNotifyWithOptions := notifyWithOptions
+	origNotifyLoadConfig := notifyLoadConfig
+	defer func() {
+		notifyWithOptions = origNotifyWithOptions
+		notifyLoadConfig = origNotifyLoadConfig
+		notifyJSON = false
+	}()
+
+	notifyJSON = true
+	notifyLoadConfig = func() (config.LoadResult, error) {
+		return config.LoadResult{Config: config.DefaultConfig()}, nil
+	}
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, _ notifier.NotifyOptions) (notifier.DeliveryReport, error) {
+		return notifier.DeliveryReport{
+			OperationID: "op-1",
+			Tier:        "idle",
+			Backends:    []notifier.BackendResult{{Backend: "ntfy", Attempts: 1, Error: "status 500"}},
+		}, errors.New("ntfy: status 500")
+	}
+
+	origArgs := os.Args
+	os.Args = []string{"ding-ding", "notify"}
+	defer func() { os.Args = origArgs }()
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	err := notifyCmd.RunE(cmd, []string{"hello"})
+	var deliveryErr *notifyDeliveryError
+	if !errors.As(err, &deliveryErr) {
+		t.Fatalf("RunE error = %v, want delivery error", err)
+	}
+
+	var report notifier.DeliveryReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("stdout is not a JSON report: %q (%v)", out.String(), err)
+	}
+	if report.OperationID != "op-1" || len(report.Backends) != 1 || report.Backends[0].Error != "status 500" {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestNotifyRunE_DryRunExplainPrintsPlan(t *testing.T) {
+	origNotifyWithOptions := notifyWithOptions
+	origNotifyLoadConfig := notifyLoadConfig
+	defer func() {
+		notifyWithOptions = origNotifyWithOptions
+		notifyLoadConfig = origNotifyLoadConfig
+		notifyDryRun = false
+		notifyExplain = false
+	}()
+
+	notifyDryRun = true
+	notifyExplain = true
+	notifyLoadConfig = func() (config.LoadResult, error) {
+		return config.LoadResult{Config: config.DefaultConfig()}, nil
+	}
+	notifyWithOptions = func(_ context.Context, _ config.Config, _ notifier.Message, opts notifier.NotifyOptions) (notifier.DeliveryReport, error) {
+		if !opts.DryRun {
+			t.Fatal("expected a dry run")
+		}
+		return notifier.DeliveryReport{
+			Tier:    "idle",
+			Plan:    &notifier.DeliveryPlan{Local: true, Backends: []string{"ntfy", "webhook"}},
+			Explain: []string{"the user is idle, so push backends fire"},
+		}, nil
+	}
+
+	origArgs := os.Args
+	os.Args = []string{"ding-ding", "notify"}
+	defer func() { os.Args = origArgs }()
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := notifyCmd.RunE(cmd, []string{"hello"}); err != nil {
+		t.Fatalf("RunE returned error: %v", err)
+	}
+	for _, want := range []string{"dry run: nothing was sent", "tier: idle", "local notification: would send", "push backends: ntfy, webhook", "why:\n  - the user is idle"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -30,12 +30,22 @@ Usage:
   ding-ding notify -m "Task completed"    Send a notification via CLI
   ding-ding serve                         Start HTTP server for agent POSTs
   ding-ding config init                   Create default config file
+  ding-ding config validate               Report every problem in the config file
+  ding-ding test                          Send a test message through every channel
+  ding-ding doctor                        Check detection tools and backends
   ding-ding agent init claude project     Install agent integration hooks`,
 }
 
 func Execute() {
 	rootCmd.Version = Version
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *notifyExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.err != nil {
+				fmt.Fprintln(os.Stderr, exitErr.err)
+			}
+			os.Exit(exitErr.code)
+		}
 		if isBestEffortNotifyError(err) {
 			fmt.Fprintf(os.Stderr, "notification delivery failed: %v\n", err)
 			return
@@ -51,15 +61,46 @@ func isBestEffortNotifyError(err error) bool {
 	return errors.As(err, &deliveryErr)
 }
 
+// loadConfigForCommand loads the config with the project config found from
+// the current directory layered on top.
 func loadConfigForCommand() (config.LoadResult, error) {
+	cwd, _ := os.Getwd()
+	return loadCommandConfig(cwd)
+}
+
+// loadUserConfigForCommand loads the config without a project layer, for
+// the server, which layers each caller's project config per request.
+func loadUserConfigForCommand() (config.LoadResult, error) {
+	return loadCommandConfig("")
+}
+
+func loadCommandConfig(projectDir string) (config.LoadResult, error) {
 	return config.LoadWithOptions(config.LoadOptions{
 		ExplicitPath: configPathOverride,
+		ProjectDir:   projectDir,
 		Warn: func(message string) {
 			fmt.Fprintln(os.Stderr, message)
 		},
 	})
 }
 
+// printConfigLoadDetails reports in verbose mode each config layer, lowest
+// precedence first, and which fields environment variables overrode.
+func printConfigLoadDetails(cmd *cobra.Command, result config.LoadResult) {
+	for _, source := range result.Sources {
+		printConfigSourceDetails(cmd, source)
+	}
+	if !verboseMode {
+		return
+	}
+	for _, field := range result.EnvFields {
+		fmt.Fprintf(cmd.ErrOrStderr(), "config env: %s (%s)\n", field, config.EnvVar(field))
+	}
+	for _, field := range result.DroppedFields {
+		fmt.Fprintf(cmd.ErrOrStderr(), "config dropped: %s (project config moved its backend)\n", field)
+	}
+}
+
 func printConfigSourceDetails(cmd *cobra.Command, source config.SourceSelection) {
 	if !verboseMode {
 		return
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Digni/ding-ding/internal/logging"
+	"github.com/Digni/ding-ding/internal/notifier"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfTestBackend string
+
+	selfTestLoadConfig = loadConfigForCommand
+	selfTestRun        = notifier.SelfTest
+)
+
+var selfTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test message through every enabled channel",
+	Long: `Send a labelled test message through each channel on its own and report
+whether it was accepted, with the error and latency.
+
+The local notification goes first, then the notification sound (when
+sound.enabled is on), then every enabled push backend, one at a time so each
+arrival can be matched to its line. The sound is skipped, not failed, when no
+sound player is installed. Focus and idle routing is skipped. Use
+--backend to test a single channel (` + strings.Join(notifier.SelfTestChannels, ", ") + `).
+
+test exits 1 when any channel fails, so it can gate setup scripts:
+  ding-ding test
+  ding-ding test --backend ntfy`,
+	Args: cobra.NoArgs,
+	RunE: runSelfTest,
+}
+
+func runSelfTest(cmd *cobra.Command, args []string) error {
+	loadResult, err := selfTestLoadConfig()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	printConfigLoadDetails(cmd, loadResult)
+	cfg := loadResult.Config
+	initializeCommandLogging(cmd.ErrOrStderr(), cfg.Logging, logging.RoleCLI)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, err := selfTestRun(ctx, cfg, selfTestBackend)
+	if err != nil {
+		return err
+	}
+
+	checks := make([]doctorCheck, 0, len(results))
+	failed := false
+	for _, result := range results {
+		detail := "sent"
+		if result.Skipped != "" {
+			detail = "skipped: " + result.Skipped
+		}
+		checks = append(checks, doctorCheck{
+			Name:      result.Backend,
+			OK:        result.OK,
+			LatencyMs: result.LatencyMs,
+			Detail:    detail,
+			Error:     result.Error,
+		})
+		failed = failed || !result.OK
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "sent one test message per channel; check that each one arrived")
+	writeDoctorSection(cmd.OutOrStdout(), "channels", checks, "none enabled")
+
+	if failed {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &notifyExitError{code: 1}
+	}
+	return nil
+}
+
+func init() {
+	selfTestCmd.Flags().StringVar(&selfTestBackend, "backend", "", "Test only this channel")
+	rootCmd.AddCommand(selfTestCmd)
+}
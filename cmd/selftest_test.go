@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Digni/ding-ding/internal/config"
+	"github.com/Digni/ding-ding/internal/logging"
+	"github.com/Digni/ding-ding/internal/notifier"
+	"github.com/spf13/cobra"
+)
+
+func stubSelfTest(t *testing.T, results []notifier.BackendResult) *string {
+	t.Helper()
+	origLoadConfig := selfTestLoadConfig
+	origRun := selfTestRun
+	origBootstrap := commandLoggingBootstrap
+	t.Cleanup(func() {
+		selfTestLoadConfig = origLoadConfig
+		selfTestRun = origRun
+		commandLoggingBootstrap = origBootstrap
+		selfTestBackend = ""
+	})
+
+	selfTestLoadConfig = func() (config.LoadResult, error) {
+		return config.LoadResult{Config: config.DefaultConfig()}, nil
+	}
+	commandLoggingBootstrap = func(config.LoggingConfig, logging.Role) error { return nil }
+	var channel string
+	selfTestRun = func(_ context.Context, _ config.Config, only string) ([]notifier.BackendResult, error) {
+		channel = only
+		return results, nil
+	}
+	return &channel
+}
+
+func TestSelfTest_PrintsEachChannel(t *testing.T) {
+	channel := stubSelfTest(t, []notifier.BackendResult{
+		{Backend: "local", OK: true, Attempts: 1, LatencyMs: 8},
+		{Backend: "ntfy", OK: true, Attempts: 1, LatencyMs: 140},
+	})
+	selfTestBackend = "ntfy"
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := runSelfTest(cmd, nil); err != nil {
+		t.Fatalf("runSelfTest returned error: %v", err)
+	}
+	if *channel != "ntfy" {
+		t.Errorf("channel = %q, want ntfy", *channel)
+	}
+	for _, want := range []string{"ok    local             8ms  sent", "ok    ntfy            140ms  sent"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSelfTest_SkippedChannelPasses(t *testing.T) {
+	stubSelfTest(t, []notifier.BackendResult{
+		{Backend: "local", OK: true, Attempts: 1},
+		{Backend: "sound", OK: true, Attempts: 1, Skipped: "no sound player found"},
+	})
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := runSelfTest(cmd, nil); err != nil {
+		t.Fatalf("runSelfTest returned error: %v", err)
+	}
+	if want := "ok    sound             0ms  skipped: no sound player found"; !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+}
+
+func TestSelfTest_FailureExitsNonZero(t *testing.T) {
+	stubSelfTest(t, []notifier.BackendResult{
+		{Backend: "local", OK: true, Attempts: 1},
+		{Backend: "discord", Attempts: 1, LatencyMs: 90, Error: "discord returned status 404\nnot found"},
+	})
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	err := runSelfTest(cmd, nil)
+	var exitErr *notifyExitError
+	if !errors.As(err, &exitErr) || exitErr.code != 1 {
+		t.Fatalf("runSelfTest error = %v, want exit status 1", err)
+	}
+	if want := "FAIL  discord          90ms  discord returned status 404; not found"; !strings.Contains(out.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, out.String())
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 var serveAddress string
 var startServer = server.Start
-var serveLoadConfig = loadConfigForCommand
+var serveLoadConfig = loadUserConfigForCommand
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
@@ -18,8 +18,14 @@ var serveCmd = &cobra.Command{
 	Long: `Start an HTTP server that agents can POST to when tasks complete.
 
 Endpoints:
-  POST /notify    Send notification (JSON body: {"title":"...", "body":"...", "agent":"..."})
-  GET  /notify    Quick notify (?title=...&message=...&agent=...)
+  POST /notify    Send notification (JSON body: {"title":"...", "body":"...", "agent":"...", "session":"...", "event":"...", "exit_code":0, "duration_ms":0, "priority":"...", "cwd":"...", "url":"...", "actions":[{"label":"...","url":"..."}], "wait_action":"allow,deny", "wait_timeout":300, "replies":["allow","deny"], "ntfy":{"tags":["..."], "markdown":true, "icon":"...", "attach":"...", "filename":"...", "email":"...", "delay":"..."}})
+  GET  /notify    Quick notify (?title=...&message=...&agent=...&session=...&event=...&exit_code=...&duration_ms=...&priority=...&cwd=...&url=...&wait_action=...&wait_timeout=...&replies=...)
+                  With wait_action, the request blocks until an action is clicked on this desktop
+                  Otherwise answers with a delivery report (tier, idle/focus, per-backend results);
+                  500 only when every push backend failed
+                  With ?dry_run=1, routing runs and the plan is reported, but nothing is sent
+  GET  /replies/{operation_id}
+                  Replies received on ntfy.reply_topic for a notification sent with replies
   GET  /health    Health check
 
 Example:
@@ -30,7 +36,7 @@ Example:
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
-		printConfigSourceDetails(cmd, loadResult.Source)
+		printConfigLoadDetails(cmd, loadResult)
 		cfg := loadResult.Config
 		initializeCommandLogging(cmd.ErrOrStderr(), cfg.Logging, logging.RoleServer)
 
@@ -3,6 +3,7 @@ module github.com/Digni/ding-ding
 go 1.24.7
 
 require (
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/spf13/cobra v1.10.2
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 	gopkg.in/yaml.v3 v3.0.1
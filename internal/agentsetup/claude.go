@@ -151,14 +151,29 @@ func claudeCommandForEvent(event string, mode Mode) string {
 	switch mode {
 	case ModeServer:
 		if event == claudeEventNotification {
-			return `curl -s localhost:8228/notify -d '{"agent":"claude","body":"Needs your attention","pid":'$$'}'`
+			return `curl -s localhost:8228/notify -d '{"agent":"claude","event":"needs_input","body":"Needs your attention","pid":'$$'}'`
 		}
-		return `curl -s localhost:8228/notify -d '{"agent":"claude","body":"Task finished","pid":'$$'}'`
+		return `curl -s localhost:8228/notify -d '{"agent":"claude","event":"completed","body":"Task finished","pid":'$$'}'`
 	default:
 		if event == claudeEventNotification {
-			return "ding-ding notify -a claude -m 'Needs your attention'"
+			return "ding-ding notify -a claude --event needs_input -m 'Needs your attention'"
 		}
-		return "ding-ding notify -a claude -m 'Task finished'"
+		return "ding-ding notify -a claude --event completed -m 'Task finished'"
+	}
+}
+
+// legacyClaudeCommandsForEvent lists hook commands written by earlier releases
+// so upgrading replaces them instead of leaving duplicate hooks behind.
+func legacyClaudeCommandsForEvent(event string) []string {
+	if event == claudeEventNotification {
+		return []string{
+			"ding-ding notify -a claude -m 'Needs your attention'",
+			`curl -s localhost:8228/notify -d '{"agent":"claude","body":"Needs your attention","pid":'$$'}'`,
+		}
+	}
+	return []string{
+		"ding-ding notify -a claude -m 'Task finished'",
+		`curl -s localhost:8228/notify -d '{"agent":"claude","body":"Task finished","pid":'$$'}'`,
 	}
 }
 
@@ -175,10 +190,10 @@ func isManagedClaudeCommand(event string, command string) bool {
 func managedClaudeCommandsForEvent(event string) []string {
 	switch event {
 	case claudeEventStop, claudeEventNotification:
-		return []string{
+		return append([]string{
 			claudeCommandForEvent(event, ModeCLI),
 			claudeCommandForEvent(event, ModeServer),
-		}
+		}, legacyClaudeCommandsForEvent(event)...)
 	default:
 		return nil
 	}
@@ -27,8 +27,8 @@ func TestUpsertClaudeSettings_CreatesMinimalSettings(t *testing.T) {
 	stopCommands := eventCommands(t, root, claudeEventStop)
 	notificationCommands := eventCommands(t, root, claudeEventNotification)
 
-	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude -m 'Task finished'")
-	assertContainsCommand(t, notificationCommands, "ding-ding notify -a claude -m 'Needs your attention'")
+	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude --event completed -m 'Task finished'")
+	assertContainsCommand(t, notificationCommands, "ding-ding notify -a claude --event needs_input -m 'Needs your attention'")
 }
 
 func TestUpsertClaudeSettings_MergesAndDeduplicatesManagedHooks(t *testing.T) {
@@ -85,8 +85,8 @@ func TestUpsertClaudeSettings_MergesAndDeduplicatesManagedHooks(t *testing.T) {
 
 	assertContainsCommand(t, stopCommands, "echo keep-stop")
 	assertContainsCommand(t, notificationCommands, "echo keep-notification")
-	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude -m 'Task finished'")
-	assertContainsCommand(t, notificationCommands, "ding-ding notify -a claude -m 'Needs your attention'")
+	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude --event completed -m 'Task finished'")
+	assertContainsCommand(t, notificationCommands, "ding-ding notify -a claude --event needs_input -m 'Needs your attention'")
 
 	if countManagedCommands(claudeEventStop, stopCommands) != 1 {
 		t.Fatalf("Stop managed hook count = %d, want 1", countManagedCommands(claudeEventStop, stopCommands))
@@ -96,6 +96,45 @@ func TestUpsertClaudeSettings_MergesAndDeduplicatesManagedHooks(t *testing.T) {
 	}
 }
 
+func TestUpsertClaudeSettings_ReplacesLegacyCommandsWithoutEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	seed := `{
+  "hooks": {
+    "Stop": [
+      {
+        "hooks": [
+          {"type": "command", "command": "ding-ding notify -a claude -m 'Task finished'", "async": true}
+        ]
+      }
+    ],
+    "Notification": [
+      {
+        "hooks": [
+          {"type": "command", "command": "curl -s localhost:8228/notify -d '{\"agent\":\"claude\",\"body\":\"Needs your attention\",\"pid\":'$$'}'", "async": true}
+        ]
+      }
+    ]
+  }
+}`
+	if err := os.WriteFile(path, []byte(seed), 0o644); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+
+	if _, err := upsertClaudeSettings(path, ModeServer); err != nil {
+		t.Fatalf("upsertClaudeSettings() error = %v", err)
+	}
+
+	root := readJSONFile(t, path)
+	stopCommands := eventCommands(t, root, claudeEventStop)
+	notificationCommands := eventCommands(t, root, claudeEventNotification)
+
+	if len(stopCommands) != 1 || len(notificationCommands) != 1 {
+		t.Fatalf("legacy hooks not replaced: stop=%v notification=%v", stopCommands, notificationCommands)
+	}
+	assertContainsCommandWith(t, stopCommands, `"event":"completed"`)
+	assertContainsCommandWith(t, notificationCommands, `"event":"needs_input"`)
+}
+
 func TestUpsertClaudeSettings_ModeSwitchUpdatesCommands(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "settings.json")
 
@@ -153,7 +192,7 @@ func TestUpsertClaudeSettings_PreservesCustomServerHook(t *testing.T) {
 	root := readJSONFile(t, path)
 	stopCommands := eventCommands(t, root, claudeEventStop)
 	assertContainsCommand(t, stopCommands, customCommand)
-	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude -m 'Task finished'")
+	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude --event completed -m 'Task finished'")
 	if countManagedCommands(claudeEventStop, stopCommands) != 1 {
 		t.Fatalf("managed stop commands = %d, want 1", countManagedCommands(claudeEventStop, stopCommands))
 	}
@@ -231,7 +270,7 @@ func TestUpsertClaudeSettings_PreservesMatcherEntryWithNonManagedHook(t *testing
 	root := readJSONFile(t, path)
 	stopCommands := eventCommands(t, root, claudeEventStop)
 	assertContainsCommand(t, stopCommands, "echo keep-stop")
-	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude -m 'Task finished'")
+	assertContainsCommand(t, stopCommands, "ding-ding notify -a claude --event completed -m 'Task finished'")
 	if countManagedCommands(claudeEventStop, stopCommands) != 1 {
 		t.Fatalf("managed stop commands = %d, want 1", countManagedCommands(claudeEventStop, stopCommands))
 	}
@@ -43,6 +43,7 @@ export const DingDing: Plugin = async ({ $ }) => ({
     if (event.type === "session.idle") {
       const payload = JSON.stringify({
         agent: "opencode",
+        event: "completed",
         body: "Task finished",
         pid: process.pid,
       })
@@ -57,7 +58,7 @@ export const DingDing: Plugin = async ({ $ }) => ({
 export const DingDing: Plugin = async ({ $ }) => ({
   event: async ({ event }) => {
     if (event.type === "session.idle") {
-      await $` + "`" + `ding-ding notify -a opencode -m "Task finished"` + "`" + `
+      await $` + "`" + `ding-ding notify -a opencode --event completed -m "Task finished"` + "`" + `
     }
   },
 })
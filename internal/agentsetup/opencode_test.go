@@ -19,7 +19,7 @@ func TestUpsertOpenCodePlugin_CreatesFile(t *testing.T) {
 	}
 
 	content := readTextFile(t, path)
-	if !strings.Contains(content, `ding-ding notify -a opencode -m "Task finished"`) {
+	if !strings.Contains(content, `ding-ding notify -a opencode --event completed -m "Task finished"`) {
 		t.Fatalf("unexpected plugin content:\n%s", content)
 	}
 }
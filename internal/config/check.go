@@ -0,0 +1,227 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is one error in a config file. Line is 0 when the problem cannot
+// be tied to a line, such as a required field that is not set.
+type Problem struct {
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// Check validates config YAML strictly and reports every problem instead of
+// stopping at the first: syntax errors, unknown fields (which loading
+// ignores), values of the wrong type, and each Validate rule. Problems are
+// tied to file lines where possible.
+func Check(data []byte) []Problem {
+	problems, doc, cfg, ok := checkSchema(data)
+	if !ok {
+		return problems
+	}
+
+	cfg.Logging.Dir = normalizeLoggingDir(cfg.Logging.Dir)
+	for _, err := range ValidateAll(cfg) {
+		field, _, _ := strings.Cut(err.Error(), " ")
+		problems = append(problems, Problem{
+			Line:    lineOf(doc, strings.TrimRight(field, ":,")),
+			Message: err.Error(),
+		})
+	}
+	return problems
+}
+
+// CheckProject is Check for a project config. Validate rules are left out,
+// since they apply to the layered result, and the project restrictions of
+// WithProject are checked instead.
+func CheckProject(data []byte) []Problem {
+	problems, doc, cfg, ok := checkSchema(data)
+	if !ok || doc == nil {
+		return problems
+	}
+	problems = append(problems, projectProblems(doc)...)
+	for _, problem := range projectChangeProblems(cfg, DefaultConfig()) {
+		if !slices.ContainsFunc(problems, func(p Problem) bool { return p.Message == problem.Message }) {
+			problems = append(problems, problem)
+		}
+	}
+	slices.SortStableFunc(problems, func(a, b Problem) int { return cmp.Compare(a.Line, b.Line) })
+	return problems
+}
+
+// checkSchema reports syntax errors, unknown fields and type errors, sorted
+// by line, and returns the parsed file over the defaults. ok is false when
+// the file could not be decoded at all.
+func checkSchema(data []byte) (problems []Problem, doc *yaml.Node, cfg Config, ok bool) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Problem{yamlProblem(err.Error())}, nil, cfg, false
+	}
+	doc = documentRoot(&root)
+
+	if doc != nil {
+		problems = unknownFields(doc, reflect.TypeFor[Config](), "", problems)
+	}
+
+	cfg = DefaultConfig()
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return append(problems, yamlProblem(err.Error())), doc, cfg, false
+		}
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, yamlProblem(msg))
+		}
+	}
+	slices.SortStableFunc(problems, func(a, b Problem) int { return cmp.Compare(a.Line, b.Line) })
+	return problems, doc, cfg, true
+}
+
+var yamlLinePattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// yamlProblem splits the "line N: " prefix off a yaml error message.
+func yamlProblem(msg string) Problem {
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Problem{Line: line, Message: m[2]}
+	}
+	return Problem{Message: msg}
+}
+
+// documentRoot returns the top-level mapping of a parsed file, or nil for an
+// empty file.
+func documentRoot(root *yaml.Node) *yaml.Node {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		return root.Content[0]
+	}
+	return nil
+}
+
+// unknownFields reports mapping keys that match no yaml tag of t,
+// descending into known struct fields and map values.
+func unknownFields(node *yaml.Node, t reflect.Type, path string, problems []Problem) []Problem {
+	if node.Kind != yaml.MappingNode {
+		return problems
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			continue
+		}
+		keyPath := joinPath(path, key.Value)
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(t, key.Value)
+			if !ok {
+				msg := "unknown field " + keyPath
+				if near := closestField(t, key.Value); near != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", joinPath(path, near))
+				}
+				problems = append(problems, Problem{Line: key.Line, Message: msg})
+				continue
+			}
+			problems = unknownFields(value, field.Type, keyPath, problems)
+		case reflect.Map:
+			problems = unknownFields(value, t.Elem(), keyPath, problems)
+		}
+	}
+	return problems
+}
+
+// yamlField finds the field of struct type t decoded from key name.
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.IsExported() && yamlName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// closestField suggests the field of t that name is most likely a typo of.
+func closestField(t reflect.Type, name string) string {
+	best, bestDistance := "", 3
+	for i := range t.NumField() {
+		candidate := yamlName(t.Field(i))
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// lineOf returns the line of the deepest key along a dotted path, so a
+// problem with a field that is not set points at its section.
+func lineOf(doc *yaml.Node, path string) int {
+	line := 0
+	node := doc
+	for _, segment := range strings.Split(path, ".") {
+		if node == nil || node.Kind != yaml.MappingNode {
+			break
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				line, next = node.Content[i].Line, node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return line
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck_ReportsEveryProblemWithLines(t *testing.T) {
+	data := []byte(`ntfy:
+  enabled: true
+  topik: alerts
+notification:
+  supress_when_focused: false
+idle:
+  threshold_seconds: five
+email:
+  enabled: true
+logging:
+  level: loud
+`)
+
+	var got []string
+	for _, problem := range Check(data) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		"line 3: unknown field ntfy.topik (did you mean ntfy.topic?)",
+		"line 5: unknown field notification.supress_when_focused (did you mean notification.suppress_when_focused?)",
+		"line 7: cannot unmarshal !!str `five` into int",
+		"line 8: email.host is required when email.enabled is true",
+		"line 8: email.from is required when email.enabled is true",
+		"line 8: email.to must list at least one recipient when email.enabled is true",
+		"line 8: email.username is required when email.auth is plain",
+		"line 11: logging.level must be one of error, warn, info, debug",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Check() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheck_AcceptsMapKeysAndValidConfig(t *testing.T) {
+	data := []byte(`webhook:
+  headers:
+    X-Anything: "1"
+discord:
+  agents:
+    claude:
+      username: Claude
+`)
+	if problems := Check(data); len(problems) != 0 {
+		t.Errorf("Check() = %v, want no problems", problems)
+	}
+	if problems := Check(nil); len(problems) != 0 {
+		t.Errorf("Check(empty) = %v, want no problems", problems)
+	}
+}
+
+func TestCheck_SyntaxError(t *testing.T) {
+	problems := Check([]byte("ntfy:\n  enabled: true\n topic: x\n"))
+	if len(problems) != 1 || problems[0].Line == 0 {
+		t.Errorf("Check() = %v, want one problem with a line", problems)
+	}
+}
+
+func TestAnnotate_MarksSourcesAndRedactsSecrets(t *testing.T) {
+	data := []byte(`ntfy:
+  enabled: true
+  token: tk_secret
+webhook:
+  headers:
+    Authorization: Bearer abc
+`)
+	cfg, err := LoadFromBytes(data)
+	if err != nil {
+		t.Fatalf("LoadFromBytes() error = %v", err)
+	}
+	fields, err := FileFields(data)
+	if err != nil {
+		t.Fatalf("FileFields() error = %v", err)
+	}
+	if want := []string{"ntfy.enabled", "ntfy.token", "webhook.headers.Authorization"}; !slices.Equal(fields, want) {
+		t.Errorf("FileFields() = %v, want %v", fields, want)
+	}
+
+	sources := map[string]string{}
+	for _, field := range fields {
+		sources[field] = FieldFile
+	}
+	out, err := Annotate(cfg, sources)
+	if err != nil {
+		t.Fatalf("Annotate() error = %v", err)
+	}
+	text := string(out)
+	for _, want := range []string{
+		"  enabled: true # file\n",
+		"  token: '[REDACTED]' # file\n",
+		"  server: https://ntfy.sh # default\n",
+		"    Authorization: '[REDACTED]' # file\n",
+		`  password: "" # default` + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Annotate() missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "tk_secret") || strings.Contains(text, "Bearer abc") {
+		t.Errorf("Annotate() leaked a secret:\n%s", text)
+	}
+}
+
+func TestAnnotate_ShowsSecretReferences(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ntfy.Token = "keyring:ding-ding/ntfy"
+	cfg.Email.Password = "hunter2"
+	out, err := Annotate(cfg, nil)
+	if err != nil {
+		t.Fatalf("Annotate() error = %v", err)
+	}
+	text := string(out)
+	if !strings.Contains(text, "token: keyring:ding-ding/ntfy # default") {
+		t.Errorf("Annotate() redacted a secret reference:\n%s", text)
+	}
+	if strings.Contains(text, "hunter2") {
+		t.Errorf("Annotate() leaked a secret:\n%s", text)
+	}
+}
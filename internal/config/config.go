@@ -1,6 +1,7 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
 	"log"
 	"os"
@@ -19,6 +20,7 @@ const (
 	SourceConfigFile   SourceType = "config-file"
 	SourceEnvironment  SourceType = "environment"
 	SourceDefaults     SourceType = "defaults"
+	SourceProject      SourceType = "project"
 )
 
 type SourceSelection struct {
@@ -29,7 +31,15 @@ type SourceSelection struct {
 
 type LoadResult struct {
 	Config Config
-	Source SourceSelection
+	// Sources lists the config layers from lowest to highest precedence:
+	// the user config (or the defaults), then any project config.
+	Sources []SourceSelection
+	// EnvFields lists the dotted paths of the fields overridden by
+	// DING_DING_* environment variables, in config order.
+	EnvFields []string
+	// DroppedFields lists the credential fields cleared because a project
+	// config moved their backend to another server.
+	DroppedFields []string
 }
 
 type LoadOptions struct {
@@ -37,12 +47,25 @@ type LoadOptions struct {
 	EnvPath      string
 	Warn         func(string)
 	Resolve      ResolveOptions
+	// LookupEnv reads the field override variables; nil uses os.LookupEnv.
+	LookupEnv func(string) (string, bool)
+	// ProjectDir, when set, layers the nearest project config found from
+	// this directory upwards (see LoadResult.WithProject).
+	ProjectDir string
 }
 
+// Config is the ding-ding configuration. Fields tagged secret:"true" hold
+// credentials: they are redacted wherever the config is shown, and accept a
+// reference such as "env:NAME" or "keyring:service/account" (see
+// SecretSchemes) that the notifier resolves at send time.
 type Config struct {
 	Ntfy         NtfyConfig         `yaml:"ntfy"`
 	Discord      DiscordConfig      `yaml:"discord"`
 	Webhook      WebhookConfig      `yaml:"webhook"`
+	Email        EmailConfig        `yaml:"email"`
+	Teams        TeamsConfig        `yaml:"teams"`
+	MQTT         MQTTConfig         `yaml:"mqtt"`
+	HTTP         HTTPConfig         `yaml:"http"`
 	Idle         IdleConfig         `yaml:"idle"`
 	Notification NotificationConfig `yaml:"notification"`
 	Server       ServerConfig       `yaml:"server"`
@@ -51,22 +74,205 @@ type Config struct {
 }
 
 type NtfyConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	Server   string `yaml:"server"`
-	Topic    string `yaml:"topic"`
-	Token    string `yaml:"token"`
+	Enabled bool   `yaml:"enabled"`
+	Server  string `yaml:"server"`
+	Topic   string `yaml:"topic"`
+	Token   string `yaml:"token" secret:"true"`
+	// Username and Password authenticate with HTTP basic auth instead of
+	// Token.
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
 	Priority string `yaml:"priority"`
+	// Tags are added to every message; emoji shortcodes render as emojis.
+	Tags     []string `yaml:"tags"`
+	Markdown bool     `yaml:"markdown"`
+	Icon     string   `yaml:"icon"`
+	// Click is opened when a message without its own URL is tapped.
+	Click string `yaml:"click"`
+	// Email forwards every message to this address.
+	Email string `yaml:"email"`
+	// Delay schedules delivery, e.g. "30m" or "tomorrow, 10am".
+	Delay string `yaml:"delay"`
+	// Cache and Firebase can be turned off on self-hosted servers that
+	// should not store messages or forward them through Firebase.
+	Cache    bool `yaml:"cache"`
+	Firebase bool `yaml:"firebase"`
+	// PriorityMap maps a message priority (low, normal, high, urgent) to an
+	// ntfy priority. It only applies to messages that carry a priority;
+	// others use Priority.
+	PriorityMap map[string]string `yaml:"priority_map"`
+	// ReplyTopic, when set, turns a message's reply options into buttons
+	// that publish the answer to this topic, where `ding-ding serve`
+	// subscribes to collect them.
+	ReplyTopic string `yaml:"reply_topic"`
+	// ReplyToken authorizes reply buttons to publish to ReplyTopic. Buttons
+	// carry it inside the message, where every subscriber of Topic can read
+	// it, so it should be a token that can only write to the reply topic.
+	// Empty means the reply topic accepts anonymous publishes.
+	ReplyToken string         `yaml:"reply_token" secret:"true"`
+	Template   TemplateConfig `yaml:"template"`
+	// HTTP overrides fields of the top-level http section for this backend.
+	HTTP HTTPConfig `yaml:"http"`
 }
 
 type DiscordConfig struct {
 	Enabled    bool   `yaml:"enabled"`
-	WebhookURL string `yaml:"webhook_url"`
+	WebhookURL string `yaml:"webhook_url" secret:"true"`
+	// PriorityMentions maps a message priority to a mention prepended to the
+	// message: @here, @everyone, <@user_id> or <@&role_id>. An empty value
+	// mentions nobody.
+	PriorityMentions map[string]string `yaml:"priority_mentions"`
+	// Embed posts a rich embed (title, colored by event, agent/host/repo/
+	// duration fields and a timestamp) instead of plain content.
+	Embed bool `yaml:"embed"`
+	// Username and AvatarURL override the webhook's identity; Agents
+	// overrides them per agent name.
+	Username  string                     `yaml:"username"`
+	AvatarURL string                     `yaml:"avatar_url"`
+	Agents    map[string]DiscordIdentity `yaml:"agents"`
+	// ThreadID posts into an existing thread, e.g. a forum channel post.
+	ThreadID string `yaml:"thread_id"`
+	// Template.Body, when set, replaces the whole message content (the
+	// embed description in embed mode).
+	Template TemplateConfig `yaml:"template"`
+	// HTTP overrides fields of the top-level http section for this backend.
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+// DiscordIdentity is the name and avatar a Discord message is posted as.
+type DiscordIdentity struct {
+	Username  string `yaml:"username"`
+	AvatarURL string `yaml:"avatar_url"`
 }
 
 type WebhookConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	URL     string `yaml:"url"`
 	Method  string `yaml:"method"`
+	// PriorityMap rewrites the payload's priority field, e.g. to match the
+	// receiver's own severity names. Unmapped priorities are sent as-is.
+	PriorityMap map[string]string `yaml:"priority_map"`
+	Template    TemplateConfig    `yaml:"template"`
+	// Payload, when set, is a template for the entire request body. With
+	// the json format it must render valid JSON.
+	Payload string `yaml:"payload"`
+	// Format selects the body encoding: "json" (the message, or Payload
+	// when set), "form" (URL-encoded message fields) or "template"
+	// (Payload sent verbatim as ContentType).
+	Format      string `yaml:"format"`
+	ContentType string `yaml:"content_type"`
+	// Headers are added to every request. Values may embed environment
+	// variables as ${NAME}, or be a secret reference such as "env:NAME".
+	Headers map[string]string `yaml:"headers" secret:"true"`
+	// Secret, when set, signs each request with HMAC-SHA256 over
+	// "<unix timestamp>.<body>", sent in SignatureHeader as "t=<ts>,v1=<hex>".
+	// It accepts the same references as Headers.
+	Secret          string `yaml:"secret" secret:"true"`
+	SignatureHeader string `yaml:"signature_header"`
+	// SuccessStatus lists the response statuses that count as delivered,
+	// as codes ("202") or classes ("2xx"). Empty accepts any 2xx.
+	SuccessStatus []string `yaml:"success_status"`
+	// HTTP overrides fields of the top-level http section for this backend.
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+type EmailConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+	// Security selects the transport: "starttls" upgrades a plain connection,
+	// "tls" dials with implicit TLS (usually port 465), "none" sends in clear.
+	Security string `yaml:"security"`
+	// Auth selects the SMTP auth mechanism: "plain", "login" or "none".
+	Auth     string   `yaml:"auth"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password" secret:"true"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	// Subject is a text/template rendered with the notification message.
+	Subject  string         `yaml:"subject"`
+	Template TemplateConfig `yaml:"template"`
+}
+
+type TeamsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// WebhookURL accepts both legacy incoming-webhook connectors and
+	// Power Automate "Workflows" HTTP trigger URLs.
+	WebhookURL  string         `yaml:"webhook_url" secret:"true"`
+	ActionURL   string         `yaml:"action_url"`
+	ActionTitle string         `yaml:"action_title"`
+	Template    TemplateConfig `yaml:"template"`
+	// HTTP overrides fields of the top-level http section for this backend.
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+// HTTPConfig configures the HTTP clients of the ntfy, Discord, webhook and
+// Teams backends. The top-level http section applies to all of them; each
+// backend's http section overrides the fields it sets.
+type HTTPConfig struct {
+	// Proxy is an http, https or socks5 proxy URL. Empty uses the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string `yaml:"proxy"`
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are a PEM client certificate and key for mTLS.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// InsecureSkipVerify disables server certificate checks, globally or
+	// for one backend. A warning is logged when it is in effect. It is a
+	// pointer so that a backend's explicit false overrides a global true.
+	InsecureSkipVerify *bool `yaml:"insecure_skip_verify"`
+	// TimeoutSeconds bounds a whole request, ConnectTimeoutSeconds
+	// establishing the connection.
+	TimeoutSeconds        int `yaml:"timeout_seconds"`
+	ConnectTimeoutSeconds int `yaml:"connect_timeout_seconds"`
+}
+
+// Merged returns c with the fields set in override replacing its own.
+// Merging is idempotent, so an already merged config can be merged again.
+func (c HTTPConfig) Merged(override HTTPConfig) HTTPConfig {
+	merged := c
+	merged.Proxy = cmp.Or(override.Proxy, c.Proxy)
+	merged.CAFile = cmp.Or(override.CAFile, c.CAFile)
+	if override.CertFile != "" || override.KeyFile != "" {
+		merged.CertFile, merged.KeyFile = override.CertFile, override.KeyFile
+	}
+	if override.InsecureSkipVerify != nil {
+		merged.InsecureSkipVerify = override.InsecureSkipVerify
+	}
+	merged.TimeoutSeconds = cmp.Or(override.TimeoutSeconds, c.TimeoutSeconds)
+	merged.ConnectTimeoutSeconds = cmp.Or(override.ConnectTimeoutSeconds, c.ConnectTimeoutSeconds)
+	return merged
+}
+
+// SkipVerify reports whether server certificate checks are disabled.
+func (c HTTPConfig) SkipVerify() bool {
+	return c.InsecureSkipVerify != nil && *c.InsecureSkipVerify
+}
+
+// WithBackendHTTP returns c with each HTTP backend's http section replaced by
+// its effective settings: the top-level section merged with its overrides.
+func (c Config) WithBackendHTTP() Config {
+	c.Ntfy.HTTP = c.HTTP.Merged(c.Ntfy.HTTP)
+	c.Discord.HTTP = c.HTTP.Merged(c.Discord.HTTP)
+	c.Webhook.HTTP = c.HTTP.Merged(c.Webhook.HTTP)
+	c.Teams.HTTP = c.HTTP.Merged(c.Teams.HTTP)
+	return c
+}
+
+type MQTTConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Broker is a URL such as tcp://host:1883 or tls://host:8883
+	// (ssl:// and mqtts:// are accepted as TLS aliases, mqtt:// as plain).
+	Broker string `yaml:"broker"`
+	// Topic may contain {agent} and {event} placeholders.
+	Topic    string         `yaml:"topic"`
+	QoS      int            `yaml:"qos"`
+	Retain   bool           `yaml:"retain"`
+	ClientID string         `yaml:"client_id"`
+	Username string         `yaml:"username"`
+	Password string         `yaml:"password" secret:"true"`
+	Template TemplateConfig `yaml:"template"`
 }
 
 type IdleConfig struct {
@@ -78,6 +284,26 @@ type NotificationConfig struct {
 	// SuppressWhenFocused skips the system notification when the terminal
 	// that spawned ding-ding is the focused window (user is watching).
 	SuppressWhenFocused bool `yaml:"suppress_when_focused"`
+	// ReplaceInPlace makes later notifications from the same agent session
+	// update the earlier one instead of stacking new bubbles and pushes.
+	ReplaceInPlace bool `yaml:"replace_in_place"`
+	// DeliveryTimeoutSeconds bounds a whole delivery: idle and focus
+	// detection plus every push backend. 0 disables the deadline.
+	DeliveryTimeoutSeconds int `yaml:"delivery_timeout_seconds"`
+	// Urgency is the desktop urgency hint: low, normal or critical.
+	Urgency string `yaml:"urgency"`
+	// ExpireTimeoutMs is how long the desktop notification stays visible;
+	// -1 leaves it to the notification server and 0 never expires.
+	ExpireTimeoutMs int    `yaml:"expire_timeout_ms"`
+	Icon            string `yaml:"icon"`
+	Category        string `yaml:"category"`
+	// PriorityUrgency maps a message priority to the desktop urgency hint,
+	// overriding Urgency for messages that carry a priority.
+	PriorityUrgency map[string]string `yaml:"priority_urgency"`
+	// PriorityScenario maps a message priority to a Windows toast scenario:
+	// default, reminder, alarm, incomingCall or urgent.
+	PriorityScenario map[string]string `yaml:"priority_scenario"`
+	Template         TemplateConfig    `yaml:"template"`
 }
 
 type ServerConfig struct {
@@ -104,20 +330,65 @@ func DefaultConfig() Config {
 			Server:   "https://ntfy.sh",
 			Topic:    "ding-ding",
 			Priority: "high",
+			Cache:    true,
+			Firebase: true,
+			PriorityMap: map[string]string{
+				"low":    "low",
+				"normal": "default",
+				"high":   "high",
+				"urgent": "urgent",
+			},
 		},
 		Discord: DiscordConfig{
-			Enabled: false,
+			Enabled:          false,
+			PriorityMentions: map[string]string{},
 		},
 		Webhook: WebhookConfig{
+			Enabled:         false,
+			Method:          "POST",
+			Format:          "json",
+			SignatureHeader: "X-Ding-Ding-Signature",
+		},
+		Email: EmailConfig{
+			Enabled:  false,
+			Port:     587,
+			Security: "starttls",
+			Auth:     "plain",
+			Subject:  "[ding-ding] {{.Title}}",
+		},
+		Teams: TeamsConfig{
+			Enabled:     false,
+			ActionTitle: "Open",
+		},
+		MQTT: MQTTConfig{
 			Enabled: false,
-			Method:  "POST",
+			Topic:   "ding-ding/{agent}/{event}",
+			QoS:     0,
+		},
+		HTTP: HTTPConfig{
+			InsecureSkipVerify:    new(bool),
+			TimeoutSeconds:        15,
+			ConnectTimeoutSeconds: 10,
 		},
 		Idle: IdleConfig{
 			ThresholdSeconds: 300,
 			FallbackPolicy:   "active",
 		},
 		Notification: NotificationConfig{
-			SuppressWhenFocused: true,
+			SuppressWhenFocused:    true,
+			ReplaceInPlace:         false,
+			DeliveryTimeoutSeconds: 60,
+			Urgency:                "normal",
+			ExpireTimeoutMs:        -1,
+			PriorityUrgency: map[string]string{
+				"low":    "low",
+				"normal": "normal",
+				"high":   "critical",
+				"urgent": "critical",
+			},
+			PriorityScenario: map[string]string{
+				"urgent": "urgent",
+			},
 		},
 		Server: ServerConfig{
 			Address: "127.0.0.1:8228",
@@ -188,26 +459,40 @@ func LoadFromBytes(data []byte) (Config, error) {
 	return cfg, nil
 }
 
-func loadFromSource(source SourceSelection) (LoadResult, error) {
-	if source.Type == SourceDefaults {
-		return LoadResult{Config: DefaultConfig(), Source: source}, nil
-	}
+// loadFromSource reads the selected config file, overrides it with the
+// environment, and validates the result.
+func loadFromSource(source SourceSelection, lookupEnv func(string) (string, bool)) (LoadResult, error) {
+	cfg := DefaultConfig()
+	if source.Type != SourceDefaults {
+		data, err := os.ReadFile(source.Path)
+		if err != nil {
+			return LoadResult{}, fmt.Errorf("read %s config %q: %w", source.Type, source.Path, err)
+		}
 
-	data, err := os.ReadFile(source.Path)
-	if err != nil {
-		return LoadResult{}, fmt.Errorf("read %s config %q: %w", source.Type, source.Path, err)
+		cfg, err = LoadFromBytes(data)
+		if err != nil {
+			return LoadResult{}, fmt.Errorf("parse %s config %q: %w", source.Type, source.Path, err)
+		}
 	}
 
-	cfg, err := LoadFromBytes(data)
+	envFields, err := applyEnv(&cfg, lookupEnv)
 	if err != nil {
-		return LoadResult{}, fmt.Errorf("parse %s config %q: %w", source.Type, source.Path, err)
+		return LoadResult{}, fmt.Errorf("environment override: %w", err)
 	}
+	cfg.Logging.Dir = normalizeLoggingDir(cfg.Logging.Dir)
 
 	if err := Validate(cfg); err != nil {
-		return LoadResult{}, fmt.Errorf("validate %s config %q: %w", source.Type, source.Path, err)
+		label := fmt.Sprintf("%s config", source.Type)
+		if source.Path != "" {
+			label += fmt.Sprintf(" %q", source.Path)
+		}
+		if len(envFields) > 0 {
+			label += " with environment overrides"
+		}
+		return LoadResult{}, fmt.Errorf("validate %s: %w", label, err)
 	}
 
-	return LoadResult{Config: cfg, Source: source}, nil
+	return LoadResult{Config: cfg, Sources: []SourceSelection{source}, EnvFields: envFields}, nil
 }
 
 func warnf(warn func(string), format string, args ...any) {
@@ -218,7 +503,23 @@ func warnf(warn func(string), format string, args ...any) {
 }
 
 // LoadWithOptions reads config with deterministic source selection and metadata.
+// DING_DING_* environment variables override the fields of the selected
+// file, or of the defaults when there is none, and of the project config
+// when ProjectDir is set.
 func LoadWithOptions(opts LoadOptions) (LoadResult, error) {
+	result, err := loadUserConfig(opts)
+	if err != nil || opts.ProjectDir == "" {
+		return result, err
+	}
+	return result.WithProject(opts.ProjectDir, opts.LookupEnv)
+}
+
+func loadUserConfig(opts LoadOptions) (LoadResult, error) {
+	lookupEnv := opts.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
 	resolveOpts := opts.Resolve
 	if opts.EnvPath != "" {
 		resolveOpts.EnvPath = opts.EnvPath
@@ -234,7 +535,7 @@ func LoadWithOptions(opts LoadOptions) (LoadResult, error) {
 			Reason: "selected by --config flag",
 		}
 
-		result, err := loadFromSource(explicit)
+		result, err := loadFromSource(explicit, lookupEnv)
 		if err == nil {
 			return result, nil
 		}
@@ -247,7 +548,7 @@ func LoadWithOptions(opts LoadOptions) (LoadResult, error) {
 		return LoadResult{}, err
 	}
 
-	return loadFromSource(source)
+	return loadFromSource(source, lookupEnv)
 }
 
 // Load reads the config from disk, falling back to defaults.
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -24,6 +26,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Ntfy.Priority != "high" {
 		t.Errorf("Ntfy.Priority: got %q, want %q", cfg.Ntfy.Priority, "high")
 	}
+	if !cfg.Ntfy.Cache || !cfg.Ntfy.Firebase {
+		t.Errorf("Ntfy.Cache/Firebase: got %v/%v, want true/true", cfg.Ntfy.Cache, cfg.Ntfy.Firebase)
+	}
 
 	// Discord
 	if cfg.Discord.Enabled != false {
@@ -50,6 +55,9 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Notification.SuppressWhenFocused != true {
 		t.Errorf("Notification.SuppressWhenFocused: got %v, want true", cfg.Notification.SuppressWhenFocused)
 	}
+	if cfg.Notification.ReplaceInPlace {
+		t.Errorf("Notification.ReplaceInPlace: got true, want false")
+	}
 
 	// Server
 	if cfg.Server.Address != "127.0.0.1:8228" {
@@ -88,7 +96,7 @@ func TestLoadFromBytes_EmptyData(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	want := DefaultConfig()
-	if cfg != want {
+	if !reflect.DeepEqual(cfg, want) {
 		t.Errorf("got %+v, want %+v", cfg, want)
 	}
 }
@@ -308,8 +316,8 @@ func TestLoadWithOptions_ExplicitPathFailureWarnsAndFallsBack(t *testing.T) {
 		t.Fatalf("warning missing explicit path details: %q", warnings[0])
 	}
 
-	if result.Source.Path != preferredPath {
-		t.Fatalf("resolved path = %q, want %q", result.Source.Path, preferredPath)
+	if result.Sources[0].Path != preferredPath {
+		t.Fatalf("resolved path = %q, want %q", result.Sources[0].Path, preferredPath)
 	}
 	if result.Config.Server.Address != "127.0.0.1:8444" {
 		t.Fatalf("server.address = %q, want %q", result.Config.Server.Address, "127.0.0.1:8444")
@@ -382,3 +390,522 @@ func TestValidate_RejectsNonPositiveLoggingRotationBounds(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate_Email(t *testing.T) {
+	valid := func() Config {
+		cfg := DefaultConfig()
+		cfg.Email.Enabled = true
+		cfg.Email.Host = "smtp.example.com"
+		cfg.Email.Username = "user"
+		cfg.Email.From = "ding@example.com"
+		cfg.Email.To = []string{"me@example.com"}
+		return cfg
+	}
+
+	if err := Validate(valid()); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+	local := valid()
+	local.Email.Host = "localhost"
+	local.Email.Security = "none"
+	if err := Validate(local); err != nil {
+		t.Errorf("Validate() error = %v, want password auth in clear allowed to localhost", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "missing host", mutate: func(cfg *Config) { cfg.Email.Host = "" }, want: "email.host"},
+		{name: "missing from", mutate: func(cfg *Config) { cfg.Email.From = "" }, want: "email.from"},
+		{name: "no recipients", mutate: func(cfg *Config) { cfg.Email.To = nil }, want: "email.to"},
+		{name: "bad port", mutate: func(cfg *Config) { cfg.Email.Port = 0 }, want: "email.port"},
+		{name: "bad security", mutate: func(cfg *Config) { cfg.Email.Security = "ssl" }, want: "email.security"},
+		{name: "bad auth", mutate: func(cfg *Config) { cfg.Email.Auth = "cram-md5" }, want: "email.auth"},
+		{name: "auth without username", mutate: func(cfg *Config) { cfg.Email.Username = "" }, want: "email.username"},
+		{name: "bad subject template", mutate: func(cfg *Config) { cfg.Email.Subject = "{{.Title" }, want: "email.subject"},
+		{name: "password auth in clear", mutate: func(cfg *Config) { cfg.Email.Security = "none" }, want: "email.auth plain needs email.security starttls or tls"},
+		{name: "header injection in from", mutate: func(cfg *Config) { cfg.Email.From = "ding@example.com\r\nBcc: evil@example.com" }, want: "email.from must not contain line breaks"},
+		{name: "header injection in to", mutate: func(cfg *Config) { cfg.Email.To = []string{"a@example.com\nBcc: evil@example.com"} }, want: "email.to must not contain line breaks"},
+		{name: "unparsable from", mutate: func(cfg *Config) { cfg.Email.From = "not an address" }, want: "email.from has invalid address"},
+		{name: "two recipients in one entry", mutate: func(cfg *Config) { cfg.Email.To = []string{"a@example.com, b@example.com"} }, want: "email.to has invalid address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil {
+				t.Fatal("Validate() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_TeamsRequiresWebhookURL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Teams.Enabled = true
+
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("Validate() error = nil, want error")
+	}
+	if !strings.Contains(err.Error(), "teams.webhook_url") {
+		t.Fatalf("error %q does not contain teams.webhook_url", err)
+	}
+}
+
+func TestValidate_NtfyReplyTopic(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ntfy.Topic = "agents"
+	cfg.Ntfy.ReplyTopic = "agents-replies_1"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	for topic, want := range map[string]string{
+		"agents":     "must differ from ntfy.topic",
+		"has/slash":  "ntfy.reply_topic must be",
+		"with space": "ntfy.reply_topic must be",
+	} {
+		cfg.Ntfy.ReplyTopic = topic
+		err := Validate(cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("reply_topic %q: error = %v, want %q", topic, err, want)
+		}
+	}
+}
+
+func TestValidate_NtfyReplyTokenMustDifferFromToken(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ntfy.Token = "tk_publish"
+	cfg.Ntfy.ReplyToken = "tk_publish"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "ntfy.reply_token must differ from ntfy.token") {
+		t.Errorf("Validate() error = %v, want reply_token rejected", err)
+	}
+	cfg.Ntfy.ReplyToken = "tk_reply"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestValidate_DiscordIdentityAndThread(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "reserved username", mutate: func(cfg *Config) { cfg.Discord.Username = "Discord Bot" }, want: "discord.username"},
+		{name: "long username", mutate: func(cfg *Config) { cfg.Discord.Username = strings.Repeat("x", 81) }, want: "discord.username"},
+		{name: "relative avatar", mutate: func(cfg *Config) { cfg.Discord.AvatarURL = "bell.png" }, want: "discord.avatar_url"},
+		{name: "agent identity", mutate: func(cfg *Config) {
+			cfg.Discord.Agents = map[string]DiscordIdentity{"claude": {Username: "clyde"}}
+		}, want: "discord.agents.claude.username"},
+		{name: "thread id", mutate: func(cfg *Config) { cfg.Discord.ThreadID = "general" }, want: "discord.thread_id"},
+	}
+
+	valid := DefaultConfig()
+	valid.Discord.Username = "ding-ding"
+	valid.Discord.AvatarURL = "https://example.com/bell.png"
+	valid.Discord.Agents = map[string]DiscordIdentity{"claude": {Username: "Claude"}}
+	valid.Discord.ThreadID = "1234567890"
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_NtfyExtras(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "token and basic auth", mutate: func(cfg *Config) {
+			cfg.Ntfy.Token = "tk_abc"
+			cfg.Ntfy.Username = "phil"
+		}, want: "mutually exclusive"},
+		{name: "password without username", mutate: func(cfg *Config) { cfg.Ntfy.Password = "secret" }, want: "ntfy.username is required"},
+		{name: "tag with comma", mutate: func(cfg *Config) { cfg.Ntfy.Tags = []string{"a,b"} }, want: "ntfy.tags"},
+		{name: "relative icon", mutate: func(cfg *Config) { cfg.Ntfy.Icon = "bell.png" }, want: "ntfy.icon"},
+		{name: "click scheme", mutate: func(cfg *Config) { cfg.Ntfy.Click = "file:///etc/passwd" }, want: "ntfy.click"},
+		{name: "email", mutate: func(cfg *Config) { cfg.Ntfy.Email = "phil" }, want: "ntfy.email"},
+		{name: "email list", mutate: func(cfg *Config) { cfg.Ntfy.Email = "a@b, evil@x" }, want: "ntfy.email"},
+		{name: "email line break", mutate: func(cfg *Config) { cfg.Ntfy.Email = "a@b\r\nBcc: evil@x" }, want: "ntfy.email must not contain line breaks"},
+		{name: "multi-line delay", mutate: func(cfg *Config) { cfg.Ntfy.Delay = "30m\nX-Evil: 1" }, want: "ntfy.delay"},
+	}
+
+	valid := DefaultConfig()
+	valid.Ntfy.Username = "phil"
+	valid.Ntfy.Password = "secret"
+	valid.Ntfy.Tags = []string{"robot", "warning"}
+	valid.Ntfy.Markdown = true
+	valid.Ntfy.Icon = "https://example.com/bell.png"
+	valid.Ntfy.Click = "https://example.com"
+	valid.Ntfy.Email = "phil@example.com"
+	valid.Ntfy.Delay = "tomorrow, 10am"
+	valid.Ntfy.Cache = false
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_MQTT(t *testing.T) {
+	valid := func() Config {
+		cfg := DefaultConfig()
+		cfg.MQTT.Enabled = true
+		cfg.MQTT.Broker = "tcp://broker.local:1883"
+		return cfg
+	}
+
+	if err := Validate(valid()); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "missing broker", mutate: func(cfg *Config) { cfg.MQTT.Broker = "" }, want: "mqtt.broker"},
+		{name: "unknown scheme", mutate: func(cfg *Config) { cfg.MQTT.Broker = "ws://broker.local" }, want: "mqtt.broker scheme"},
+		{name: "wildcard topic", mutate: func(cfg *Config) { cfg.MQTT.Topic = "ding/#" }, want: "mqtt.topic"},
+		{name: "qos 2", mutate: func(cfg *Config) { cfg.MQTT.QoS = 2 }, want: "mqtt.qos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil {
+				t.Fatal("Validate() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromBytes_PriorityMapMergesWithDefaults(t *testing.T) {
+	cfg, err := LoadFromBytes([]byte(`
+ntfy:
+  priority_map:
+    high: max
+discord:
+  priority_mentions:
+    urgent: ""
+    high: "<@&123>"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := cfg.Ntfy.PriorityMap["high"]; got != "max" {
+		t.Errorf("ntfy.priority_map.high: got %q, want %q", got, "max")
+	}
+	if got := cfg.Ntfy.PriorityMap["normal"]; got != "default" {
+		t.Errorf("ntfy.priority_map.normal: got %q, want default %q", got, "default")
+	}
+	if got := cfg.Discord.PriorityMentions["urgent"]; got != "" {
+		t.Errorf("discord.priority_mentions.urgent: got %q, want it cleared", got)
+	}
+	if got := cfg.Discord.PriorityMentions["high"]; got != "<@&123>" {
+		t.Errorf("discord.priority_mentions.high: got %q, want %q", got, "<@&123>")
+	}
+}
+
+func TestValidate_PriorityMaps(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "unknown priority key", mutate: func(cfg *Config) { cfg.Ntfy.PriorityMap["critical"] = "max" }, want: `ntfy.priority_map has unknown priority "critical"`},
+		{name: "invalid ntfy priority", mutate: func(cfg *Config) { cfg.Ntfy.PriorityMap["high"] = "loud" }, want: "ntfy.priority_map.high"},
+		{name: "invalid urgency", mutate: func(cfg *Config) { cfg.Notification.PriorityUrgency["urgent"] = "high" }, want: "notification.priority_urgency.urgent"},
+		{name: "invalid scenario", mutate: func(cfg *Config) { cfg.Notification.PriorityScenario["high"] = "popup" }, want: "notification.priority_scenario.high"},
+		{name: "invalid discord mention", mutate: func(cfg *Config) { cfg.Discord.PriorityMentions["urgent"] = "@channel" }, want: "discord.priority_mentions.urgent"},
+		{name: "unknown webhook key", mutate: func(cfg *Config) { cfg.Webhook.PriorityMap = map[string]string{"p1": "sev1"} }, want: "webhook.priority_map"},
+	}
+
+	if err := Validate(DefaultConfig()); err != nil {
+		t.Fatalf("Validate(DefaultConfig()) error = %v, want nil", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil {
+				t.Fatal("Validate() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAll_ReportsEveryProblemInASection(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MQTT.Enabled = true
+	cfg.MQTT.Broker = "http://broker:1883"
+	cfg.MQTT.Topic = "alerts/#"
+	cfg.MQTT.QoS = 2
+	cfg.Logging.Level = "loud"
+	cfg.Logging.MaxSizeMB = 0
+
+	var got []string
+	for _, err := range ValidateAll(cfg) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"mqtt.broker scheme must be one of tcp, mqtt, tls, ssl, mqtts",
+		"mqtt.topic must not contain wildcards",
+		"mqtt.qos must be 0 or 1",
+		"logging.level must be one of error, warn, info, debug",
+		"logging.max_size_mb must be greater than 0",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ValidateAll() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateAll_ReportsMapProblemsInKeyOrder(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ntfy.PriorityMap = map[string]string{"urgent": "a", "high": "b", "low": "c", "normal": "d", "p9": "max"}
+	cfg.Ntfy.Icon = "icon.png"
+	cfg.Ntfy.Click = "click"
+	cfg.Webhook.Headers = map[string]string{"X Two": "2", "X One": "1"}
+
+	want := []string{
+		"ntfy.icon must be an absolute http or https URL",
+		"ntfy.click must be an absolute http or https URL",
+		"ntfy.priority_map.high must be one of min, low, default, high, max, urgent, 1, 2, 3, 4, 5",
+		"ntfy.priority_map.low must be one of min, low, default, high, max, urgent, 1, 2, 3, 4, 5",
+		"ntfy.priority_map.normal must be one of min, low, default, high, max, urgent, 1, 2, 3, 4, 5",
+		`ntfy.priority_map has unknown priority "p9" (expected one of low, normal, high, urgent)`,
+		"ntfy.priority_map.urgent must be one of min, low, default, high, max, urgent, 1, 2, 3, 4, 5",
+		`webhook.headers has invalid header name "X One"`,
+		`webhook.headers has invalid header name "X Two"`,
+	}
+	for range 5 {
+		var got []string
+		for _, err := range ValidateAll(cfg) {
+			got = append(got, err.Error())
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("ValidateAll() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestValidate_DeliveryTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Notification.DeliveryTimeoutSeconds != 60 {
+		t.Fatalf("DeliveryTimeoutSeconds = %d, want 60", cfg.Notification.DeliveryTimeoutSeconds)
+	}
+
+	cfg.Notification.DeliveryTimeoutSeconds = 0
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() with no deadline error = %v, want nil", err)
+	}
+
+	cfg.Notification.DeliveryTimeoutSeconds = -1
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "notification.delivery_timeout_seconds") {
+		t.Fatalf("Validate() error = %v, want delivery_timeout_seconds error", err)
+	}
+}
+
+func TestValidate_Webhook(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Webhook.Format = "template"
+	valid.Webhook.Payload = "{{.Title}}: {{.Body}}"
+	valid.Webhook.Headers = map[string]string{"Authorization": "Bearer ${TOKEN}", "X-Api-Key": "file:/run/secrets/key"}
+	valid.Webhook.Secret = "env:WEBHOOK_SECRET"
+	valid.Webhook.SuccessStatus = []string{"202", "2xx"}
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "unknown format", mutate: func(cfg *Config) { cfg.Webhook.Format = "xml" }, want: "webhook.format"},
+		{name: "template without payload", mutate: func(cfg *Config) { cfg.Webhook.Format = "template" }, want: "webhook.payload is required"},
+		{name: "form with payload", mutate: func(cfg *Config) {
+			cfg.Webhook.Format = "form"
+			cfg.Webhook.Payload = `{"text": {{json .Body}}}`
+		}, want: "webhook.payload cannot be used"},
+		{name: "header name", mutate: func(cfg *Config) { cfg.Webhook.Headers = map[string]string{"X Bad": "1"} }, want: "webhook.headers"},
+		{name: "signature header", mutate: func(cfg *Config) {
+			cfg.Webhook.Secret = "s"
+			cfg.Webhook.SignatureHeader = ""
+		}, want: "webhook.signature_header"},
+		{name: "success status", mutate: func(cfg *Config) { cfg.Webhook.SuccessStatus = []string{"ok"} }, want: "webhook.success_status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPConfig_Merged(t *testing.T) {
+	skip, verify := true, false
+	global := HTTPConfig{Proxy: "http://proxy:3128", CAFile: "/etc/ca.pem", TimeoutSeconds: 15, ConnectTimeoutSeconds: 10}
+	override := HTTPConfig{CertFile: "client.pem", KeyFile: "client-key.pem", InsecureSkipVerify: &skip, TimeoutSeconds: 60}
+
+	got := global.Merged(override)
+	want := HTTPConfig{
+		Proxy:                 "http://proxy:3128",
+		CAFile:                "/etc/ca.pem",
+		CertFile:              "client.pem",
+		KeyFile:               "client-key.pem",
+		InsecureSkipVerify:    &skip,
+		TimeoutSeconds:        60,
+		ConnectTimeoutSeconds: 10,
+	}
+	if got != want {
+		t.Fatalf("Merged() = %+v, want %+v", got, want)
+	}
+	if again := global.Merged(got); again != got {
+		t.Fatalf("Merged() is not idempotent: %+v", again)
+	}
+
+	global.InsecureSkipVerify = &skip
+	if !global.Merged(HTTPConfig{}).SkipVerify() {
+		t.Error("Merged() without an override dropped the global insecure_skip_verify")
+	}
+	if global.Merged(HTTPConfig{InsecureSkipVerify: &verify}).SkipVerify() {
+		t.Error("Merged() ignored a backend's insecure_skip_verify: false")
+	}
+
+	cfg := DefaultConfig()
+	cfg.HTTP.Proxy = "http://proxy:3128"
+	cfg.Webhook.HTTP.TimeoutSeconds = 60
+	cfg = cfg.WithBackendHTTP()
+	if cfg.Ntfy.HTTP.Proxy != "http://proxy:3128" || cfg.Ntfy.HTTP.TimeoutSeconds != 15 {
+		t.Errorf("ntfy.http = %+v, want the global section", cfg.Ntfy.HTTP)
+	}
+	if cfg.Webhook.HTTP.Proxy != "http://proxy:3128" || cfg.Webhook.HTTP.TimeoutSeconds != 60 {
+		t.Errorf("webhook.http = %+v, want global proxy with its own timeout", cfg.Webhook.HTTP)
+	}
+}
+
+func TestValidate_HTTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "proxy scheme", mutate: func(cfg *Config) { cfg.HTTP.Proxy = "ftp://proxy:21" }, want: "http.proxy scheme"},
+		{name: "proxy host", mutate: func(cfg *Config) { cfg.Ntfy.HTTP.Proxy = "proxy:3128" }, want: "ntfy.http.proxy"},
+		{name: "cert without key", mutate: func(cfg *Config) { cfg.Webhook.HTTP.CertFile = "client.pem" }, want: "webhook.http.cert_file and webhook.http.key_file"},
+		{name: "negative timeout", mutate: func(cfg *Config) { cfg.Discord.HTTP.TimeoutSeconds = -1 }, want: "discord.http.timeout_seconds"},
+		{name: "negative connect timeout", mutate: func(cfg *Config) { cfg.Teams.HTTP.ConnectTimeoutSeconds = -1 }, want: "teams.http.connect_timeout_seconds"},
+	}
+
+	valid := DefaultConfig()
+	valid.HTTP.Proxy = "socks5://127.0.0.1:1080"
+	skip := true
+	valid.Webhook.HTTP = HTTPConfig{CertFile: "client.pem", KeyFile: "client-key.pem", InsecureSkipVerify: &skip}
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_Templates(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Discord.Template.Body = "{{.Agent | default \"agent\"}} {{truncate 100 .Body}} on {{.Hostname}} ({{.Tier}})"
+	valid.Webhook.Payload = `{"text": {{json .Body}}, "at": {{json .Timestamp}}, "exit": {{json .ExitCode}}}`
+	valid.Ntfy.Template.Body = "{{.Body}}{{with .ExitCode}} (exit {{.}}){{end}}{{with .Duration}} in {{.}}{{end}}"
+	valid.Teams.Template.Body = "{{.Body}}{{if .URL}} {{.URL}}{{end}}{{range .Actions}} [{{.Label}}]({{.URL}}){{end}}{{range .Replies}} {{.}}{{end}}"
+	if err := Validate(valid); err != nil {
+		t.Fatalf("Validate() error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "parse error", mutate: func(cfg *Config) { cfg.Ntfy.Template.Title = "{{.Title" }, want: "ntfy.template.title is not a valid template"},
+		{name: "unknown field", mutate: func(cfg *Config) { cfg.Notification.Template.Body = "{{.Titel}}" }, want: "notification.template.body is not a valid template"},
+		{name: "unknown function", mutate: func(cfg *Config) { cfg.Teams.Template.Body = "{{shout .Body}}" }, want: "teams.template.body"},
+		{name: "payload not json", mutate: func(cfg *Config) { cfg.Webhook.Payload = `{"text": {{.Body}}}` }, want: "webhook.payload must render valid JSON"},
+		{name: "email subject unknown field", mutate: func(cfg *Config) { cfg.Email.Subject = "{{.Subjct}}" }, want: "email.subject"},
+		{name: "unset exit code printed", mutate: func(cfg *Config) { cfg.Ntfy.Template.Body = "{{.Body}} (exit {{.ExitCode}})" }, want: "ntfy.template.body renders <no value> when ExitCode or Duration is unset"},
+		{name: "unset exit code formatted", mutate: func(cfg *Config) { cfg.Discord.Template.Body = `{{printf "%d" .ExitCode}}` }, want: "discord.template.body renders <nil>"},
+		{name: "unset duration method", mutate: func(cfg *Config) { cfg.Teams.Template.Title = "{{.Duration.Minutes}}" }, want: "teams.template.title is not a valid template when ExitCode or Duration is unset"},
+		{name: "unknown action field", mutate: func(cfg *Config) { cfg.MQTT.Template.Body = "{{range .Actions}}{{.Link}}{{end}}" }, want: "mqtt.template.body is not a valid template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Email.Enabled = true
+			cfg.Email.Host = "smtp.example.com"
+			cfg.Email.From = "a@example.com"
+			cfg.Email.To = []string{"b@example.com"}
+			cfg.Email.Username = "a"
+			tt.mutate(&cfg)
+			err := Validate(cfg)
+			if err == nil {
+				t.Fatal("Validate() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// config field.
+const EnvPrefix = "DING_DING_"
+
+// EnvVar returns the environment variable that overrides the field at a
+// dotted path: "ntfy.token" is DING_DING_NTFY_TOKEN.
+func EnvVar(field string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(field, ".", "_"))
+}
+
+// applyEnv overrides the fields of cfg that have an environment variable set
+// and returns their dotted paths. Lists are comma-separated and string maps
+// are comma-separated key=value pairs; both replace the configured value.
+// Maps of sections, such as discord.agents, cannot be set from the
+// environment.
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) ([]string, error) {
+	var fields []string
+	err := walkEnvFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.Value) error {
+		raw, ok := lookup(EnvVar(path))
+		if !ok {
+			return nil
+		}
+		if err := setFromEnv(field, raw); err != nil {
+			return fmt.Errorf("%s: %w", EnvVar(path), err)
+		}
+		fields = append(fields, path)
+		return nil
+	})
+	return fields, err
+}
+
+// walkEnvFields calls fn for every field below v that can be set from a
+// single environment variable.
+func walkEnvFields(v reflect.Value, path string, fn func(path string, field reflect.Value) error) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		field := v.Field(i)
+		fieldPath := joinPath(path, yamlName(t.Field(i)))
+		switch field.Kind() {
+		case reflect.Struct:
+			if err := walkEnvFields(field, fieldPath, fn); err != nil {
+				return err
+			}
+		case reflect.Map:
+			if field.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			if err := fn(fieldPath, field); err != nil {
+				return err
+			}
+		default:
+			if err := fn(fieldPath, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setFromEnv(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Pointer:
+		value := reflect.New(field.Type().Elem())
+		if err := setFromEnv(value.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(value)
+	case reflect.Map:
+		m := map[string]string{}
+		for _, pair := range strings.Split(raw, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return fmt.Errorf("want key=value pairs, got %q", pair)
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		field.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
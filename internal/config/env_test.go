@@ -0,0 +1,153 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	tests := map[string]string{
+		"ntfy.token":                 "DING_DING_NTFY_TOKEN",
+		"discord.webhook_url":        "DING_DING_DISCORD_WEBHOOK_URL",
+		"idle.threshold_seconds":     "DING_DING_IDLE_THRESHOLD_SECONDS",
+		"notification.template.body": "DING_DING_NOTIFICATION_TEMPLATE_BODY",
+	}
+	for field, want := range tests {
+		if got := EnvVar(field); got != want {
+			t.Errorf("EnvVar(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestLoadWithOptions_EnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("ntfy:\n  enabled: true\n  topic: from-file\n  token: file-token\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	result, err := LoadWithOptions(LoadOptions{
+		ExplicitPath: path,
+		LookupEnv: lookupFrom(map[string]string{
+			"DING_DING_NTFY_TOKEN":                     "env-token",
+			"DING_DING_NTFY_TAGS":                      "robot, ci",
+			"DING_DING_IDLE_THRESHOLD_SECONDS":         "60",
+			"DING_DING_SOUND_ENABLED":                  "false",
+			"DING_DING_WEBHOOK_HEADERS":                "Authorization=Bearer abc,X-Team=ops",
+			"DING_DING_NTFY_HTTP_INSECURE_SKIP_VERIFY": "false",
+		}),
+	})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+
+	cfg := result.Config
+	if cfg.Ntfy.Topic != "from-file" || cfg.Ntfy.Token != "env-token" {
+		t.Errorf("ntfy topic/token = %q/%q, want from-file/env-token", cfg.Ntfy.Topic, cfg.Ntfy.Token)
+	}
+	if !slices.Equal(cfg.Ntfy.Tags, []string{"robot", "ci"}) {
+		t.Errorf("ntfy.tags = %v, want [robot ci]", cfg.Ntfy.Tags)
+	}
+	if cfg.Idle.ThresholdSeconds != 60 || cfg.Sound.Enabled {
+		t.Errorf("idle.threshold_seconds = %d, sound.enabled = %v", cfg.Idle.ThresholdSeconds, cfg.Sound.Enabled)
+	}
+	wantHeaders := map[string]string{"Authorization": "Bearer abc", "X-Team": "ops"}
+	if !maps.Equal(cfg.Webhook.Headers, wantHeaders) {
+		t.Errorf("webhook.headers = %v, want %v", cfg.Webhook.Headers, wantHeaders)
+	}
+
+	if skip := cfg.Ntfy.HTTP.InsecureSkipVerify; skip == nil || *skip {
+		t.Errorf("ntfy.http.insecure_skip_verify = %v, want explicit false", skip)
+	}
+
+	wantFields := []string{"ntfy.token", "ntfy.tags", "ntfy.http.insecure_skip_verify", "webhook.headers", "idle.threshold_seconds", "sound.enabled"}
+	if !slices.Equal(result.EnvFields, wantFields) {
+		t.Errorf("EnvFields = %v, want %v", result.EnvFields, wantFields)
+	}
+}
+
+func TestLoadWithOptions_EnvWithoutFile(t *testing.T) {
+	dir := t.TempDir()
+	result, err := LoadWithOptions(LoadOptions{
+		Resolve: ResolveOptions{
+			GOOS:          "linux",
+			PreferredPath: filepath.Join(dir, "missing.yaml"),
+		},
+		LookupEnv: lookupFrom(map[string]string{
+			"DING_DING_DISCORD_ENABLED":     "true",
+			"DING_DING_DISCORD_WEBHOOK_URL": "https://discord.com/api/webhooks/1/abc",
+		}),
+	})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+	if result.Sources[0].Type != SourceDefaults {
+		t.Errorf("source = %q, want defaults", result.Sources[0].Type)
+	}
+	if !result.Config.Discord.Enabled || result.Config.Discord.WebhookURL == "" {
+		t.Errorf("discord not configured from env: %+v", result.Config.Discord)
+	}
+}
+
+func TestLoadWithOptions_EnvErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "bad integer",
+			env:  map[string]string{"DING_DING_IDLE_THRESHOLD_SECONDS": "five"},
+			want: `DING_DING_IDLE_THRESHOLD_SECONDS: invalid integer "five"`,
+		},
+		{
+			name: "bad boolean",
+			env:  map[string]string{"DING_DING_NTFY_ENABLED": "maybe"},
+			want: `DING_DING_NTFY_ENABLED: invalid boolean "maybe"`,
+		},
+		{
+			name: "bad map entry",
+			env:  map[string]string{"DING_DING_NTFY_PRIORITY_MAP": "high"},
+			want: `DING_DING_NTFY_PRIORITY_MAP: want key=value pairs`,
+		},
+		{
+			name: "invalid result",
+			env:  map[string]string{"DING_DING_LOGGING_LEVEL": "loud"},
+			want: "with environment overrides: logging.level must be one of",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadWithOptions(LoadOptions{
+				Resolve:   ResolveOptions{GOOS: "linux", PreferredPath: filepath.Join(dir, "missing.yaml")},
+				LookupEnv: lookupFrom(tt.env),
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadWithOptions() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotate_MapFieldFromEnv(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Webhook.Headers = map[string]string{"X-Team": "ops"}
+	out, err := Annotate(cfg, map[string]string{"webhook.headers": FieldEnv})
+	if err != nil {
+		t.Fatalf("Annotate() error = %v", err)
+	}
+	if want := "    X-Team: '[REDACTED]' # env\n"; !strings.Contains(string(out), want) {
+		t.Errorf("Annotate() missing %q:\n%s", want, out)
+	}
+}
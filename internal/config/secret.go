@@ -0,0 +1,23 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// SecretSchemes are the reference prefixes that secret-valued fields accept
+// in place of a literal value, e.g. "env:NTFY_TOKEN" or
+// "keyring:ding-ding/ntfy". References are resolved when a message is sent.
+var SecretSchemes = []string{"env", "file", "cmd", "keyring"}
+
+// LiteralPrefix marks the rest of a secret-valued field as the secret
+// itself, for values that would otherwise read as a reference:
+// "literal:env:abc" is the value "env:abc".
+const LiteralPrefix = "literal:"
+
+// IsSecretReference reports whether value refers to a secret stored
+// elsewhere rather than holding it.
+func IsSecretReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	return ok && slices.Contains(SecretSchemes, scheme)
+}
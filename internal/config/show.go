@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue replaces secret values in shown config.
+const RedactedValue = "[REDACTED]"
+
+// Field sources reported by Annotate.
+const (
+	FieldDefault = "default"
+	FieldFile    = "file"
+	FieldEnv     = "env"
+	FieldProject = "project"
+)
+
+// FileFields lists the dotted paths of the values a config file sets, such
+// as "ntfy.topic" or "webhook.headers.Authorization".
+func FileFields(data []byte) ([]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	var fields []string
+	if doc := documentRoot(&root); doc != nil {
+		walkLeaves(doc, reflect.TypeFor[Config](), "", false, func(path string, _, _ *yaml.Node, _ bool) {
+			fields = append(fields, path)
+		})
+	}
+	return fields, nil
+}
+
+// Annotate renders cfg as YAML with every value commented with where it came
+// from. Secrets are redacted unless they are references such as "env:NAME",
+// which do not hold the secret itself. sources maps a dotted field path to
+// its source, which also covers the entries of a map field; fields not
+// listed are defaults.
+func Annotate(cfg Config, sources map[string]string) ([]byte, error) {
+	var root yaml.Node
+	if err := root.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	walkLeaves(&root, reflect.TypeFor[Config](), "", false, func(path string, key, value *yaml.Node, secret bool) {
+		if secret && value.Kind == yaml.ScalarNode && value.Value != "" && !IsSecretReference(value.Value) {
+			value.SetString(RedactedValue)
+		}
+		source := cmp.Or(sourceOf(sources, path), FieldDefault)
+		if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+			value.LineComment = source
+		} else {
+			key.LineComment = source
+		}
+	})
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sourceOf looks up the source of path, or of the nearest field containing
+// it.
+func sourceOf(sources map[string]string, path string) string {
+	for {
+		if source, ok := sources[path]; ok {
+			return source
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return ""
+		}
+		path = path[:i]
+	}
+}
+
+// walkLeaves calls fn for every value of a mapping that is not itself a
+// non-empty mapping, with its dotted path. secret is set below fields
+// tagged secret:"true".
+func walkLeaves(node *yaml.Node, t reflect.Type, path string, secret bool, fn func(path string, key, value *yaml.Node, secret bool)) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := joinPath(path, key.Value)
+
+		var childType reflect.Type
+		childSecret := secret
+		switch {
+		case t == nil:
+		case t.Kind() == reflect.Struct:
+			if field, ok := yamlField(t, key.Value); ok {
+				childType = field.Type
+				childSecret = secret || field.Tag.Get("secret") == "true"
+			}
+		case t.Kind() == reflect.Map:
+			childType = t.Elem()
+		}
+
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			walkLeaves(value, childType, keyPath, childSecret, fn)
+			continue
+		}
+		fn(keyPath, key, value, childSecret)
+	}
+}
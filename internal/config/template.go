@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// TemplateConfig holds optional text/template overrides for a backend's
+// title and body. Empty templates keep the backend's built-in format.
+type TemplateConfig struct {
+	Title string `yaml:"title"`
+	Body  string `yaml:"body"`
+}
+
+// TemplateFields lists the top-level fields available to message templates.
+// The notifier builds its template data with exactly these keys.
+var TemplateFields = []string{
+	"Title", "Body", "Agent", "PID", "RequestID", "OperationID", "Session",
+	"Event", "ExitCode", "Duration", "Priority", "URL", "Actions", "Replies",
+	"Hostname", "Cwd", "Repo", "Branch", "Timestamp", "Tier",
+}
+
+// templateAction mirrors the notifier's Action, so sample data supports the
+// same field access and json encoding as a real message.
+type templateAction struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// templateFuncs are available to every message template.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	// truncate shortens s to at most n runes, ending in "…" when cut.
+	"truncate": func(n int, s string) string {
+		if n <= 0 || utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		runes := []rune(s)
+		return string(runes[:n-1]) + "…"
+	},
+	// json encodes v as a JSON value, e.g. a quoted and escaped string.
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// default returns fallback when value is empty.
+	"default": func(fallback, value any) any {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// ParseMessageTemplate parses a message template with the shared helper
+// functions. Referencing a field that does not exist fails at execution.
+func ParseMessageTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// checkMessageTemplate parses text and executes it against sample data, so
+// typos in field names are reported at load time instead of on first send.
+func checkMessageTemplate(field, text string) error {
+	_, err := renderSampleTemplate(field, text)
+	return err
+}
+
+func renderSampleTemplate(field, text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := ParseMessageTemplate(field, text)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a valid template: %w", field, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, sampleTemplateData()); err != nil {
+		return "", fmt.Errorf("%s is not a valid template: %w", field, err)
+	}
+
+	// Most messages carry no exit code or duration, so the template must
+	// also render cleanly without them.
+	sparse := sampleTemplateData()
+	sparse["ExitCode"] = nil
+	sparse["Duration"] = nil
+	var sparseOut strings.Builder
+	if err := tmpl.Execute(&sparseOut, sparse); err != nil {
+		return "", fmt.Errorf("%s is not a valid template when ExitCode or Duration is unset: %w", field, err)
+	}
+	for _, marker := range []string{"<no value>", "<nil>"} {
+		if strings.Contains(sparseOut.String(), marker) && !strings.Contains(text, marker) {
+			return "", fmt.Errorf("%s renders %s when ExitCode or Duration is unset (wrap them in {{with}})", field, marker)
+		}
+	}
+	return out.String(), nil
+}
+
+func sampleTemplateData() map[string]any {
+	return map[string]any{
+		"Title":       "ding ding!",
+		"Body":        "Task finished",
+		"Agent":       "claude",
+		"PID":         1234,
+		"RequestID":   "req",
+		"OperationID": "op",
+		"Session":     "session",
+		"Event":       "completed",
+		"ExitCode":    0,
+		"Duration":    3*time.Minute + 12*time.Second,
+		"Priority":    "normal",
+		"URL":         "https://example.com/runs/1",
+		"Actions":     []templateAction{{Label: "Open PR", URL: "https://example.com/pull/1"}},
+		"Replies":     []string{"yes", "no"},
+		"Hostname":    "localhost",
+		"Cwd":         "/src/ding-ding",
+		"Repo":        "ding-ding",
+		"Branch":      "main",
+		"Timestamp":   time.Unix(0, 0),
+		"Tier":        "idle",
+	}
+}
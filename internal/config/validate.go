@@ -1,59 +1,493 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
-// Validate enforces required values for enabled integrations.
+// Validate enforces required values for enabled integrations and returns
+// the first problem found.
 func Validate(cfg Config) error {
+	if problems := ValidateAll(cfg); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// ValidateAll runs every check and returns all problems, in config order.
+func ValidateAll(cfg Config) []error {
+	var problems []error
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
 	if cfg.Ntfy.Enabled {
 		if cfg.Ntfy.Server == "" {
-			return fmt.Errorf("ntfy.server is required when ntfy.enabled is true")
+			check(fmt.Errorf("ntfy.server is required when ntfy.enabled is true"))
 		}
 		if cfg.Ntfy.Topic == "" {
-			return fmt.Errorf("ntfy.topic is required when ntfy.enabled is true")
+			check(fmt.Errorf("ntfy.topic is required when ntfy.enabled is true"))
 		}
 	}
+	if topic := cfg.Ntfy.ReplyTopic; topic != "" {
+		switch {
+		case !ntfyTopicPattern.MatchString(topic):
+			check(fmt.Errorf("ntfy.reply_topic must be 1-64 letters, digits, '-' or '_'"))
+		case topic == cfg.Ntfy.Topic:
+			check(fmt.Errorf("ntfy.reply_topic must differ from ntfy.topic"))
+		}
+	}
+	if cfg.Ntfy.ReplyToken != "" && cfg.Ntfy.ReplyToken == cfg.Ntfy.Token {
+		check(fmt.Errorf("ntfy.reply_token must differ from ntfy.token: reply buttons publish it in the message"))
+	}
+	problems = append(problems, validateNtfyExtras(cfg.Ntfy)...)
+	problems = append(problems, validatePriorityMap("ntfy.priority_map", cfg.Ntfy.PriorityMap,
+		"min", "low", "default", "high", "max", "urgent", "1", "2", "3", "4", "5")...)
 
 	if cfg.Discord.Enabled && cfg.Discord.WebhookURL == "" {
-		return fmt.Errorf("discord.webhook_url is required when discord.enabled is true")
+		check(fmt.Errorf("discord.webhook_url is required when discord.enabled is true"))
+	}
+	problems = append(problems, validateDiscordMentions(cfg.Discord.PriorityMentions)...)
+	problems = append(problems, validateDiscordIdentity("discord", DiscordIdentity{Username: cfg.Discord.Username, AvatarURL: cfg.Discord.AvatarURL})...)
+	for _, agent := range slices.Sorted(maps.Keys(cfg.Discord.Agents)) {
+		problems = append(problems, validateDiscordIdentity("discord.agents."+agent, cfg.Discord.Agents[agent])...)
+	}
+	if id := cfg.Discord.ThreadID; id != "" && !discordSnowflakePattern.MatchString(id) {
+		check(fmt.Errorf("discord.thread_id must be a numeric Discord ID"))
 	}
 
 	if cfg.Webhook.Enabled && cfg.Webhook.URL == "" {
-		return fmt.Errorf("webhook.url is required when webhook.enabled is true")
+		check(fmt.Errorf("webhook.url is required when webhook.enabled is true"))
+	}
+	problems = append(problems, validatePriorityMap("webhook.priority_map", cfg.Webhook.PriorityMap)...)
+	problems = append(problems, validateWebhook(cfg.Webhook)...)
+
+	problems = append(problems, validateEmail(cfg.Email)...)
+
+	if cfg.Teams.Enabled && cfg.Teams.WebhookURL == "" {
+		check(fmt.Errorf("teams.webhook_url is required when teams.enabled is true"))
+	}
+
+	problems = append(problems, validateMQTT(cfg.MQTT)...)
+
+	httpSections := []struct {
+		field string
+		http  HTTPConfig
+	}{
+		{"http", cfg.HTTP},
+		{"ntfy.http", cfg.Ntfy.HTTP},
+		{"discord.http", cfg.Discord.HTTP},
+		{"webhook.http", cfg.Webhook.HTTP},
+		{"teams.http", cfg.Teams.HTTP},
+	}
+	for _, section := range httpSections {
+		problems = append(problems, validateHTTP(section.field, section.http)...)
 	}
 
+	switch cfg.Idle.FallbackPolicy {
+	case "active", "idle":
+		// valid
+	default:
+		check(fmt.Errorf("idle.fallback_policy must be one of active, idle"))
+	}
+
+	problems = append(problems, validateNotification(cfg.Notification)...)
+	problems = append(problems, validateTemplates(cfg)...)
+
 	if cfg.Server.Address == "" {
-		return fmt.Errorf("server.address is required")
+		check(fmt.Errorf("server.address is required"))
+	}
+
+	problems = append(problems, validateLogging(cfg.Logging)...)
+
+	return problems
+}
+
+func validateNotification(notification NotificationConfig) []error {
+	var problems []error
+	switch strings.ToLower(strings.TrimSpace(notification.Urgency)) {
+	case "", "low", "normal", "critical":
+		// valid
+	default:
+		problems = append(problems, fmt.Errorf("notification.urgency must be one of low, normal, critical"))
+	}
+
+	if notification.DeliveryTimeoutSeconds < 0 {
+		problems = append(problems, fmt.Errorf("notification.delivery_timeout_seconds must be 0 (no deadline) or positive"))
 	}
 
-	if err := validateLogging(cfg.Logging); err != nil {
-		return err
+	if notification.ExpireTimeoutMs < -1 {
+		problems = append(problems, fmt.Errorf("notification.expire_timeout_ms must be -1 (server default), 0 (never) or positive"))
 	}
 
+	problems = append(problems, validatePriorityMap("notification.priority_urgency", notification.PriorityUrgency,
+		"low", "normal", "critical")...)
+	problems = append(problems, validatePriorityMap("notification.priority_scenario", notification.PriorityScenario,
+		"", "default", "reminder", "alarm", "incomingCall", "urgent")...)
+
+	return problems
+}
+
+func validateTemplates(cfg Config) []error {
+	var problems []error
+	templates := []struct {
+		section string
+		tmpl    TemplateConfig
+	}{
+		{"ntfy", cfg.Ntfy.Template},
+		{"discord", cfg.Discord.Template},
+		{"webhook", cfg.Webhook.Template},
+		{"email", cfg.Email.Template},
+		{"teams", cfg.Teams.Template},
+		{"mqtt", cfg.MQTT.Template},
+		{"notification", cfg.Notification.Template},
+	}
+	for _, t := range templates {
+		if err := checkMessageTemplate(t.section+".template.title", t.tmpl.Title); err != nil {
+			problems = append(problems, err)
+		}
+		if err := checkMessageTemplate(t.section+".template.body", t.tmpl.Body); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	payload, err := renderSampleTemplate("webhook.payload", cfg.Webhook.Payload)
+	switch {
+	case err != nil:
+		problems = append(problems, err)
+	case payload != "" && WebhookFormat(cfg.Webhook.Format) == "json" && !json.Valid([]byte(payload)):
+		problems = append(problems, fmt.Errorf("webhook.payload must render valid JSON (use {{json .Field}} to quote values)"))
+	}
+
+	return problems
+}
+
+// messagePriorities are the keys accepted by the per-backend priority maps.
+var messagePriorities = []string{"low", "normal", "high", "urgent"}
+
+// validatePriorityMap checks that every key is a message priority and, when
+// allowed values are given, that every value is one of them.
+func validatePriorityMap(field string, m map[string]string, allowed ...string) []error {
+	var problems []error
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		value := m[key]
+		switch {
+		case !slices.Contains(messagePriorities, key):
+			problems = append(problems, fmt.Errorf("%s has unknown priority %q (expected one of %s)", field, key, strings.Join(messagePriorities, ", ")))
+		case len(allowed) > 0 && !slices.Contains(allowed, value):
+			problems = append(problems, fmt.Errorf("%s.%s must be one of %s", field, key, strings.Join(nonEmpty(allowed), ", ")))
+		}
+	}
+	return problems
+}
+
+// validateNtfyExtras checks authentication and the optional publish
+// features of the ntfy section.
+func validateNtfyExtras(ntfy NtfyConfig) []error {
+	var problems []error
+	switch {
+	case ntfy.Token != "" && (ntfy.Username != "" || ntfy.Password != ""):
+		problems = append(problems, fmt.Errorf("ntfy.token and ntfy.username/password are mutually exclusive"))
+	case ntfy.Password != "" && ntfy.Username == "":
+		problems = append(problems, fmt.Errorf("ntfy.username is required when ntfy.password is set"))
+	}
+	for _, tag := range ntfy.Tags {
+		if tag == "" || strings.Contains(tag, ",") {
+			problems = append(problems, fmt.Errorf("ntfy.tags entries must be non-empty without commas"))
+			break
+		}
+	}
+	links := []struct {
+		field string
+		link  string
+	}{
+		{"ntfy.icon", ntfy.Icon},
+		{"ntfy.click", ntfy.Click},
+	}
+	for _, l := range links {
+		if l.link == "" {
+			continue
+		}
+		u, err := url.Parse(l.link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Errorf("%s must be an absolute http or https URL", l.field))
+		}
+	}
+	if ntfy.Email != "" {
+		if err := checkEmailAddress("ntfy.email", ntfy.Email); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if strings.ContainsAny(ntfy.Delay, "\r\n") {
+		problems = append(problems, fmt.Errorf("ntfy.delay must be a single line such as 30m or \"tomorrow, 10am\""))
+	}
+	return problems
+}
+
+func validateDiscordMentions(mentions map[string]string) []error {
+	problems := validatePriorityMap("discord.priority_mentions", mentions)
+	for _, key := range slices.Sorted(maps.Keys(mentions)) {
+		mention := mentions[key]
+		switch {
+		case !slices.Contains(messagePriorities, key):
+			// reported above
+		case mention == "", mention == "@here", mention == "@everyone":
+			// valid
+		case discordMentionPattern.MatchString(mention):
+			// valid
+		default:
+			problems = append(problems, fmt.Errorf("discord.priority_mentions.%s must be @here, @everyone, <@user_id> or <@&role_id>", key))
+		}
+	}
+	return problems
+}
+
+// validateDiscordIdentity applies Discord's webhook username rules: at most
+// 80 characters and no "discord" or "clyde".
+func validateDiscordIdentity(field string, identity DiscordIdentity) []error {
+	var problems []error
+	if name := identity.Username; name != "" {
+		lower := strings.ToLower(name)
+		if utf8.RuneCountInString(name) > 80 || strings.Contains(lower, "discord") || strings.Contains(lower, "clyde") {
+			problems = append(problems, fmt.Errorf("%s.username must be at most 80 characters and not contain \"discord\" or \"clyde\"", field))
+		}
+	}
+	if avatar := identity.AvatarURL; avatar != "" {
+		u, err := url.Parse(avatar)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Errorf("%s.avatar_url must be an absolute http or https URL", field))
+		}
+	}
+	return problems
+}
+
+var discordMentionPattern = regexp.MustCompile(`^<@&?[0-9]+>$`)
+
+var discordSnowflakePattern = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+// ntfyTopicPattern matches the topic names ntfy accepts.
+var ntfyTopicPattern = regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`)
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func validateWebhook(webhook WebhookConfig) []error {
+	var problems []error
+	switch WebhookFormat(webhook.Format) {
+	case "json":
+		// valid
+	case "form":
+		if webhook.Payload != "" {
+			problems = append(problems, fmt.Errorf("webhook.payload cannot be used with webhook.format form"))
+		}
+	case "template":
+		if webhook.Payload == "" {
+			problems = append(problems, fmt.Errorf("webhook.payload is required when webhook.format is template"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("webhook.format must be one of json, form, template"))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(webhook.Headers)) {
+		if !httpHeaderNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Errorf("webhook.headers has invalid header name %q", name))
+		}
+	}
+	if webhook.Secret != "" && !httpHeaderNamePattern.MatchString(webhook.SignatureHeader) {
+		problems = append(problems, fmt.Errorf("webhook.signature_header must be a header name when webhook.secret is set"))
+	}
+
+	for _, status := range webhook.SuccessStatus {
+		if !webhookStatusPattern.MatchString(status) {
+			problems = append(problems, fmt.Errorf("webhook.success_status entries must be status codes like 202 or classes like 2xx"))
+			break
+		}
+	}
+	return problems
+}
+
+// WebhookFormat normalizes webhook.format; empty means json.
+func WebhookFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		return "json"
+	}
+	return format
+}
+
+var httpHeaderNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+var webhookStatusPattern = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// IsLocalSMTPHost reports whether host is one that net/smtp lets
+// authenticate over an unencrypted connection.
+func IsLocalSMTPHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func validateEmail(email EmailConfig) []error {
+	if !email.Enabled {
+		return nil
+	}
+
+	var problems []error
+	if email.Host == "" {
+		problems = append(problems, fmt.Errorf("email.host is required when email.enabled is true"))
+	}
+	if email.Port <= 0 || email.Port > 65535 {
+		problems = append(problems, fmt.Errorf("email.port must be between 1 and 65535"))
+	}
+	if email.From == "" {
+		problems = append(problems, fmt.Errorf("email.from is required when email.enabled is true"))
+	} else if err := checkEmailAddress("email.from", email.From); err != nil {
+		problems = append(problems, err)
+	}
+	if len(email.To) == 0 {
+		problems = append(problems, fmt.Errorf("email.to must list at least one recipient when email.enabled is true"))
+	}
+	for _, rcpt := range email.To {
+		if err := checkEmailAddress("email.to", rcpt); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(email.Security)) {
+	case "starttls", "tls", "none":
+		// valid
+	default:
+		problems = append(problems, fmt.Errorf("email.security must be one of starttls, tls, none"))
+	}
+
+	switch strings.ToLower(strings.TrimSpace(email.Auth)) {
+	case "plain", "login":
+		if email.Username == "" {
+			problems = append(problems, fmt.Errorf("email.username is required when email.auth is %s", email.Auth))
+		}
+		// Go's SMTP auth refuses to send a password in clear to anything
+		// but localhost, so the send would always fail.
+		if strings.EqualFold(strings.TrimSpace(email.Security), "none") && !IsLocalSMTPHost(email.Host) {
+			problems = append(problems, fmt.Errorf("email.auth %s needs email.security starttls or tls unless email.host is localhost", email.Auth))
+		}
+	case "none", "":
+		// valid
+	default:
+		problems = append(problems, fmt.Errorf("email.auth must be one of plain, login, none"))
+	}
+
+	if err := checkMessageTemplate("email.subject", email.Subject); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// checkEmailAddress accepts one address, bare or as "Name <addr>". Line
+// breaks are refused outright since the value ends up in a message header.
+func checkEmailAddress(field, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s must not contain line breaks", field)
+	}
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("%s has invalid address %q: %v", field, value, err)
+	}
 	return nil
 }
 
-func validateLogging(logging LoggingConfig) error {
+func validateMQTT(mqtt MQTTConfig) []error {
+	if !mqtt.Enabled {
+		return nil
+	}
+
+	var problems []error
+	if mqtt.Broker == "" {
+		problems = append(problems, fmt.Errorf("mqtt.broker is required when mqtt.enabled is true"))
+	} else if broker, err := url.Parse(mqtt.Broker); err != nil || broker.Host == "" {
+		problems = append(problems, fmt.Errorf("mqtt.broker must be a URL such as tcp://host:1883"))
+	} else {
+		switch broker.Scheme {
+		case "tcp", "mqtt", "tls", "ssl", "mqtts":
+			// valid
+		default:
+			problems = append(problems, fmt.Errorf("mqtt.broker scheme must be one of tcp, mqtt, tls, ssl, mqtts"))
+		}
+	}
+
+	if mqtt.Topic == "" {
+		problems = append(problems, fmt.Errorf("mqtt.topic is required when mqtt.enabled is true"))
+	}
+	if strings.ContainsAny(mqtt.Topic, "#+") {
+		problems = append(problems, fmt.Errorf("mqtt.topic must not contain wildcards"))
+	}
+
+	if mqtt.QoS != 0 && mqtt.QoS != 1 {
+		problems = append(problems, fmt.Errorf("mqtt.qos must be 0 or 1"))
+	}
+
+	return problems
+}
+
+func validateHTTP(field string, http HTTPConfig) []error {
+	var problems []error
+	if http.Proxy != "" {
+		if proxy, err := url.Parse(http.Proxy); err != nil || proxy.Host == "" {
+			problems = append(problems, fmt.Errorf("%s.proxy must be a URL such as http://proxy:3128", field))
+		} else {
+			switch proxy.Scheme {
+			case "http", "https", "socks5":
+				// valid
+			default:
+				problems = append(problems, fmt.Errorf("%s.proxy scheme must be one of http, https, socks5", field))
+			}
+		}
+	}
+	if (http.CertFile == "") != (http.KeyFile == "") {
+		problems = append(problems, fmt.Errorf("%s.cert_file and %s.key_file must be set together", field, field))
+	}
+	if http.TimeoutSeconds < 0 {
+		problems = append(problems, fmt.Errorf("%s.timeout_seconds must not be negative", field))
+	}
+	if http.ConnectTimeoutSeconds < 0 {
+		problems = append(problems, fmt.Errorf("%s.connect_timeout_seconds must not be negative", field))
+	}
+	return problems
+}
+
+func validateLogging(logging LoggingConfig) []error {
+	var problems []error
 	switch strings.ToLower(strings.TrimSpace(logging.Level)) {
 	case "error", "warn", "info", "debug":
 		// valid
 	default:
-		return fmt.Errorf("logging.level must be one of error, warn, info, debug")
+		problems = append(problems, fmt.Errorf("logging.level must be one of error, warn, info, debug"))
 	}
 
 	if logging.MaxSizeMB <= 0 {
-		return fmt.Errorf("logging.max_size_mb must be greater than 0")
+		problems = append(problems, fmt.Errorf("logging.max_size_mb must be greater than 0"))
 	}
 
 	if logging.MaxBackups <= 0 {
-		return fmt.Errorf("logging.max_backups must be greater than 0")
+		problems = append(problems, fmt.Errorf("logging.max_backups must be greater than 0"))
 	}
 
 	if strings.TrimSpace(logging.Dir) == "" {
-		return fmt.Errorf("logging.dir is required")
+		problems = append(problems, fmt.Errorf("logging.dir is required"))
 	}
 
-	return nil
+	return problems
 }
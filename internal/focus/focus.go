@@ -1,6 +1,9 @@
 package focus
 
-import "os"
+import (
+	"context"
+	"os"
+)
 
 // State describes focus detection output.
 // Known=false means focus detection could not determine a result.
@@ -27,11 +30,41 @@ func ProcessInFocusedTerminal(pid int) bool {
 
 // TerminalFocusState reports focus and whether detection is known.
 func TerminalFocusState() State {
-	return ProcessFocusState(os.Getpid())
+	return TerminalFocusStateContext(context.Background())
+}
+
+// TerminalFocusStateContext is TerminalFocusState with cancellation.
+func TerminalFocusStateContext(ctx context.Context) State {
+	return ProcessFocusStateContext(ctx, os.Getpid())
 }
 
 // ProcessFocusState reports focus and detection certainty for a PID's terminal.
 func ProcessFocusState(pid int) State {
-	focused, known := processInFocusedTerminalState(pid)
+	return ProcessFocusStateContext(context.Background(), pid)
+}
+
+// ProcessFocusStateContext is ProcessFocusState with cancellation: ending ctx
+// stops the detection commands, and the state is reported as unknown.
+func ProcessFocusStateContext(ctx context.Context, pid int) State {
+	focused, known := processInFocusedTerminalState(ctx, pid)
+	if ctx.Err() != nil {
+		return State{}
+	}
 	return State{Focused: focused, Known: known}
 }
+
+// Provider is an external source focus detection relies on, such as
+// xdotool on X11 or osascript on macOS.
+type Provider struct {
+	Name string
+	// Note says when the provider matters, if not always.
+	Note string
+	// Probe queries the provider once and describes its answer.
+	Probe func(ctx context.Context) (string, error)
+}
+
+// Providers lists the providers detection uses on this platform, in the
+// order it tries them.
+func Providers() []Provider {
+	return providers()
+}
@@ -3,6 +3,9 @@
 package focus
 
 import (
+	"context"
+	"fmt"
+	"os/exec"
 	"strconv"
 	"strings"
 )
@@ -16,8 +19,8 @@ var (
 	tmuxClientPIDsFn = tmuxClientPIDs
 )
 
-func processInFocusedTerminalState(pid int) (bool, bool) {
-	focusedPID, ok := frontmostPIDFunc()
+func processInFocusedTerminalState(ctx context.Context, pid int) (bool, bool) {
+	focusedPID, ok := frontmostPIDFunc(ctx)
 	if !ok {
 		return false, false
 	}
@@ -28,7 +31,7 @@ func processInFocusedTerminalState(pid int) (bool, bool) {
 		return true, true
 	}
 
-	env, err := processEnvFunc(pid)
+	env, err := processEnvFunc(ctx, pid)
 	if err != nil {
 		// If we cannot inspect the process environment, keep prior behavior:
 		// active + unfocused is known/unfocused.
@@ -36,18 +39,18 @@ func processInFocusedTerminalState(pid int) (bool, bool) {
 	}
 
 	if sessionName := strings.TrimSpace(env["ZELLIJ_SESSION_NAME"]); sessionName != "" {
-		return zellijFocusState(sessionName, focusedPID)
+		return zellijFocusState(ctx, sessionName, focusedPID)
 	}
 
 	if tmuxEnv := strings.TrimSpace(env["TMUX"]); tmuxEnv != "" {
-		return tmuxFocusState(tmuxEnv, focusedPID)
+		return tmuxFocusState(ctx, tmuxEnv, focusedPID)
 	}
 
 	return false, true
 }
 
-func frontmostPID() (int, bool) {
-	out, err := osascriptOutputFunc()
+func frontmostPID(ctx context.Context) (int, bool) {
+	out, err := osascriptOutputFunc(ctx)
 	if err != nil {
 		return 0, false
 	}
@@ -59,3 +62,61 @@ func frontmostPID() (int, bool) {
 
 	return pid, true
 }
+
+func providers() []Provider {
+	return []Provider{
+		{
+			Name: "osascript",
+			Probe: func(ctx context.Context) (string, error) {
+				out, err := osascriptOutputFunc(ctx)
+				if err != nil {
+					return "", err
+				}
+				pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+				if err != nil || pid <= 0 {
+					return "", fmt.Errorf("unexpected osascript output %q (is Accessibility access granted?)", strings.TrimSpace(string(out)))
+				}
+				return fmt.Sprintf("frontmost app pid %d", pid), nil
+			},
+		},
+		{
+			Name: "ps",
+			Note: "process environments and tree, for tmux and zellij",
+			Probe: func(ctx context.Context) (string, error) {
+				procs, err := processListFunc(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d processes", len(procs)), nil
+			},
+		},
+		{
+			Name: "tmux",
+			Note: "only inside tmux",
+			Probe: func(ctx context.Context) (string, error) {
+				out, err := exec.CommandContext(ctx, "tmux", "-V").Output()
+				if err != nil {
+					return "", err
+				}
+				return strings.TrimSpace(string(out)), nil
+			},
+		},
+		{
+			Name: "zellij",
+			Note: "only inside zellij",
+			Probe: func(ctx context.Context) (string, error) {
+				procs, err := processListFunc(ctx)
+				if err != nil {
+					return "", err
+				}
+				clients := 0
+				for _, proc := range procs {
+					if isZellijClientProcess(proc) {
+						clients++
+					}
+				}
+				return fmt.Sprintf("%d zellij client(s) running", clients), nil
+			},
+		},
+	}
+}
@@ -2,10 +2,13 @@
 
 package focus
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
-func zellijFocusState(sessionName string, focusedPID int) (bool, bool) {
-	procs, err := processListFunc()
+func zellijFocusState(ctx context.Context, sessionName string, focusedPID int) (bool, bool) {
+	procs, err := processListFunc(ctx)
 	if err != nil {
 		return false, false
 	}
@@ -80,13 +83,13 @@ func zellijSessionMatches(args string, sessionName string) bool {
 	return false
 }
 
-func tmuxFocusState(tmuxEnv string, focusedPID int) (bool, bool) {
+func tmuxFocusState(ctx context.Context, tmuxEnv string, focusedPID int) (bool, bool) {
 	socketPath, sessionID, ok := tmuxSocketAndSession(tmuxEnv)
 	if !ok {
 		return false, false
 	}
 
-	clientPIDs, err := tmuxClientPIDsFn(socketPath, sessionID)
+	clientPIDs, err := tmuxClientPIDsFn(ctx, socketPath, sessionID)
 	if err != nil {
 		return false, false
 	}
@@ -3,6 +3,7 @@
 package focus
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strconv"
@@ -18,22 +19,22 @@ type processInfo struct {
 }
 
 var (
-	osascriptOutputFunc = func() ([]byte, error) {
-		return exec.Command("osascript", "-e", frontmostPIDAppleScript).Output()
+	osascriptOutputFunc = func(ctx context.Context) ([]byte, error) {
+		return exec.CommandContext(ctx, "osascript", "-e", frontmostPIDAppleScript).Output()
 	}
-	psEnvOutputFunc = func(pid int) ([]byte, error) {
-		return exec.Command("ps", "eww", "-p", strconv.Itoa(pid)).Output()
+	psEnvOutputFunc = func(ctx context.Context, pid int) ([]byte, error) {
+		return exec.CommandContext(ctx, "ps", "eww", "-p", strconv.Itoa(pid)).Output()
 	}
-	psProcessListOutputFunc = func() ([]byte, error) {
-		return exec.Command("ps", "-axo", "pid=,ppid=,comm=,args=").Output()
+	psProcessListOutputFunc = func(ctx context.Context) ([]byte, error) {
+		return exec.CommandContext(ctx, "ps", "-axo", "pid=,ppid=,comm=,args=").Output()
 	}
-	tmuxListClientsOutputFunc = func(socketPath string) ([]byte, error) {
-		return exec.Command("tmux", "-S", socketPath, "list-clients", "-F", "#{session_id} #{client_pid}").Output()
+	tmuxListClientsOutputFunc = func(ctx context.Context, socketPath string) ([]byte, error) {
+		return exec.CommandContext(ctx, "tmux", "-S", socketPath, "list-clients", "-F", "#{session_id} #{client_pid}").Output()
 	}
 )
 
-func processEnv(pid int) (map[string]string, error) {
-	out, err := psEnvOutputFunc(pid)
+func processEnv(ctx context.Context, pid int) (map[string]string, error) {
+	out, err := psEnvOutputFunc(ctx, pid)
 	if err != nil {
 		return nil, err
 	}
@@ -91,8 +92,8 @@ func isEnvKey(key string) bool {
 	return true
 }
 
-func processList() ([]processInfo, error) {
-	out, err := psProcessListOutputFunc()
+func processList(ctx context.Context) ([]processInfo, error) {
+	out, err := psProcessListOutputFunc(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -171,8 +172,8 @@ func nextField(input string) (field string, rest string, ok bool) {
 	return s[:i], s[i:], true
 }
 
-func tmuxClientPIDs(socketPath string, sessionID string) ([]int, error) {
-	out, err := tmuxListClientsOutputFunc(socketPath)
+func tmuxClientPIDs(ctx context.Context, socketPath string, sessionID string) ([]int, error) {
+	out, err := tmuxListClientsOutputFunc(ctx, socketPath)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@
 package focus
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -29,15 +30,15 @@ func TestProcessInFocusedTerminalState_DirectAncestor(t *testing.T) {
 		100: 200,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 200, true }
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 200, true }
 
 	envCalled := false
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		envCalled = true
 		return map[string]string{}, nil
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if !focused || !known {
 		t.Fatalf("got focused=%v known=%v, want focused=true known=true", focused, known)
 	}
@@ -56,18 +57,18 @@ func TestProcessInFocusedTerminalState_ZellijFocusedSession(t *testing.T) {
 		3000: 1,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 999, true }
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 999, true }
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		return map[string]string{"ZELLIJ_SESSION_NAME": "cyphant_homepage"}, nil
 	}
-	processListFunc = func() ([]processInfo, error) {
+	processListFunc = func(context.Context) ([]processInfo, error) {
 		return []processInfo{
 			{PID: 3000, Comm: "zellij", Args: "zellij --server /tmp/socket"},
 			{PID: 300, Comm: "zellij", Args: "zellij -s cyphant_homepage"},
 		}, nil
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if !focused || !known {
 		t.Fatalf("got focused=%v known=%v, want focused=true known=true", focused, known)
 	}
@@ -82,17 +83,17 @@ func TestProcessInFocusedTerminalState_ZellijSessionMismatch(t *testing.T) {
 		999: 1,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 999, true }
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 999, true }
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		return map[string]string{"ZELLIJ_SESSION_NAME": "cyphant_homepage"}, nil
 	}
-	processListFunc = func() ([]processInfo, error) {
+	processListFunc = func(context.Context) ([]processInfo, error) {
 		return []processInfo{
 			{PID: 300, Comm: "zellij", Args: "zellij -s other_session"},
 		}, nil
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if focused || !known {
 		t.Fatalf("got focused=%v known=%v, want focused=false known=true", focused, known)
 	}
@@ -106,15 +107,15 @@ func TestProcessInFocusedTerminalState_ZellijProbeFailureIsUncertain(t *testing.
 		999: 1,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 999, true }
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 999, true }
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		return map[string]string{"ZELLIJ_SESSION_NAME": "cyphant_homepage"}, nil
 	}
-	processListFunc = func() ([]processInfo, error) {
+	processListFunc = func(context.Context) ([]processInfo, error) {
 		return nil, errors.New("ps failed")
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if focused || known {
 		t.Fatalf("got focused=%v known=%v, want focused=false known=false", focused, known)
 	}
@@ -129,11 +130,11 @@ func TestProcessInFocusedTerminalState_TmuxFocusedClient(t *testing.T) {
 		999: 1,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 999, true }
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 999, true }
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		return map[string]string{"TMUX": "/tmp/tmux-501/default,123,0"}, nil
 	}
-	tmuxClientPIDsFn = func(socketPath string, sessionID string) ([]int, error) {
+	tmuxClientPIDsFn = func(_ context.Context, socketPath string, sessionID string) ([]int, error) {
 		if socketPath != "/tmp/tmux-501/default" {
 			t.Fatalf("socketPath=%q, want %q", socketPath, "/tmp/tmux-501/default")
 		}
@@ -143,7 +144,7 @@ func TestProcessInFocusedTerminalState_TmuxFocusedClient(t *testing.T) {
 		return []int{700}, nil
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if !focused || !known {
 		t.Fatalf("got focused=%v known=%v, want focused=true known=true", focused, known)
 	}
@@ -159,15 +160,15 @@ func TestProcessInFocusedTerminalState_TmuxUnfocused(t *testing.T) {
 		999: 1,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 999, true }
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 999, true }
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		return map[string]string{"TMUX": "/tmp/tmux-501/default,123,0"}, nil
 	}
-	tmuxClientPIDsFn = func(socketPath string, sessionID string) ([]int, error) {
+	tmuxClientPIDsFn = func(_ context.Context, socketPath string, sessionID string) ([]int, error) {
 		return []int{700}, nil
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if focused || !known {
 		t.Fatalf("got focused=%v known=%v, want focused=false known=true", focused, known)
 	}
@@ -181,15 +182,15 @@ func TestProcessInFocusedTerminalState_TmuxQueryFailureIsUncertain(t *testing.T)
 		999: 1,
 	})
 
-	frontmostPIDFunc = func() (int, bool) { return 999, true }
-	processEnvFunc = func(pid int) (map[string]string, error) {
+	frontmostPIDFunc = func(context.Context) (int, bool) { return 999, true }
+	processEnvFunc = func(_ context.Context, pid int) (map[string]string, error) {
 		return map[string]string{"TMUX": "/tmp/tmux-501/default,123,0"}, nil
 	}
-	tmuxClientPIDsFn = func(socketPath string, sessionID string) ([]int, error) {
+	tmuxClientPIDsFn = func(_ context.Context, socketPath string, sessionID string) ([]int, error) {
 		return nil, errors.New("tmux failed")
 	}
 
-	focused, known := processInFocusedTerminalState(100)
+	focused, known := processInFocusedTerminalState(context.Background(), 100)
 	if focused || known {
 		t.Fatalf("got focused=%v known=%v, want focused=false known=false", focused, known)
 	}
@@ -3,6 +3,7 @@
 package focus
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,8 +11,8 @@ import (
 	"strings"
 )
 
-func processInFocusedTerminalState(pid int) (bool, bool) {
-	focusedPID, ok := focusedWindowPID()
+func processInFocusedTerminalState(ctx context.Context, pid int) (bool, bool) {
+	focusedPID, ok := focusedWindowPID(ctx)
 	if !ok {
 		return false, false
 	}
@@ -21,27 +22,60 @@ func processInFocusedTerminalState(pid int) (bool, bool) {
 	return isAncestor(focusedPID, pid), true
 }
 
-func focusedWindowPID() (int, bool) {
-	// Try xdotool (X11)
-	out, err := exec.Command("xdotool", "getactivewindow", "getwindowpid").Output()
-	if err == nil {
-		pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
-		if err == nil && pid > 0 {
+// windowPIDProviders report the focused window's PID. They are tried in
+// order: xdotool (X11), kdotool (Wayland/KDE), gdbus (Wayland/GNOME).
+var windowPIDProviders = []struct {
+	name       string
+	note       string
+	focusedPID func(ctx context.Context) (int, error)
+}{
+	{name: "xdotool", note: "X11", focusedPID: func(ctx context.Context) (int, error) { return dotoolWindowPID(ctx, "xdotool") }},
+	{name: "kdotool", note: "KDE Wayland", focusedPID: func(ctx context.Context) (int, error) { return dotoolWindowPID(ctx, "kdotool") }},
+	{name: "gdbus", note: "GNOME Wayland", focusedPID: gnomeShellWindowPID},
+}
+
+func focusedWindowPID(ctx context.Context) (int, bool) {
+	for _, provider := range windowPIDProviders {
+		if pid, err := provider.focusedPID(ctx); err == nil {
 			return pid, true
 		}
 	}
+	return 0, false
+}
 
-	// Try kdotool (Wayland/KDE)
-	out, err = exec.Command("kdotool", "getactivewindow", "getwindowpid").Output()
-	if err == nil {
-		pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
-		if err == nil && pid > 0 {
-			return pid, true
-		}
+func providers() []Provider {
+	list := make([]Provider, 0, len(windowPIDProviders))
+	for _, provider := range windowPIDProviders {
+		list = append(list, Provider{
+			Name: provider.name,
+			Note: provider.note,
+			Probe: func(ctx context.Context) (string, error) {
+				pid, err := provider.focusedPID(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("focused window pid %d", pid), nil
+			},
+		})
+	}
+	return list
+}
+
+func dotoolWindowPID(ctx context.Context, tool string) (int, error) {
+	out, err := exec.CommandContext(ctx, tool, "getactivewindow", "getwindowpid").Output()
+	if err != nil {
+		return 0, err
 	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("unexpected %s output %q", tool, strings.TrimSpace(string(out)))
+	}
+	return pid, nil
+}
 
-	// Try gdbus (Wayland/GNOME)
-	out, err = exec.Command(
+func gnomeShellWindowPID(ctx context.Context) (int, error) {
+	out, err := exec.CommandContext(
+		ctx,
 		"gdbus",
 		"call",
 		"--session",
@@ -50,13 +84,15 @@ func focusedWindowPID() (int, bool) {
 		"--method", "org.gnome.Shell.Eval",
 		"global.display.focus_window ? global.display.focus_window.get_pid().toString() : '0'",
 	).Output()
-	if err == nil {
-		if pid, ok := parseGnomeShellEvalPID(string(out)); ok {
-			return pid, true
-		}
+	if err != nil {
+		return 0, err
 	}
-
-	return 0, false
+	pid, ok := parseGnomeShellEvalPID(string(out))
+	if !ok {
+		// GNOME 41+ only allows Eval in unsafe mode and answers (false, '').
+		return 0, fmt.Errorf("org.gnome.Shell.Eval returned %s", strings.TrimSpace(string(out)))
+	}
+	return pid, nil
 }
 
 func parseGnomeShellEvalPID(out string) (int, bool) {
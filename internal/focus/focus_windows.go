@@ -3,6 +3,8 @@
 package focus
 
 import (
+	"context"
+	"fmt"
 	"syscall"
 	"unsafe"
 )
@@ -19,19 +21,41 @@ var (
 
 const processQueryInfo = 0x0400
 
-func processInFocusedTerminalState(pid int) (bool, bool) {
+func processInFocusedTerminalState(_ context.Context, pid int) (bool, bool) {
+	focusedPID, ok := foregroundWindowPID()
+	if !ok {
+		return false, false
+	}
+
+	return isAncestor(focusedPID, pid), true
+}
+
+func foregroundWindowPID() (int, bool) {
 	hwnd, _, _ := getForegroundWindow.Call()
 	if hwnd == 0 {
-		return false, false
+		return 0, false
 	}
 
 	var focusedPID uint32
 	getWindowThreadPID.Call(hwnd, uintptr(unsafe.Pointer(&focusedPID)))
 	if focusedPID == 0 {
-		return false, false
+		return 0, false
 	}
 
-	return isAncestor(int(focusedPID), pid), true
+	return int(focusedPID), true
+}
+
+func providers() []Provider {
+	return []Provider{{
+		Name: "GetForegroundWindow",
+		Probe: func(context.Context) (string, error) {
+			pid, ok := foregroundWindowPID()
+			if !ok {
+				return "", fmt.Errorf("no foreground window (locked screen or service session?)")
+			}
+			return fmt.Sprintf("foreground window pid %d", pid), nil
+		},
+	}}
 }
 
 type processBasicInfo struct {
@@ -1,9 +1,38 @@
 package idle
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Duration returns how long the user has been idle (no keyboard/mouse input).
 // Returns an error if idle time cannot be determined.
 func Duration() (time.Duration, error) {
-	return idleDuration()
+	return DurationContext(context.Background())
+}
+
+// DurationContext is Duration with cancellation: ending ctx stops the
+// detection commands and returns ctx's error.
+func DurationContext(ctx context.Context) (time.Duration, error) {
+	d, err := idleDuration(ctx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return 0, ctxErr
+	}
+	return d, err
+}
+
+// Provider is an external source idle detection relies on, such as
+// xprintidle on X11 or ioreg on macOS.
+type Provider struct {
+	Name string
+	// Note says when the provider matters, if not always.
+	Note string
+	// Probe reads the idle time once from this provider alone.
+	Probe func(ctx context.Context) (time.Duration, error)
+}
+
+// Providers lists the providers detection uses on this platform, in the
+// order it tries them.
+func Providers() []Provider {
+	return providers()
 }
@@ -3,6 +3,7 @@
 package idle
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strconv"
@@ -10,8 +11,8 @@ import (
 	"time"
 )
 
-func idleDuration() (time.Duration, error) {
-	out, err := exec.Command("ioreg", "-c", "IOHIDSystem").Output()
+func idleDuration(ctx context.Context) (time.Duration, error) {
+	out, err := exec.CommandContext(ctx, "ioreg", "-c", "IOHIDSystem").Output()
 	if err != nil {
 		return 0, fmt.Errorf("ioreg: %w", err)
 	}
@@ -24,6 +25,10 @@ func idleDuration() (time.Duration, error) {
 	return idle, nil
 }
 
+func providers() []Provider {
+	return []Provider{{Name: "ioreg", Probe: idleDuration}}
+}
+
 func parseHIDIdleTime(out []byte) (time.Duration, error) {
 	for _, line := range strings.Split(string(out), "\n") {
 		if !strings.Contains(line, "HIDIdleTime") {
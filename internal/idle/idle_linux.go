@@ -3,6 +3,7 @@
 package idle
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strconv"
@@ -10,37 +11,59 @@ import (
 	"time"
 )
 
-func idleDuration() (time.Duration, error) {
-	// Try xprintidle (X11) — returns milliseconds
-	out, err := exec.Command("xprintidle").Output()
-	if err == nil {
-		ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
-		if err == nil {
-			return time.Duration(ms) * time.Millisecond, nil
+func idleDuration(ctx context.Context) (time.Duration, error) {
+	for _, provider := range providers() {
+		if d, err := provider.Probe(ctx); err == nil {
+			return d, nil
 		}
 	}
 
-	// Try GNOME/Mutter idle monitor via dbus
-	out, err = exec.Command(
+	return 0, fmt.Errorf("idle detection unavailable: xprintidle and dbus-send both failed")
+}
+
+func providers() []Provider {
+	return []Provider{
+		{Name: "xprintidle", Note: "X11", Probe: xprintidle},
+		{Name: "dbus-send", Note: "GNOME Mutter idle monitor", Probe: mutterIdleTime},
+	}
+}
+
+// xprintidle returns milliseconds since the last X11 input.
+func xprintidle(ctx context.Context) (time.Duration, error) {
+	out, err := exec.CommandContext(ctx, "xprintidle").Output()
+	if err != nil {
+		return 0, err
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse xprintidle output: %w", err)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func mutterIdleTime(ctx context.Context) (time.Duration, error) {
+	out, err := exec.CommandContext(
+		ctx,
 		"dbus-send", "--print-reply", "--dest=org.gnome.Mutter.IdleMonitor",
 		"/org/gnome/Mutter/IdleMonitor/Core",
 		"org.gnome.Mutter.IdleMonitor.GetIdletime",
 	).Output()
-	if err == nil {
-		// Output contains "uint64 <milliseconds>"
-		for _, line := range strings.Split(string(out), "\n") {
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "uint64") {
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					ms, err := strconv.ParseInt(parts[1], 10, 64)
-					if err == nil {
-						return time.Duration(ms) * time.Millisecond, nil
-					}
+	if err != nil {
+		return 0, err
+	}
+
+	// Output contains "uint64 <milliseconds>"
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "uint64") {
+			parts := strings.Fields(line)
+			if len(parts) >= 2 {
+				ms, err := strconv.ParseInt(parts[1], 10, 64)
+				if err == nil {
+					return time.Duration(ms) * time.Millisecond, nil
 				}
 			}
 		}
 	}
-
-	return 0, fmt.Errorf("idle detection unavailable: xprintidle and dbus-send both failed")
+	return 0, fmt.Errorf("no uint64 idle time in dbus-send output")
 }
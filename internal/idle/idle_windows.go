@@ -3,6 +3,7 @@
 package idle
 
 import (
+	"context"
 	"fmt"
 	"syscall"
 	"time"
@@ -21,7 +22,7 @@ type lastInputInfo struct {
 	dwTime uint32
 }
 
-func idleDuration() (time.Duration, error) {
+func idleDuration(_ context.Context) (time.Duration, error) {
 	var info lastInputInfo
 	info.cbSize = uint32(unsafe.Sizeof(info))
 
@@ -35,3 +36,7 @@ func idleDuration() (time.Duration, error) {
 
 	return time.Duration(idleMs) * time.Millisecond, nil
 }
+
+func providers() []Provider {
+	return []Provider{{Name: "GetLastInputInfo", Probe: idleDuration}}
+}
@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxActions bounds Message.Actions; it matches the button count Discord
+// renders in a single row. ntfy shows at most three and drops the rest.
+const maxActions = 5
+
+// Action is a labeled link rendered as a button where the backend supports it.
+type Action struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// ParseAction parses the CLI form "Label=https://...". The label ends at the
+// first '=' so the URL may contain query parameters.
+func ParseAction(value string) (Action, error) {
+	label, link, ok := strings.Cut(value, "=")
+	if !ok {
+		return Action{}, fmt.Errorf("invalid action %q (expected Label=URL)", value)
+	}
+	action := Action{Label: strings.TrimSpace(label), URL: strings.TrimSpace(link)}
+	if err := validateAction(action); err != nil {
+		return Action{}, err
+	}
+	return action, nil
+}
+
+// ValidateLinks checks the message URL and actions. Only http and https links
+// are accepted: they are opened on click, so other schemes could launch
+// arbitrary local handlers.
+func ValidateLinks(msg Message) error {
+	if msg.URL != "" {
+		if err := validateLinkURL(msg.URL); err != nil {
+			return fmt.Errorf("invalid url: %w", err)
+		}
+	}
+	if len(msg.Actions) > maxActions {
+		return fmt.Errorf("too many actions: %d (max %d)", len(msg.Actions), maxActions)
+	}
+	for _, action := range msg.Actions {
+		if err := validateAction(action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAction(action Action) error {
+	if action.Label == "" {
+		return fmt.Errorf("invalid action: label is required")
+	}
+	if err := validateLinkURL(action.URL); err != nil {
+		return fmt.Errorf("invalid action %q: %w", action.Label, err)
+	}
+	return nil
+}
+
+func validateLinkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL", raw)
+	}
+	return nil
+}
+
+// linkButtons returns the message URL (labeled "Open") followed by its
+// actions, for backends without a separate click target.
+func linkButtons(msg Message) []Action {
+	var buttons []Action
+	if msg.URL != "" {
+		buttons = append(buttons, Action{Label: "Open", URL: msg.URL})
+	}
+	return append(buttons, msg.Actions...)
+}
@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAction(t *testing.T) {
+	got, err := ParseAction("Open PR=https://example.com/pr?x=1&y=2")
+	if err != nil {
+		t.Fatalf("ParseAction() error = %v", err)
+	}
+	if got.Label != "Open PR" || got.URL != "https://example.com/pr?x=1&y=2" {
+		t.Fatalf("ParseAction() = %+v", got)
+	}
+
+	for _, value := range []string{"no-separator", "=https://example.com", "Logs=ftp://example.com", "Logs=/relative"} {
+		if _, err := ParseAction(value); err == nil {
+			t.Errorf("ParseAction(%q) error = nil, want error", value)
+		}
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	action := Action{Label: "Logs", URL: "https://example.com/logs"}
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{name: "no links", msg: Message{}},
+		{name: "valid", msg: Message{URL: "http://localhost:8080/run", Actions: []Action{action}}},
+		{name: "non-http url", msg: Message{URL: "javascript:alert(1)"}, want: "invalid url"},
+		{name: "missing host", msg: Message{URL: "https:///path"}, want: "invalid url"},
+		{name: "missing label", msg: Message{Actions: []Action{{URL: "https://example.com"}}}, want: "label is required"},
+		{name: "too many", msg: Message{Actions: []Action{action, action, action, action, action, action}}, want: "too many actions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLinks(tt.msg)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ValidateLinks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ValidateLinks() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
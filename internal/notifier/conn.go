@@ -0,0 +1,17 @@
+package notifier
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// abortOnDone expires conn's deadline when ctx ends, failing any blocked
+// read or write. The connection itself stays usable for a later deadline,
+// so persistent sessions survive a cancelled delivery. Call the returned
+// function to stop watching ctx.
+func abortOnDone(ctx context.Context, conn net.Conn) func() bool {
+	return context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+}
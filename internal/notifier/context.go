@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var hostnameFunc = os.Hostname
+var processCwdFunc = processCwd
+
+// WithContext fills the message's working directory, git repository, branch
+// and hostname from cwd. Fields the caller already set are kept, so remote
+// callers can report their own context.
+func WithContext(msg Message, cwd string) Message {
+	if msg.Cwd == "" {
+		msg.Cwd = cwd
+	}
+	if msg.Cwd != "" && msg.Repo == "" {
+		repo, branch := gitInfo(msg.Cwd)
+		msg.Repo = repo
+		if msg.Branch == "" {
+			msg.Branch = branch
+		}
+	}
+	if msg.Hostname == "" {
+		msg.Hostname, _ = hostnameFunc()
+	}
+	return msg
+}
+
+// ProcessCwd returns the working directory of the process pid.
+func ProcessCwd(pid int) (string, error) {
+	return processCwdFunc(pid)
+}
+
+// contextLabel renders the agent and repository as "claude • ding-ding@main".
+// It is empty when the message carries no repository or directory context.
+func contextLabel(msg Message) string {
+	where := workspaceLabel(msg)
+	if where == "" {
+		return ""
+	}
+	if msg.Agent == "" {
+		return where
+	}
+	return msg.Agent + " • " + where
+}
+
+// workspaceLabel is "repo@branch", "repo", or the cwd's base name.
+func workspaceLabel(msg Message) string {
+	switch {
+	case msg.Repo != "" && msg.Branch != "":
+		return msg.Repo + "@" + msg.Branch
+	case msg.Repo != "":
+		return msg.Repo
+	case msg.Cwd != "":
+		return filepath.Base(msg.Cwd)
+	default:
+		return ""
+	}
+}
+
+// gitInfo finds the git repository containing dir by walking up to the
+// nearest .git entry and reads the branch from HEAD without running git.
+// A detached HEAD reports the abbreviated commit.
+func gitInfo(dir string) (repo, branch string) {
+	for current := filepath.Clean(dir); ; {
+		dotGit := filepath.Join(current, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			gitDir := dotGit
+			repo = filepath.Base(current)
+			if !info.IsDir() {
+				// Worktrees and submodules use a ".git" file pointing at
+				// the real git directory.
+				gitDir = resolveGitFile(current, dotGit)
+				if gitDir == "" {
+					return repo, ""
+				}
+			}
+			return repo, gitBranch(gitDir)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", ""
+		}
+		current = parent
+	}
+}
+
+func resolveGitFile(worktree, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(worktree, target)
+	}
+	return target
+}
+
+func gitBranch(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if len(head) >= 7 {
+		return head[:7]
+	}
+	return head
+}
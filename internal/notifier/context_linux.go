@@ -0,0 +1,13 @@
+//go:build linux
+
+package notifier
+
+import (
+	"fmt"
+	"os"
+)
+
+// processCwd resolves another process's working directory via procfs.
+func processCwd(pid int) (string, error) {
+	return os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package notifier
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func processCwd(pid int) (string, error) {
+	return "", fmt.Errorf("process cwd lookup is not supported on %s", runtime.GOOS)
+}
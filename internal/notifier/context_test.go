@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func writeGitHead(t *testing.T, gitDir, head string) {
+	t.Helper()
+	if err := os.MkdirAll(gitDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte(head+"\n"), 0o644); err != nil {
+		t.Fatalf("write HEAD: %v", err)
+	}
+}
+
+func TestGitInfo(t *testing.T) {
+	root := t.TempDir()
+
+	repo := filepath.Join(root, "ding-ding")
+	writeGitHead(t, filepath.Join(repo, ".git"), "ref: refs/heads/feature/context")
+	nested := filepath.Join(repo, "internal", "notifier")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	detached := filepath.Join(root, "detached")
+	writeGitHead(t, filepath.Join(detached, ".git"), "0123456789abcdef0123456789abcdef01234567")
+
+	worktree := filepath.Join(root, "wt")
+	worktreeGitDir := filepath.Join(repo, ".git", "worktrees", "wt")
+	writeGitHead(t, worktreeGitDir, "ref: refs/heads/hotfix")
+	if err := os.MkdirAll(worktree, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+worktreeGitDir+"\n"), 0o644); err != nil {
+		t.Fatalf("write .git file: %v", err)
+	}
+
+	tests := []struct {
+		dir          string
+		repo, branch string
+	}{
+		{dir: nested, repo: "ding-ding", branch: "feature/context"},
+		{dir: detached, repo: "detached", branch: "0123456"},
+		{dir: worktree, repo: "wt", branch: "hotfix"},
+		{dir: root, repo: "", branch: ""},
+	}
+
+	for _, tt := range tests {
+		repo, branch := gitInfo(tt.dir)
+		if repo != tt.repo || branch != tt.branch {
+			t.Errorf("gitInfo(%s) = (%q, %q), want (%q, %q)", tt.dir, repo, branch, tt.repo, tt.branch)
+		}
+	}
+}
+
+func TestWithContext_KeepsCallerFields(t *testing.T) {
+	origHostname := hostnameFunc
+	t.Cleanup(func() { hostnameFunc = origHostname })
+	hostnameFunc = func() (string, error) { return "devbox", nil }
+
+	repo := filepath.Join(t.TempDir(), "ding-ding")
+	writeGitHead(t, filepath.Join(repo, ".git"), "ref: refs/heads/main")
+
+	msg := WithContext(Message{Agent: "claude"}, repo)
+	if msg.Cwd != repo || msg.Repo != "ding-ding" || msg.Branch != "main" || msg.Hostname != "devbox" {
+		t.Fatalf("WithContext() = %+v", msg)
+	}
+	if got := contextLabel(msg); got != "claude • ding-ding@main" {
+		t.Errorf("contextLabel() = %q, want %q", got, "claude • ding-ding@main")
+	}
+
+	remote := WithContext(Message{Cwd: "/elsewhere", Repo: "api", Hostname: "ci-runner"}, repo)
+	if remote.Cwd != "/elsewhere" || remote.Repo != "api" || remote.Branch != "" || remote.Hostname != "ci-runner" {
+		t.Fatalf("caller-provided context was overwritten: %+v", remote)
+	}
+	if got := contextLabel(remote); got != "api" {
+		t.Errorf("contextLabel() = %q, want %q", got, "api")
+	}
+}
+
+func TestContextLabel_EmptyWithoutWorkspace(t *testing.T) {
+	if got := contextLabel(Message{Agent: "claude", Hostname: "devbox"}); got != "" {
+		t.Fatalf("contextLabel() = %q, want empty", got)
+	}
+	if got := contextLabel(Message{Cwd: "/home/me/scratch"}); got != "scratch" {
+		t.Fatalf("contextLabel() = %q, want cwd base name", got)
+	}
+}
+
+func TestNotifyRemote_ResolvesCwdFromPID(t *testing.T) {
+	state := setupStubs(t, 10*time.Second, nil, false)
+	cfg := testConfig()
+
+	repo := filepath.Join(t.TempDir(), "ding-ding")
+	writeGitHead(t, filepath.Join(repo, ".git"), "ref: refs/heads/main")
+
+	var gotPID int
+	processCwdFunc = func(pid int) (string, error) {
+		gotPID = pid
+		return repo, nil
+	}
+
+	if _, err := NotifyRemote(cfg, Message{Title: "done", Body: "b", Agent: "claude", PID: 4242}); err != nil {
+		t.Fatalf("NotifyRemote() error = %v", err)
+	}
+	if gotPID != 4242 {
+		t.Fatalf("processCwd called with pid %d, want 4242", gotPID)
+	}
+	if want := "done (claude • ding-ding@main)"; state.systemNotifyTitle != want {
+		t.Fatalf("system title = %q, want %q", state.systemNotifyTitle, want)
+	}
+}
+
+func TestProcessCwd_CurrentProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("procfs cwd lookup is Linux-only")
+	}
+
+	want, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	got, err := processCwd(os.Getpid())
+	if err != nil {
+		t.Fatalf("processCwd() error = %v", err)
+	}
+	if got != want {
+		t.Fatalf("processCwd() = %q, want %q", got, want)
+	}
+}
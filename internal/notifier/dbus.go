@@ -0,0 +1,378 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusNotificationsName          = "org.freedesktop.Notifications"
+	dbusNotificationsPath          = "/org/freedesktop/Notifications"
+	dbusNotificationsNotify        = dbusNotificationsName + ".Notify"
+	dbusNotificationsActionInvoked = dbusNotificationsName + ".ActionInvoked"
+	dbusNotificationsClosed        = dbusNotificationsName + ".NotificationClosed"
+
+	dbusNotificationsCloseNotification = dbusNotificationsName + ".CloseNotification"
+)
+
+// dbusSession is the subset of a session bus connection used to talk to the
+// notification server, so tests can substitute a fake connection.
+type dbusSession interface {
+	// CallWithContext calls method on the notification server, giving up
+	// once ctx ends.
+	CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call
+	// Subscribe delivers the notification server's signals to ch.
+	Subscribe(ch chan<- *dbus.Signal) error
+	Close() error
+}
+
+var dbusConnect = connectSessionNotifications
+var notifySendFunc = notifySend
+var openURLFunc = openURL
+
+// dbusCloseTimeout bounds withdrawing a prompt after its wait has ended,
+// when the wait's own context can no longer bound the call.
+var dbusCloseTimeout = 5 * time.Second
+
+// dbusActionTimeout bounds how long a notification's action buttons stay
+// live; servers that never report NotificationClosed would otherwise leak
+// a connection per notification.
+var dbusActionTimeout = time.Hour
+
+var (
+	dbusActionsMu      sync.Mutex
+	dbusHandleActions  bool
+	dbusActionWatchers = map[chan struct{}]struct{}{}
+)
+
+type sessionNotifications struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+}
+
+func connectSessionNotifications() (dbusSession, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect session bus: %w", err)
+	}
+	return &sessionNotifications{conn: conn, obj: conn.Object(dbusNotificationsName, dbusNotificationsPath)}, nil
+}
+
+func (s *sessionNotifications) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	return s.obj.CallWithContext(ctx, method, flags, args...)
+}
+
+func (s *sessionNotifications) Subscribe(ch chan<- *dbus.Signal) error {
+	if err := s.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(dbusNotificationsPath),
+		dbus.WithMatchInterface(dbusNotificationsName),
+	); err != nil {
+		return fmt.Errorf("subscribe to notification signals: %w", err)
+	}
+	s.conn.Signal(ch)
+	return nil
+}
+
+func (s *sessionNotifications) Close() error {
+	return s.conn.Close()
+}
+
+// setDBusActionHandling turns on clickable D-Bus actions. Clicks arrive as
+// signals on the sending connection, so actions are only offered by
+// long-running processes that stay around to open the link.
+func setDBusActionHandling(enabled bool) {
+	dbusActionsMu.Lock()
+	defer dbusActionsMu.Unlock()
+	dbusHandleActions = enabled
+	if !enabled {
+		for stop := range dbusActionWatchers {
+			close(stop)
+			delete(dbusActionWatchers, stop)
+		}
+	}
+}
+
+func dbusActionsEnabled() bool {
+	dbusActionsMu.Lock()
+	defer dbusActionsMu.Unlock()
+	return dbusHandleActions
+}
+
+// linuxNotify sends a notification over the freedesktop D-Bus API and falls
+// back to notify-send when no session bus or notification server is reachable.
+// Grouped notifications replace the group's previous bubble via replaces_id.
+func linuxNotify(ctx context.Context, n SystemNotification) error {
+	var replacesID uint32
+	if n.Group != "" {
+		replacesID = currentGroupStore().load(n.Group).DBusID
+	}
+
+	id, dbusErr := dbusNotify(ctx, n, replacesID)
+	if dbusErr == nil {
+		if n.Group != "" {
+			currentGroupStore().update(n.Group, func(state *groupState) { state.DBusID = id })
+		}
+		return nil
+	}
+	// A delivery that ran out of time does not get a second attempt.
+	if ctx.Err() != nil {
+		return dbusErr
+	}
+
+	if err := notifySendFunc(n); err != nil {
+		return errors.Join(dbusErr, fmt.Errorf("notify-send fallback: %w", err))
+	}
+	return nil
+}
+
+// dbusNotify calls org.freedesktop.Notifications.Notify and returns the
+// notification ID assigned by the server. A non-zero replacesID updates an
+// existing notification in place.
+func dbusNotify(ctx context.Context, n SystemNotification, replacesID uint32) (uint32, error) {
+	session, err := dbusConnect()
+	if err != nil {
+		return 0, err
+	}
+
+	var actions []string
+	var links map[string]string
+	var signals chan *dbus.Signal
+	if dbusActionsEnabled() {
+		actions, links = dbusActions(n)
+	}
+	if len(actions) > 0 {
+		// Subscribe before Notify so a fast click cannot be missed.
+		signals = make(chan *dbus.Signal, 8)
+		if err := session.Subscribe(signals); err != nil {
+			actions, links, signals = nil, nil, nil
+		}
+	}
+
+	keepOpen := false
+	defer func() {
+		if !keepOpen {
+			_ = session.Close()
+		}
+	}()
+
+	id, err := dbusCallNotify(ctx, session, n, replacesID, actions)
+	if err != nil {
+		return 0, err
+	}
+
+	if signals != nil {
+		keepOpen = true
+		stop := make(chan struct{})
+		dbusActionsMu.Lock()
+		dbusActionWatchers[stop] = struct{}{}
+		dbusActionsMu.Unlock()
+		go watchDBusActions(session, signals, stop, id, links)
+	}
+	return id, nil
+}
+
+func dbusCallNotify(ctx context.Context, session dbusSession, n SystemNotification, replacesID uint32, actions []string) (uint32, error) {
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(dbusUrgency(n.Urgency)),
+	}
+	if n.Category != "" {
+		hints["category"] = dbus.MakeVariant(n.Category)
+	}
+
+	if actions == nil {
+		actions = []string{}
+	}
+
+	call := session.CallWithContext(ctx, dbusNotificationsNotify, 0,
+		"ding-ding",
+		replacesID,
+		n.Icon,
+		n.Title,
+		n.Body,
+		actions,
+		hints,
+		int32(n.ExpireTimeoutMs),
+	)
+	if call.Err != nil {
+		return 0, fmt.Errorf("dbus notify: %w", call.Err)
+	}
+
+	var id uint32
+	if err := call.Store(&id); err != nil {
+		return 0, fmt.Errorf("dbus notify reply: %w", err)
+	}
+	return id, nil
+}
+
+// dbusWaitAction shows n with one button per key and blocks until a button
+// is clicked, the notification is closed, or ctx ends. On timeout the
+// notification is withdrawn so a stale prompt is not left behind.
+func dbusWaitAction(ctx context.Context, n SystemNotification, keys []string) (string, error) {
+	session, err := dbusConnect()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	signals := make(chan *dbus.Signal, 8)
+	if err := session.Subscribe(signals); err != nil {
+		return "", err
+	}
+
+	actions := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		actions = append(actions, key, actionLabel(key))
+	}
+	id, err := dbusCallNotify(ctx, session, n, 0, actions)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dbusCloseTimeout)
+			_ = session.CallWithContext(closeCtx, dbusNotificationsCloseNotification, 0, id).Err
+			cancel()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", ErrActionTimeout
+			}
+			return "", ctx.Err()
+		case sig, ok := <-signals:
+			if !ok {
+				return "", errors.New("dbus connection closed while waiting for an action")
+			}
+			if len(sig.Body) < 2 {
+				continue
+			}
+			if sigID, _ := sig.Body[0].(uint32); sigID != id {
+				continue
+			}
+			switch sig.Name {
+			case dbusNotificationsActionInvoked:
+				// Clicking the body invokes "default", which is not a choice.
+				if key, _ := sig.Body[1].(string); slices.Contains(keys, key) {
+					return key, nil
+				}
+			case dbusNotificationsClosed:
+				return "", ErrActionDismissed
+			}
+		}
+	}
+}
+
+// dbusActions builds the Notify actions array (alternating key and label)
+// and the link each key opens. The message URL is the "default" action,
+// invoked by clicking the notification body.
+func dbusActions(n SystemNotification) ([]string, map[string]string) {
+	var actions []string
+	links := map[string]string{}
+	if n.URL != "" {
+		actions = append(actions, "default", "Open")
+		links["default"] = n.URL
+	}
+	for i, action := range n.Actions {
+		key := "action-" + strconv.Itoa(i)
+		actions = append(actions, key, action.Label)
+		links[key] = action.URL
+	}
+	return actions, links
+}
+
+// watchDBusActions opens the link for a clicked action and releases the
+// connection once the notification closes, times out, or handling stops.
+func watchDBusActions(session dbusSession, signals <-chan *dbus.Signal, stop chan struct{}, id uint32, links map[string]string) {
+	defer func() {
+		_ = session.Close()
+		dbusActionsMu.Lock()
+		delete(dbusActionWatchers, stop)
+		dbusActionsMu.Unlock()
+	}()
+
+	timeout := time.NewTimer(dbusActionTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timeout.C:
+			return
+		case sig, ok := <-signals:
+			if !ok {
+				return
+			}
+			if len(sig.Body) < 2 {
+				continue
+			}
+			if sigID, _ := sig.Body[0].(uint32); sigID != id {
+				continue
+			}
+			switch sig.Name {
+			case dbusNotificationsActionInvoked:
+				key, _ := sig.Body[1].(string)
+				if link := links[key]; link != "" {
+					if err := openURLFunc(link); err != nil {
+						DefaultLoggerFunc().Warn("notifier.dbus.open_url_failed", "error", err)
+					}
+				}
+			case dbusNotificationsClosed:
+				return
+			}
+		}
+	}
+}
+
+// dbusUrgency maps urgency names onto the freedesktop byte levels.
+func dbusUrgency(urgency string) byte {
+	switch strings.ToLower(strings.TrimSpace(urgency)) {
+	case "low":
+		return 0
+	case "critical":
+		return 2
+	default:
+		return 1
+	}
+}
+
+func notifySendArgs(n SystemNotification) []string {
+	args := []string{"--app-name=ding-ding"}
+
+	switch strings.ToLower(strings.TrimSpace(n.Urgency)) {
+	case "low", "normal", "critical":
+		args = append(args, "--urgency="+strings.ToLower(strings.TrimSpace(n.Urgency)))
+	}
+	if n.ExpireTimeoutMs >= 0 {
+		args = append(args, "--expire-time="+strconv.Itoa(n.ExpireTimeoutMs))
+	}
+	if n.Icon != "" {
+		args = append(args, "--icon="+n.Icon)
+	}
+	if n.Category != "" {
+		args = append(args, "--category="+n.Category)
+	}
+
+	return append(args, "--", n.Title, n.Body)
+}
+
+func notifySend(n SystemNotification) error {
+	return exec.Command("notify-send", notifySendArgs(n)...).Run()
+}
+
+func openURL(link string) error {
+	cmd := exec.Command("xdg-open", link)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,351 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeNotificationsBus records Notify calls in place of a session bus.
+// Signals in emit are delivered to subscribers once Notify has been called.
+type fakeNotificationsBus struct {
+	method  string
+	args    []interface{}
+	id      uint32
+	err     error
+	signals chan<- *dbus.Signal
+	emit    []*dbus.Signal
+	other   []string
+	// hang makes every call block until its context ends.
+	hang bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (f *fakeNotificationsBus) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	if method != dbusNotificationsNotify {
+		f.other = append(f.other, method)
+		return &dbus.Call{}
+	}
+	f.method = method
+	f.args = args
+	if f.err != nil {
+		return &dbus.Call{Err: f.err}
+	}
+	for _, sig := range f.emit {
+		f.signals <- sig
+	}
+	return &dbus.Call{Body: []interface{}{f.id}}
+}
+
+func (f *fakeNotificationsBus) CallWithContext(ctx context.Context, method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	if f.hang {
+		<-ctx.Done()
+		return &dbus.Call{Err: ctx.Err()}
+	}
+	return f.Call(method, flags, args...)
+}
+
+func (f *fakeNotificationsBus) Subscribe(ch chan<- *dbus.Signal) error {
+	f.signals = ch
+	return nil
+}
+
+func (f *fakeNotificationsBus) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeNotificationsBus) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func setupFakeDBus(t *testing.T, bus *fakeNotificationsBus, connectErr error) *[]SystemNotification {
+	t.Helper()
+	origConnect := dbusConnect
+	origNotifySend := notifySendFunc
+	t.Cleanup(func() {
+		dbusConnect = origConnect
+		notifySendFunc = origNotifySend
+	})
+
+	dbusConnect = func() (dbusSession, error) {
+		if connectErr != nil {
+			return nil, connectErr
+		}
+		return bus, nil
+	}
+
+	var fallback []SystemNotification
+	notifySendFunc = func(n SystemNotification) error {
+		fallback = append(fallback, n)
+		return nil
+	}
+	return &fallback
+}
+
+func TestDBusNotify_SendsHintsAndReturnsID(t *testing.T) {
+	bus := &fakeNotificationsBus{id: 42}
+	setupFakeDBus(t, bus, nil)
+
+	n := SystemNotification{
+		Title:           "title",
+		Body:            "body",
+		Urgency:         "critical",
+		ExpireTimeoutMs: 5000,
+		Icon:            "dialog-information",
+		Category:        "im.received",
+	}
+
+	id, err := dbusNotify(context.Background(), n, 7)
+	if err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if id != 42 {
+		t.Errorf("expected id 42, got %d", id)
+	}
+	if !bus.isClosed() {
+		t.Error("expected connection to be closed")
+	}
+	if bus.method != dbusNotificationsNotify {
+		t.Errorf("expected method %q, got %q", dbusNotificationsNotify, bus.method)
+	}
+	if len(bus.args) != 8 {
+		t.Fatalf("expected 8 Notify args, got %d", len(bus.args))
+	}
+
+	if bus.args[0] != "ding-ding" {
+		t.Errorf("app_name = %v", bus.args[0])
+	}
+	if bus.args[1] != uint32(7) {
+		t.Errorf("replaces_id = %v, want 7", bus.args[1])
+	}
+	if bus.args[2] != "dialog-information" {
+		t.Errorf("app_icon = %v", bus.args[2])
+	}
+	if bus.args[3] != "title" || bus.args[4] != "body" {
+		t.Errorf("summary/body = %v/%v", bus.args[3], bus.args[4])
+	}
+	if bus.args[7] != int32(5000) {
+		t.Errorf("expire_timeout = %v, want 5000", bus.args[7])
+	}
+
+	hints := bus.args[6].(map[string]dbus.Variant)
+	if got := hints["urgency"].Value(); got != byte(2) {
+		t.Errorf("urgency hint = %v, want 2", got)
+	}
+	if got := hints["category"].Value(); got != "im.received" {
+		t.Errorf("category hint = %v, want im.received", got)
+	}
+}
+
+func TestLinuxNotify_FallsBackToNotifySend(t *testing.T) {
+	fallback := setupFakeDBus(t, nil, errors.New("no session bus"))
+
+	n := SystemNotification{Title: "t", Body: "b"}
+	if err := linuxNotify(context.Background(), n); err != nil {
+		t.Fatalf("expected fallback to succeed, got: %v", err)
+	}
+	if len(*fallback) != 1 || (*fallback)[0].Title != "t" {
+		t.Errorf("expected one notify-send fallback, got %v", *fallback)
+	}
+}
+
+func TestLinuxNotify_NoFallbackWhenDBusSucceeds(t *testing.T) {
+	fallback := setupFakeDBus(t, &fakeNotificationsBus{id: 1}, nil)
+
+	if err := linuxNotify(context.Background(), SystemNotification{Title: "t"}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if len(*fallback) != 0 {
+		t.Errorf("expected notify-send not to run, got %v", *fallback)
+	}
+}
+
+func TestLinuxNotify_BothFailReturnsJoinedError(t *testing.T) {
+	setupFakeDBus(t, &fakeNotificationsBus{err: errors.New("ServiceUnknown")}, nil)
+	notifySendFunc = func(n SystemNotification) error { return errors.New("executable not found") }
+
+	err := linuxNotify(context.Background(), SystemNotification{Title: "t"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	for _, want := range []string{"ServiceUnknown", "notify-send fallback", "executable not found"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %q", want, err.Error())
+		}
+	}
+}
+
+func TestLinuxNotify_HungServerBoundedByContext(t *testing.T) {
+	bus := &fakeNotificationsBus{hang: true}
+	fallback := setupFakeDBus(t, bus, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := linuxNotify(ctx, SystemNotification{Title: "t"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("linuxNotify() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("hung notification server held the send for %v", elapsed)
+	}
+	if len(*fallback) != 0 {
+		t.Errorf("expected no notify-send fallback after the deadline, got %v", *fallback)
+	}
+	if !bus.isClosed() {
+		t.Error("expected connection to be closed")
+	}
+}
+
+func TestNotifySendArgs(t *testing.T) {
+	got := notifySendArgs(SystemNotification{
+		Title:           "-title",
+		Body:            "body",
+		Urgency:         "low",
+		ExpireTimeoutMs: 0,
+		Icon:            "icon",
+		Category:        "cat",
+	})
+	want := []string{"--app-name=ding-ding", "--urgency=low", "--expire-time=0", "--icon=icon", "--category=cat", "--", "-title", "body"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("notifySendArgs() = %v, want %v", got, want)
+	}
+
+	got = notifySendArgs(SystemNotification{Title: "t", Body: "b", ExpireTimeoutMs: -1})
+	want = []string{"--app-name=ding-ding", "--", "t", "b"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("notifySendArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestDBusNotify_OmitsActionsWithoutActionHandling(t *testing.T) {
+	bus := &fakeNotificationsBus{id: 1}
+	setupFakeDBus(t, bus, nil)
+
+	n := SystemNotification{Title: "t", URL: "https://example.com"}
+	if _, err := dbusNotify(context.Background(), n, 0); err != nil {
+		t.Fatalf("dbusNotify() error = %v", err)
+	}
+	if actions := bus.args[5].([]string); len(actions) != 0 {
+		t.Errorf("actions = %v, want none when nothing listens for clicks", actions)
+	}
+	if bus.signals != nil {
+		t.Error("expected no signal subscription")
+	}
+}
+
+func TestDBusNotify_ActionInvokedOpensURL(t *testing.T) {
+	bus := &fakeNotificationsBus{id: 9}
+	setupFakeDBus(t, bus, nil)
+	setDBusActionHandling(true)
+	t.Cleanup(func() { setDBusActionHandling(false) })
+
+	opened := make(chan string, 1)
+	origOpen := openURLFunc
+	t.Cleanup(func() { openURLFunc = origOpen })
+	openURLFunc = func(link string) error {
+		opened <- link
+		return nil
+	}
+
+	n := SystemNotification{
+		Title:   "t",
+		URL:     "https://example.com/run",
+		Actions: []Action{{Label: "Logs", URL: "https://example.com/logs"}},
+	}
+	if _, err := dbusNotify(context.Background(), n, 0); err != nil {
+		t.Fatalf("dbusNotify() error = %v", err)
+	}
+
+	want := []string{"default", "Open", "action-0", "Logs"}
+	if got := bus.args[5].([]string); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("actions = %v, want %v", got, want)
+	}
+	if bus.isClosed() {
+		t.Fatal("connection closed before the notification was answered")
+	}
+
+	// Signals for other notifications are ignored.
+	bus.signals <- &dbus.Signal{Name: dbusNotificationsActionInvoked, Body: []interface{}{uint32(3), "action-0"}}
+	bus.signals <- &dbus.Signal{Name: dbusNotificationsActionInvoked, Body: []interface{}{uint32(9), "action-0"}}
+	select {
+	case got := <-opened:
+		if got != "https://example.com/logs" {
+			t.Errorf("opened %q, want the Logs action URL", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for action to open its URL")
+	}
+
+	bus.signals <- &dbus.Signal{Name: dbusNotificationsClosed, Body: []interface{}{uint32(9), uint32(2)}}
+	deadline := time.Now().Add(2 * time.Second)
+	for !bus.isClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not closed after NotificationClosed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDBusWaitAction_ReturnsChosenAction(t *testing.T) {
+	bus := &fakeNotificationsBus{id: 5, emit: []*dbus.Signal{
+		{Name: dbusNotificationsActionInvoked, Body: []interface{}{uint32(4), "allow"}},
+		{Name: dbusNotificationsActionInvoked, Body: []interface{}{uint32(5), "default"}},
+		{Name: dbusNotificationsActionInvoked, Body: []interface{}{uint32(5), "deny"}},
+	}}
+	setupFakeDBus(t, bus, nil)
+
+	got, err := dbusWaitAction(context.Background(), SystemNotification{Title: "t"}, []string{"allow", "deny"})
+	if err != nil {
+		t.Fatalf("dbusWaitAction() error = %v", err)
+	}
+	if got != "deny" {
+		t.Errorf("action = %q, want deny", got)
+	}
+	want := []string{"allow", "Allow", "deny", "Deny"}
+	if actions := bus.args[5].([]string); strings.Join(actions, "|") != strings.Join(want, "|") {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+	if !bus.isClosed() {
+		t.Error("expected connection to be closed")
+	}
+}
+
+func TestDBusWaitAction_Dismissed(t *testing.T) {
+	bus := &fakeNotificationsBus{id: 5, emit: []*dbus.Signal{
+		{Name: dbusNotificationsClosed, Body: []interface{}{uint32(5), uint32(2)}},
+	}}
+	setupFakeDBus(t, bus, nil)
+
+	if _, err := dbusWaitAction(context.Background(), SystemNotification{}, []string{"ok"}); !errors.Is(err, ErrActionDismissed) {
+		t.Fatalf("dbusWaitAction() error = %v, want ErrActionDismissed", err)
+	}
+}
+
+func TestDBusWaitAction_TimeoutWithdrawsNotification(t *testing.T) {
+	bus := &fakeNotificationsBus{id: 5}
+	setupFakeDBus(t, bus, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := dbusWaitAction(ctx, SystemNotification{}, []string{"ok"}); !errors.Is(err, ErrActionTimeout) {
+		t.Fatalf("dbusWaitAction() error = %v, want ErrActionTimeout", err)
+	}
+	if len(bus.other) != 1 || bus.other[0] != dbusNotificationsCloseNotification {
+		t.Errorf("calls after Notify = %v, want CloseNotification", bus.other)
+	}
+}
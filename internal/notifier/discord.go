@@ -2,41 +2,332 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Digni/ding-ding/internal/config"
 )
 
-func sendDiscord(cfg config.DiscordConfig, msg Message) error {
+func sendDiscord(ctx context.Context, cfg config.DiscordConfig, msg Message) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	msg, err = applyTemplate("discord", cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]any{}
+	content := ""
+	if cfg.Embed {
+		body["embeds"] = []map[string]any{discordEmbed(msg)}
+	} else {
+		content = discordContent(cfg, msg)
+	}
+
+	// Mentions inside embeds never ping, so the priority mention always
+	// goes in the content. Only that mention may ping; @everyone or user
+	// mentions in agent-supplied text stay silent.
+	body["allowed_mentions"] = map[string]any{"parse": []string{}}
+	if mention, ok := mapPriority(cfg.PriorityMentions, msg.Priority); ok && mention != "" {
+		content = strings.TrimSpace(mention + " " + content)
+		body["allowed_mentions"] = discordAllowedMentions(mention)
+	}
+	if content != "" {
+		body["content"] = truncateRunes(content, discordContentMax)
+	}
+
+	identity := config.DiscordIdentity{Username: cfg.Username, AvatarURL: cfg.AvatarURL}
+	if agent, ok := cfg.Agents[msg.Agent]; ok && msg.Agent != "" {
+		if agent.Username != "" {
+			identity.Username = agent.Username
+		}
+		if agent.AvatarURL != "" {
+			identity.AvatarURL = agent.AvatarURL
+		}
+	}
+
+	webhookURL := cfg.WebhookURL
+	if cfg.ThreadID != "" {
+		webhookURL, err = withDiscordQuery(webhookURL, "thread_id", cfg.ThreadID)
+		if err != nil {
+			return err
+		}
+	}
+	if buttons := linkButtons(msg); len(buttons) > 0 {
+		body["components"] = discordLinkButtons(buttons)
+		// Webhooks not owned by an application only accept (non-interactive)
+		// components when asked explicitly.
+		webhookURL, err = withDiscordQuery(webhookURL, "with_components", "true")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Edits keep the identity the message was created with; the edit
+	// endpoint does not accept one.
+	patchPayload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	if identity.Username != "" {
+		body["username"] = truncateRunes(identity.Username, discordUsernameMax)
+	}
+	if identity.AvatarURL != "" {
+		body["avatar_url"] = identity.AvatarURL
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	client, err := httpClientFor(cfg.HTTP)
+	if err != nil {
+		return err
+	}
+
+	if msg.group == "" {
+		_, err := postDiscord(ctx, client, webhookURL, payload, false)
+		return err
+	}
+
+	// Grouped messages edit the group's previous message when we know it;
+	// a deleted or foreign message (404) falls back to posting a new one.
+	store := currentGroupStore()
+	if messageID := store.load(msg.group).DiscordMessageID; messageID != "" {
+		status, err := patchDiscord(ctx, client, webhookURL, messageID, patchPayload)
+		if err == nil {
+			return nil
+		}
+		if status != http.StatusNotFound {
+			return err
+		}
+		countRetry(ctx)
+	}
+
+	messageID, err := postDiscord(ctx, client, webhookURL, payload, true)
+	if err != nil {
+		return err
+	}
+	if messageID != "" {
+		store.update(msg.group, func(state *groupState) { state.DiscordMessageID = messageID })
+	}
+	return nil
+}
+
+// Discord's length limits, in characters.
+const (
+	discordContentMax     = 2000
+	discordUsernameMax    = 80
+	discordButtonLabelMax = 80
+	discordTitleMax       = 256
+	discordDescriptionMax = 4096
+	discordFieldValueMax  = 1024
+	discordEmbedTotalMax  = 6000
+)
+
+// discordContent renders the plain-text message: "**title** (agent)" with
+// the event emoji, or the body template's output alone.
+func discordContent(cfg config.DiscordConfig, msg Message) string {
+	if cfg.Template.Body != "" {
+		return msg.Body
+	}
+
 	content := fmt.Sprintf("**%s**\n%s", msg.Title, msg.Body)
-	if msg.Agent != "" {
+	if label := contextLabel(msg); label != "" {
+		content = fmt.Sprintf("**%s** (%s)\n%s", msg.Title, label, msg.Body)
+	} else if msg.Agent != "" {
 		content = fmt.Sprintf("**%s** (%s)\n%s", msg.Title, msg.Agent, msg.Body)
 	}
+	if emoji := styleFor(msg.Event).emoji; emoji != "" {
+		content = emoji + " " + content
+	}
+	return content
+}
+
+// discordEmbed renders the message as an embed colored by event, with the
+// agent, host, repository and duration as inline fields.
+func discordEmbed(msg Message) map[string]any {
+	style := styleFor(effectiveEvent(msg))
+
+	title := msg.Title
+	if style.emoji != "" {
+		title = style.emoji + " " + title
+	}
 
-	payload, err := json.Marshal(map[string]string{
-		"content": content,
-	})
+	var fields []map[string]any
+	addField := func(name, value string) {
+		if value != "" {
+			fields = append(fields, map[string]any{"name": name, "value": truncateRunes(value, discordFieldValueMax), "inline": true})
+		}
+	}
+	addField("Agent", msg.Agent)
+	addField("Host", msg.Hostname)
+	addField("Repository", workspaceLabel(msg))
+	if msg.DurationMs > 0 {
+		addField("Duration", msg.duration().Round(time.Second).String())
+	}
+	if msg.ExitCode != nil {
+		addField("Exit code", strconv.Itoa(*msg.ExitCode))
+	}
+
+	// The description gets whatever the 6000-character embed total leaves
+	// after the other text.
+	footer := "ding-ding"
+	if msg.Event != "" {
+		footer += " • " + string(msg.Event)
+	}
+	title = truncateRunes(title, discordTitleMax)
+	used := utf8.RuneCountInString(title) + utf8.RuneCountInString(footer)
+	for _, field := range fields {
+		used += utf8.RuneCountInString(field["name"].(string)) + utf8.RuneCountInString(field["value"].(string))
+	}
+	description := truncateRunes(msg.Body, min(discordDescriptionMax, discordEmbedTotalMax-used))
+
+	embed := map[string]any{
+		"title":       title,
+		"description": description,
+		"footer":      map[string]any{"text": footer},
+		"timestamp":   templateNow().UTC().Format(time.RFC3339),
+	}
+	if style.discordRGB != 0 {
+		embed["color"] = style.discordRGB
+	}
+	if len(fields) > 0 {
+		embed["fields"] = fields
+	}
+	if msg.URL != "" {
+		embed["url"] = msg.URL
+	}
+	return embed
+}
+
+// truncateRunes shortens s to at most n characters, ending in "…" when cut.
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// discordLinkButtons renders actions as one row of link-style buttons.
+func discordLinkButtons(actions []Action) []map[string]any {
+	if len(actions) > maxActions {
+		actions = actions[:maxActions]
+	}
+
+	buttons := make([]map[string]any, 0, len(actions))
+	for _, action := range actions {
+		buttons = append(buttons, map[string]any{
+			"type":  2, // button
+			"style": 5, // link
+			"label": truncateRunes(action.Label, discordButtonLabelMax),
+			"url":   action.URL,
+		})
+	}
+	return []map[string]any{{"type": 1, "components": buttons}} // action row
+}
+
+// discordAllowedMentions limits pings to the kind of the configured priority
+// mention, so user and role mentions in agent-supplied text stay silent.
+func discordAllowedMentions(mention string) map[string]any {
+	switch {
+	case mention == "@here" || mention == "@everyone":
+		return map[string]any{"parse": []string{"everyone"}}
+	case strings.HasPrefix(mention, "<@&"):
+		return map[string]any{"parse": []string{}, "roles": []string{strings.Trim(mention, "<@&>")}}
+	default:
+		return map[string]any{"parse": []string{}, "users": []string{strings.Trim(mention, "<@>")}}
+	}
+}
+
+// postDiscord executes the webhook. With wait=true Discord returns the created
+// message, whose ID is needed to edit it later.
+func postDiscord(ctx context.Context, client *http.Client, webhookURL string, payload []byte, wait bool) (string, error) {
+	target := webhookURL
+	if wait {
+		var err error
+		target, err = withDiscordQuery(webhookURL, "wait", "true")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(payload))
 	if err != nil {
-		return fmt.Errorf("marshal payload: %w", err)
+		return "", fmt.Errorf("create request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("send request: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("discord returned status %d", resp.StatusCode)
+	}
+
+	if !wait {
+		return "", nil
 	}
 
-	req, err := http.NewRequest("POST", cfg.WebhookURL, bytes.NewReader(payload))
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", nil
+	}
+	return created.ID, nil
+}
+
+func patchDiscord(ctx context.Context, client *http.Client, webhookURL, messageID string, payload []byte) (int, error) {
+	target, err := url.Parse(webhookURL)
+	if err != nil {
+		return 0, fmt.Errorf("parse webhook url: %w", withoutURL(err))
+	}
+	target.Path = strings.TrimRight(target.Path, "/") + "/messages/" + url.PathEscape(messageID)
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", target.String(), bytes.NewReader(payload))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return 0, fmt.Errorf("create request: %w", withoutURL(err))
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("send request: %w", err)
+		return 0, fmt.Errorf("send request: %w", withoutURL(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("discord returned status %d", resp.StatusCode)
+		return resp.StatusCode, fmt.Errorf("discord returned status %d", resp.StatusCode)
 	}
 
-	return nil
+	return resp.StatusCode, nil
+}
+
+func withDiscordQuery(webhookURL, key, value string) (string, error) {
+	target, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", fmt.Errorf("parse webhook url: %w", withoutURL(err))
+	}
+	query := target.Query()
+	query.Set(key, value)
+	target.RawQuery = query.Encode()
+	return target.String(), nil
 }
@@ -1,6 +1,7 @@
 package notifier
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -25,7 +26,7 @@ func TestSendDiscord_Success(t *testing.T) {
 	cfg := config.DiscordConfig{WebhookURL: srv.URL}
 	msg := Message{Title: "hello", Body: "world"}
 
-	err := sendDiscord(cfg, msg)
+	err := sendDiscord(context.Background(), cfg, msg)
 	if err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
@@ -53,7 +54,7 @@ func TestSendDiscord_WithAgent(t *testing.T) {
 	cfg := config.DiscordConfig{WebhookURL: srv.URL}
 	msg := Message{Title: "hello", Body: "world", Agent: "claude"}
 
-	if err := sendDiscord(cfg, msg); err != nil {
+	if err := sendDiscord(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	content := gotPayload["content"]
@@ -70,7 +71,7 @@ func TestSendDiscord_ServerError(t *testing.T) {
 	cfg := config.DiscordConfig{WebhookURL: srv.URL}
 	msg := Message{Title: "t", Body: "b"}
 
-	err := sendDiscord(cfg, msg)
+	err := sendDiscord(context.Background(), cfg, msg)
 	if err == nil {
 		t.Fatal("expected an error, got nil")
 	}
@@ -78,3 +79,221 @@ func TestSendDiscord_ServerError(t *testing.T) {
 		t.Errorf("expected error to contain %q, got %q", want, err.Error())
 	}
 }
+
+func TestSendDiscord_WithRepositoryContext(t *testing.T) {
+	var gotPayload map[string]any
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.DiscordConfig{WebhookURL: srv.URL}
+	msg := Message{Title: "hello", Body: "world", Agent: "claude", Repo: "ding-ding", Branch: "main"}
+
+	if err := sendDiscord(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if want := "**hello** (claude • ding-ding@main)\nworld"; gotPayload["content"] != want {
+		t.Errorf("expected content %q, got %q", want, gotPayload["content"])
+	}
+}
+
+func TestSendDiscord_WithLinkButtons(t *testing.T) {
+	var gotQuery string
+	var gotPayload map[string]any
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cfg := config.DiscordConfig{WebhookURL: srv.URL}
+	msg := Message{
+		Title:   "hello",
+		Body:    "world",
+		URL:     "https://example.com/run",
+		Actions: []Action{{Label: strings.Repeat("x", 100), URL: "https://example.com/logs"}},
+	}
+
+	if err := sendDiscord(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if gotQuery != "with_components=true" {
+		t.Errorf("expected with_components query, got %q", gotQuery)
+	}
+
+	rows, _ := gotPayload["components"].([]any)
+	if len(rows) != 1 {
+		t.Fatalf("expected one action row, got %v", gotPayload["components"])
+	}
+	buttons, _ := rows[0].(map[string]any)["components"].([]any)
+	if len(buttons) != 2 {
+		t.Fatalf("expected 2 buttons, got %v", buttons)
+	}
+	first := buttons[0].(map[string]any)
+	if first["label"] != "Open" || first["url"] != msg.URL || first["style"] != float64(5) {
+		t.Errorf("unexpected URL button %v", first)
+	}
+	if label := buttons[1].(map[string]any)["label"].(string); len([]rune(label)) != discordButtonLabelMax {
+		t.Errorf("expected label truncated to %d runes, got %d", discordButtonLabelMax, len([]rune(label)))
+	}
+}
+
+func TestSendDiscord_Embed(t *testing.T) {
+	stubTemplateEnv(t)
+
+	var gotPayload map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	exitCode := 1
+	cfg := config.DiscordConfig{WebhookURL: srv.URL, Embed: true}
+	msg := Message{
+		Title:      "Build",
+		Body:       "tests failed",
+		Agent:      "claude",
+		Event:      EventError,
+		ExitCode:   &exitCode,
+		DurationMs: 192_400,
+		Hostname:   "devbox",
+		Repo:       "ding-ding",
+		Branch:     "main",
+	}
+	if err := sendDiscord(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("sendDiscord(context.Background(), ) error = %v", err)
+	}
+
+	if _, ok := gotPayload["content"]; ok {
+		t.Errorf("embed mode without a mention should send no content, got %q", gotPayload["content"])
+	}
+	embed := gotPayload["embeds"].([]any)[0].(map[string]any)
+	if embed["title"] != "❌ Build" || embed["description"] != "tests failed" {
+		t.Errorf("title/description = %q/%q", embed["title"], embed["description"])
+	}
+	if embed["color"] != float64(0xE74C3C) {
+		t.Errorf("color = %v, want error red", embed["color"])
+	}
+	if embed["timestamp"] != "2026-01-02T15:04:05Z" {
+		t.Errorf("timestamp = %v", embed["timestamp"])
+	}
+	if footer := embed["footer"].(map[string]any)["text"]; footer != "ding-ding • error" {
+		t.Errorf("footer = %v", footer)
+	}
+
+	var fields []string
+	for _, f := range embed["fields"].([]any) {
+		field := f.(map[string]any)
+		fields = append(fields, field["name"].(string)+"="+field["value"].(string))
+	}
+	want := "Agent=claude|Host=devbox|Repository=ding-ding@main|Duration=3m12s|Exit code=1"
+	if strings.Join(fields, "|") != want {
+		t.Errorf("fields = %v, want %s", fields, want)
+	}
+}
+
+func TestSendDiscord_EmbedMentionStaysInContent(t *testing.T) {
+	var gotPayload map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cfg := config.DiscordConfig{
+		WebhookURL:       srv.URL,
+		Embed:            true,
+		PriorityMentions: map[string]string{"urgent": "<@&42>"},
+	}
+	if err := sendDiscord(context.Background(), cfg, Message{Title: "Deploy", Body: "approve?", Priority: PriorityUrgent}); err != nil {
+		t.Fatalf("sendDiscord(context.Background(), ) error = %v", err)
+	}
+
+	if gotPayload["content"] != "<@&42>" {
+		t.Errorf("content = %q, want only the role mention", gotPayload["content"])
+	}
+	roles := gotPayload["allowed_mentions"].(map[string]any)["roles"].([]any)
+	if len(roles) != 1 || roles[0] != "42" {
+		t.Errorf("allowed_mentions.roles = %v, want [42]", roles)
+	}
+}
+
+func TestSendDiscord_IdentityAndThread(t *testing.T) {
+	useMemoryGroups(t)
+
+	var requests []string
+	var payloads []map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RawQuery)
+		var payload map[string]any
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &payload)
+		payloads = append(payloads, payload)
+		if r.Method == "POST" {
+			_, _ = io.WriteString(w, `{"id":"111"}`)
+		}
+	})
+
+	cfg := config.DiscordConfig{
+		WebhookURL: srv.URL,
+		ThreadID:   "9876",
+		Username:   "ding-ding",
+		AvatarURL:  "https://example.com/bell.png",
+		Agents:     map[string]config.DiscordIdentity{"claude": {Username: "Claude"}},
+	}
+	msg := Message{Title: "t", Body: "b", Agent: "claude", group: groupKey(Message{Agent: "claude", Session: "s1"})}
+	for i := 0; i < 2; i++ {
+		if err := sendDiscord(context.Background(), cfg, msg); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	want := []string{"POST thread_id=9876&wait=true", "PATCH thread_id=9876"}
+	if strings.Join(requests, "|") != strings.Join(want, "|") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+	if payloads[0]["username"] != "Claude" || payloads[0]["avatar_url"] != "https://example.com/bell.png" {
+		t.Errorf("post identity = %v/%v, want agent username with default avatar", payloads[0]["username"], payloads[0]["avatar_url"])
+	}
+	if _, ok := payloads[1]["username"]; ok {
+		t.Errorf("edit payload must not carry an identity: %v", payloads[1])
+	}
+}
+
+func TestSendDiscord_TruncatesToLimits(t *testing.T) {
+	var gotPayload map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	long := strings.Repeat("é", 7000)
+	if err := sendDiscord(context.Background(), config.DiscordConfig{WebhookURL: srv.URL}, Message{Title: "t", Body: long}); err != nil {
+		t.Fatalf("sendDiscord(context.Background(), ) error = %v", err)
+	}
+	content := gotPayload["content"].(string)
+	if n := len([]rune(content)); n != discordContentMax || !strings.HasSuffix(content, "…") {
+		t.Errorf("content length = %d, want %d ending in …", n, discordContentMax)
+	}
+
+	cfg := config.DiscordConfig{WebhookURL: srv.URL, Embed: true}
+	if err := sendDiscord(context.Background(), cfg, Message{Title: long, Body: long, Agent: "claude"}); err != nil {
+		t.Fatalf("sendDiscord(context.Background(), ) error = %v", err)
+	}
+	embed := gotPayload["embeds"].([]any)[0].(map[string]any)
+	title := []rune(embed["title"].(string))
+	description := []rune(embed["description"].(string))
+	if len(title) != discordTitleMax {
+		t.Errorf("title length = %d, want %d", len(title), discordTitleMax)
+	}
+	if len(description) != discordDescriptionMax {
+		t.Errorf("description length = %d, want %d", len(description), discordDescriptionMax)
+	}
+}
@@ -0,0 +1,381 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// emailDialTimeout bounds connecting to the SMTP server and
+// emailCommandTimeout each command of the dialogue after it, so a slow but
+// progressing session is not cut off by one overall deadline.
+var (
+	emailDialTimeout    = 15 * time.Second
+	emailCommandTimeout = 30 * time.Second
+)
+var emailNow = time.Now
+
+func sendEmail(ctx context.Context, cfg config.EmailConfig, msg Message) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	msg, err = applyTemplate("email", cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+
+	subject, err := renderEmailSubject(cfg.Subject, msg)
+	if err != nil {
+		return err
+	}
+
+	from, to, err := parseEmailAddresses(cfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := buildEmailMessage(from, to, msg, subject, emailNow())
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	client, stop, err := dialSMTP(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	defer client.Close()
+
+	if auth := emailAuth(cfg); auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("rcpt to %q: %w", rcpt.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finish message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dialSMTP connects to the SMTP server. The returned stop function must be
+// called when the session ends; until then, ending ctx aborts its I/O.
+func dialSMTP(ctx context.Context, cfg config.EmailConfig) (*smtp.Client, func() bool, error) {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dialer := &net.Dialer{Timeout: emailDialTimeout}
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	security := strings.ToLower(strings.TrimSpace(cfg.Security))
+
+	var conn net.Conn
+	var err error
+	if security == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect %s: %w", addr, err)
+	}
+	commandConn := &emailConn{Conn: conn}
+	_ = conn.SetDeadline(time.Now().Add(emailCommandTimeout))
+	stop := context.AfterFunc(ctx, commandConn.abort)
+
+	client, err := smtp.NewClient(commandConn, cfg.Host)
+	if err != nil {
+		stop()
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("smtp handshake: %w", err)
+	}
+
+	if security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			stop()
+			_ = client.Close()
+			return nil, nil, errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			stop()
+			_ = client.Close()
+			return nil, nil, fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	return client, stop, nil
+}
+
+// emailConn gives each SMTP command its own deadline: every write, a command
+// or a chunk of the message, extends it by emailCommandTimeout to cover the
+// write and the reply. Once aborted the deadline stays expired.
+type emailConn struct {
+	net.Conn
+	mu      sync.Mutex
+	aborted bool
+}
+
+func (c *emailConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	if !c.aborted {
+		_ = c.Conn.SetDeadline(time.Now().Add(emailCommandTimeout))
+	}
+	c.mu.Unlock()
+	return c.Conn.Write(p)
+}
+
+// abort fails any blocked or later read or write, when the delivery's
+// context ends.
+func (c *emailConn) abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.aborted = true
+	_ = c.Conn.SetDeadline(time.Unix(1, 0))
+}
+
+func emailAuth(cfg config.EmailConfig) smtp.Auth {
+	switch strings.ToLower(strings.TrimSpace(cfg.Auth)) {
+	case "plain":
+		return smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	case "login":
+		return &loginAuth{host: cfg.Host, username: cfg.Username, password: cfg.Password}
+	default:
+		return nil
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not ship but
+// which Office 365 and several hosted relays still require.
+type loginAuth struct {
+	host     string
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !config.IsLocalSMTPHost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch prompt := strings.ToLower(strings.TrimSpace(string(fromServer))); {
+	case strings.HasPrefix(prompt, "username"):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "password"):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
+
+// parseEmailAddresses splits the configured sender and recipients into
+// addresses: the envelope takes only the bare address, while the headers
+// keep any display name, re-encoded so it cannot carry a line break.
+func parseEmailAddresses(cfg config.EmailConfig) (*mail.Address, []*mail.Address, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse from address %q: %w", cfg.From, err)
+	}
+	to := make([]*mail.Address, 0, len(cfg.To))
+	for _, rcpt := range cfg.To {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse to address %q: %w", rcpt, err)
+		}
+		to = append(to, addr)
+	}
+	return from, to, nil
+}
+
+// emailHeaderAddress formats addr for a From or To header, leaving a bare
+// address without the angle brackets mail.Address.String adds.
+func emailHeaderAddress(addr *mail.Address) string {
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return addr.String()
+}
+
+func renderEmailSubject(tmpl string, msg Message) (string, error) {
+	if tmpl == "" {
+		return msg.Title, nil
+	}
+
+	subject, err := renderTemplate("email.subject", tmpl, msg)
+	if err != nil {
+		return "", err
+	}
+
+	// Header injection guard: a subject must stay on one line.
+	return strings.Join(strings.Fields(subject), " "), nil
+}
+
+func buildEmailMessage(from *mail.Address, to []*mail.Address, msg Message, subject string, sentAt time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	textPart := emailTextBody(msg, sentAt)
+	htmlPart := emailHTMLBody(msg, sentAt)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=UTF-8", content: textPart},
+		{contentType: "text/html; charset=UTF-8", content: htmlPart},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, emailHeaderAddress(addr))
+	}
+
+	var out bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", emailHeaderAddress(from)},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", sentAt.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}
+	if msg.OperationID != "" {
+		headers = append(headers, struct{ key, value string }{"X-Ding-Ding-Operation-Id", msg.OperationID})
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&out, "%s: %s\r\n", h.key, h.value)
+	}
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+
+	return out.Bytes(), nil
+}
+
+func emailTextBody(msg Message, sentAt time.Time) string {
+	var b strings.Builder
+	b.WriteString(msg.Title)
+	b.WriteString("\r\n\r\n")
+	if msg.Body != "" {
+		b.WriteString(msg.Body)
+		b.WriteString("\r\n\r\n")
+	}
+	if msg.Agent != "" {
+		fmt.Fprintf(&b, "Agent: %s\r\n", msg.Agent)
+	}
+	if msg.Event != "" {
+		fmt.Fprintf(&b, "Event: %s\r\n", msg.Event)
+	}
+	if msg.ExitCode != nil {
+		fmt.Fprintf(&b, "Exit code: %d\r\n", *msg.ExitCode)
+	}
+	if where := workspaceLabel(msg); where != "" {
+		fmt.Fprintf(&b, "Repository: %s\r\n", where)
+	}
+	if msg.Hostname != "" {
+		fmt.Fprintf(&b, "Host: %s\r\n", msg.Hostname)
+	}
+	if msg.Cwd != "" {
+		fmt.Fprintf(&b, "Directory: %s\r\n", msg.Cwd)
+	}
+	for _, action := range linkButtons(msg) {
+		fmt.Fprintf(&b, "%s: %s\r\n", action.Label, action.URL)
+	}
+	fmt.Fprintf(&b, "Sent: %s\r\n", sentAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Sent (UTC): %s\r\n", sentAt.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+func emailHTMLBody(msg Message, sentAt time.Time) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html><html><body>")
+	fmt.Fprintf(&b, "<h2>%s</h2>", html.EscapeString(msg.Title))
+	if msg.Body != "" {
+		fmt.Fprintf(&b, "<p style=\"white-space: pre-wrap\">%s</p>", html.EscapeString(msg.Body))
+	}
+	b.WriteString("<table>")
+	if msg.Agent != "" {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">Agent</th><td>%s</td></tr>", html.EscapeString(msg.Agent))
+	}
+	if msg.Event != "" {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">Event</th><td>%s</td></tr>", html.EscapeString(string(msg.Event)))
+	}
+	if msg.ExitCode != nil {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">Exit code</th><td>%d</td></tr>", *msg.ExitCode)
+	}
+	if where := workspaceLabel(msg); where != "" {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">Repository</th><td>%s</td></tr>", html.EscapeString(where))
+	}
+	if msg.Hostname != "" {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">Host</th><td>%s</td></tr>", html.EscapeString(msg.Hostname))
+	}
+	if msg.Cwd != "" {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">Directory</th><td>%s</td></tr>", html.EscapeString(msg.Cwd))
+	}
+	for _, action := range linkButtons(msg) {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">%s</th><td><a href=\"%s\">%s</a></td></tr>", html.EscapeString(action.Label), html.EscapeString(action.URL), html.EscapeString(action.URL))
+	}
+	fmt.Fprintf(&b, "<tr><th align=\"left\">Sent</th><td>%s</td></tr>", html.EscapeString(sentAt.Format(time.RFC3339)))
+	fmt.Fprintf(&b, "<tr><th align=\"left\">Sent (UTC)</th><td>%s</td></tr>", html.EscapeString(sentAt.UTC().Format(time.RFC3339)))
+	b.WriteString("</table></body></html>")
+	return b.String()
+}
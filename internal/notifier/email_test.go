@@ -0,0 +1,421 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// smtpStub is a minimal in-process SMTP server that records one session.
+type smtpStub struct {
+	ln net.Listener
+
+	mu         sync.Mutex
+	authLines  []string
+	mailFrom   string
+	rcptTo     []string
+	data       string
+	rejectRcpt bool
+	replyDelay time.Duration
+}
+
+func startSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stub := &smtpStub{ln: ln}
+	go stub.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		time.Sleep(s.replyDelay)
+		_, _ = io.WriteString(conn, line+"\r\n")
+	}
+	readLine := func() (string, bool) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", false
+		}
+		return strings.TrimRight(line, "\r\n"), true
+	}
+
+	reply("220 stub ESMTP")
+	for {
+		line, ok := readLine()
+		if !ok {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-stub")
+			reply("250 AUTH PLAIN LOGIN")
+		case "AUTH":
+			s.mu.Lock()
+			s.authLines = append(s.authLines, line)
+			s.mu.Unlock()
+			if strings.HasPrefix(strings.ToUpper(line), "AUTH LOGIN") {
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				user, _ := readLine()
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				pass, _ := readLine()
+				s.mu.Lock()
+				s.authLines = append(s.authLines, user, pass)
+				s.mu.Unlock()
+			}
+			reply("235 ok")
+		case "MAIL":
+			s.mu.Lock()
+			s.mailFrom = line
+			s.mu.Unlock()
+			reply("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcptTo = append(s.rcptTo, line)
+			reject := s.rejectRcpt
+			s.mu.Unlock()
+			if reject {
+				reply("550 no such user")
+				continue
+			}
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, ok := readLine()
+				if !ok {
+					return
+				}
+				if l == "." {
+					break
+				}
+				data.WriteString(l + "\r\n")
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func stubEmailConfig(stub *smtpStub) config.EmailConfig {
+	return config.EmailConfig{
+		Enabled:  true,
+		Host:     "127.0.0.1",
+		Port:     stub.port(),
+		Security: "none",
+		Auth:     "none",
+		From:     "ding@example.com",
+		To:       []string{"a@example.com", "b@example.com"},
+		Subject:  "[ding-ding] {{.Title}} ({{.Agent}})",
+	}
+}
+
+func TestSendEmail_MultipartMessage(t *testing.T) {
+	stub := startSMTPStub(t)
+	origNow := emailNow
+	emailNow = func() time.Time { return time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC) }
+	t.Cleanup(func() { emailNow = origNow })
+
+	cfg := stubEmailConfig(stub)
+	msg := Message{Title: "Build done", Body: "all <green> & good", Agent: "claude"}
+
+	if err := sendEmail(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+
+	if stub.mailFrom != "MAIL FROM:<ding@example.com>" {
+		t.Errorf("unexpected MAIL FROM line %q", stub.mailFrom)
+	}
+	if len(stub.rcptTo) != 2 {
+		t.Fatalf("expected 2 RCPT lines, got %v", stub.rcptTo)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(stub.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("decode subject: %v", err)
+	}
+	if subject != "[ding-ding] Build done (claude)" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if got := parsed.Header.Get("To"); got != "a@example.com, b@example.com" {
+		t.Errorf("unexpected To header %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (err=%v)", mediaType, err)
+	}
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		content, _ := io.ReadAll(quotedprintable.NewReader(part))
+		ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[ct] = string(content)
+	}
+
+	text := parts["text/plain"]
+	for _, want := range []string{"Build done", "all <green> & good", "Agent: claude", "2026-03-04T05:06:07Z"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected text part to contain %q, got %q", want, text)
+		}
+	}
+	htmlPart := parts["text/html"]
+	if !strings.Contains(htmlPart, "all &lt;green&gt; &amp; good") {
+		t.Errorf("expected escaped body in html part, got %q", htmlPart)
+	}
+	if !strings.Contains(htmlPart, "claude") {
+		t.Errorf("expected agent in html part, got %q", htmlPart)
+	}
+}
+
+func TestSendEmail_DisplayNameAddresses(t *testing.T) {
+	stub := startSMTPStub(t)
+	cfg := stubEmailConfig(stub)
+	cfg.From = "Ding Ding <ding@example.com>"
+	cfg.To = []string{"Alice <a@example.com>", "b@example.com"}
+
+	if err := sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.mailFrom != "MAIL FROM:<ding@example.com>" {
+		t.Errorf("unexpected MAIL FROM line %q", stub.mailFrom)
+	}
+	if got := strings.Join(stub.rcptTo, "|"); got != "RCPT TO:<a@example.com>|RCPT TO:<b@example.com>" {
+		t.Errorf("unexpected RCPT lines %q", got)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(stub.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if got := parsed.Header.Get("From"); got != `"Ding Ding" <ding@example.com>` {
+		t.Errorf("unexpected From header %q", got)
+	}
+	if got := parsed.Header.Get("To"); got != `"Alice" <a@example.com>, b@example.com` {
+		t.Errorf("unexpected To header %q", got)
+	}
+}
+
+func TestSendEmail_RejectsHeaderInjection(t *testing.T) {
+	stub := startSMTPStub(t)
+	cfg := stubEmailConfig(stub)
+	cfg.To = []string{"a@example.com\r\nBcc: evil@example.com"}
+
+	err := sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "parse to address") {
+		t.Fatalf("sendEmail() error = %v, want address parse error", err)
+	}
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.data != "" {
+		t.Errorf("expected no message sent, got %q", stub.data)
+	}
+}
+
+func TestSendEmail_PlainAuth(t *testing.T) {
+	stub := startSMTPStub(t)
+	cfg := stubEmailConfig(stub)
+	cfg.Auth = "plain"
+	cfg.Username = "user"
+	cfg.Password = "pass"
+
+	if err := sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.authLines) != 1 {
+		t.Fatalf("expected one AUTH line, got %v", stub.authLines)
+	}
+	want := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00pass"))
+	if stub.authLines[0] != want {
+		t.Errorf("expected %q, got %q", want, stub.authLines[0])
+	}
+}
+
+func TestSendEmail_LoginAuth(t *testing.T) {
+	stub := startSMTPStub(t)
+	cfg := stubEmailConfig(stub)
+	cfg.Auth = "login"
+	cfg.Username = "user"
+	cfg.Password = "pass"
+
+	if err := sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	want := []string{
+		"AUTH LOGIN",
+		base64.StdEncoding.EncodeToString([]byte("user")),
+		base64.StdEncoding.EncodeToString([]byte("pass")),
+	}
+	if strings.Join(stub.authLines, "|") != strings.Join(want, "|") {
+		t.Errorf("expected auth exchange %v, got %v", want, stub.authLines)
+	}
+}
+
+func TestSendEmail_StartTLSUnsupported(t *testing.T) {
+	stub := startSMTPStub(t)
+	cfg := stubEmailConfig(stub)
+	cfg.Security = "starttls"
+
+	err := sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected STARTTLS error, got %q", err.Error())
+	}
+}
+
+func TestSendEmail_RecipientRejected(t *testing.T) {
+	stub := startSMTPStub(t)
+	stub.rejectRcpt = true
+	cfg := stubEmailConfig(stub)
+
+	err := sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if want := "550"; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %q", want, err.Error())
+	}
+}
+
+func setEmailCommandTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	orig := emailCommandTimeout
+	t.Cleanup(func() { emailCommandTimeout = orig })
+	emailCommandTimeout = timeout
+}
+
+func TestSendEmail_DeadlineIsPerCommand(t *testing.T) {
+	setEmailCommandTimeout(t, 200*time.Millisecond)
+	stub := startSMTPStub(t)
+	stub.replyDelay = 40 * time.Millisecond
+
+	// Eight replies take longer than one command's deadline in total.
+	start := time.Now()
+	if err := sendEmail(context.Background(), stubEmailConfig(stub), Message{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("sendEmail() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < emailCommandTimeout {
+		t.Fatalf("dialogue took %v, want longer than one command deadline", elapsed)
+	}
+}
+
+func TestSendEmail_StalledServerTimesOut(t *testing.T) {
+	setEmailCommandTimeout(t, 100*time.Millisecond)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		// Accept and never greet.
+		conn, err := ln.Accept()
+		if err == nil {
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	cfg := config.EmailConfig{Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port, Security: "none", From: "a@b", To: []string{"c@d"}}
+	start := time.Now()
+	err = sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "smtp handshake") {
+		t.Fatalf("sendEmail() error = %v, want handshake timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stalled server held the send for %v", elapsed)
+	}
+}
+
+func TestSendEmail_ConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+
+	cfg := config.EmailConfig{Host: "127.0.0.1", Port: port, Security: "none", From: "a@b", To: []string{"c@d"}}
+	err = sendEmail(context.Background(), cfg, Message{Title: "t", Body: "b"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if want := "connect 127.0.0.1:" + strconv.Itoa(port); !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %q", want, err.Error())
+	}
+}
+
+func TestRenderEmailSubject_CollapsesNewlines(t *testing.T) {
+	got, err := renderEmailSubject("{{.Title}}", Message{Title: "line one\r\nBcc: evil@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.ContainsAny(got, "\r\n") {
+		t.Errorf("expected single-line subject, got %q", got)
+	}
+}
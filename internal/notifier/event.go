@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Event classifies what happened in the agent session.
+type Event string
+
+const (
+	EventCompleted  Event = "completed"
+	EventNeedsInput Event = "needs_input"
+	EventError      Event = "error"
+	EventProgress   Event = "progress"
+	EventStarted    Event = "started"
+)
+
+// Events lists every recognized event in display order.
+var Events = []Event{EventCompleted, EventNeedsInput, EventError, EventProgress, EventStarted}
+
+// eventStyle holds the per-backend presentation for an event.
+type eventStyle struct {
+	emoji      string // Discord/Teams text prefix
+	ntfyTag    string // ntfy emoji shortcode tag
+	icon       string // freedesktop icon name
+	teamsColor string // Adaptive Card TextBlock color
+	discordRGB int    // Discord embed color
+	// urgent events raise and quiet events lower the configured urgency.
+	urgent bool
+	quiet  bool
+}
+
+var eventStyles = map[Event]eventStyle{
+	EventCompleted:  {emoji: "✅", ntfyTag: "white_check_mark", icon: "dialog-information", teamsColor: "Good", discordRGB: 0x2ECC71},
+	EventNeedsInput: {emoji: "🙋", ntfyTag: "raising_hand", icon: "dialog-question", teamsColor: "Warning", discordRGB: 0xF1C40F, urgent: true},
+	EventError:      {emoji: "❌", ntfyTag: "x", icon: "dialog-error", teamsColor: "Attention", discordRGB: 0xE74C3C, urgent: true},
+	EventProgress:   {emoji: "⏳", ntfyTag: "hourglass_flowing_sand", icon: "view-refresh", teamsColor: "Accent", discordRGB: 0x3498DB, quiet: true},
+	EventStarted:    {emoji: "🚀", ntfyTag: "rocket", icon: "media-playback-start", teamsColor: "Accent", discordRGB: 0x5865F2, quiet: true},
+}
+
+// ParseEvent validates an event name. The empty string is accepted and means
+// "derive from the exit code" (see effectiveEvent).
+func ParseEvent(value string) (Event, error) {
+	normalized := Event(strings.ToLower(strings.TrimSpace(value)))
+	if normalized == "" {
+		return "", nil
+	}
+	if _, ok := eventStyles[normalized]; ok {
+		return normalized, nil
+	}
+
+	names := make([]string, len(Events))
+	for i, event := range Events {
+		names[i] = string(event)
+	}
+	return "", fmt.Errorf("invalid event %q (expected one of %s)", value, strings.Join(names, ", "))
+}
+
+// effectiveEvent returns the message's event, or one derived from its exit
+// code: error when non-zero, completed when zero. A message with neither
+// stays unlabeled and gets neutral styling.
+func effectiveEvent(msg Message) Event {
+	if msg.Event != "" {
+		return msg.Event
+	}
+	if msg.ExitCode == nil {
+		return ""
+	}
+	if *msg.ExitCode != 0 {
+		return EventError
+	}
+	return EventCompleted
+}
+
+func styleFor(event Event) eventStyle {
+	return eventStyles[event]
+}
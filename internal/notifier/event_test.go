@@ -0,0 +1,155 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Event
+		wantErr bool
+	}{
+		{input: "", want: ""},
+		{input: "completed", want: EventCompleted},
+		{input: " Needs_Input ", want: EventNeedsInput},
+		{input: "error", want: EventError},
+		{input: "progress", want: EventProgress},
+		{input: "started", want: EventStarted},
+		{input: "finished", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEvent(tt.input)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "expected one of completed, needs_input") {
+				t.Errorf("ParseEvent(%q) error = %v, want invalid event error", tt.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEvent(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEvent(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveEvent(t *testing.T) {
+	zero, failed := 0, 2
+
+	tests := []struct {
+		name string
+		msg  Message
+		want Event
+	}{
+		{name: "unlabeled", msg: Message{}, want: ""},
+		{name: "zero exit code", msg: Message{ExitCode: &zero}, want: EventCompleted},
+		{name: "non-zero exit code", msg: Message{ExitCode: &failed}, want: EventError},
+		{name: "explicit event wins", msg: Message{Event: EventProgress, ExitCode: &failed}, want: EventProgress},
+	}
+
+	for _, tt := range tests {
+		if got := effectiveEvent(tt.msg); got != tt.want {
+			t.Errorf("%s: effectiveEvent() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNtfyPriority_AdjustsByEvent(t *testing.T) {
+	tests := []struct {
+		configured string
+		event      Event
+		want       string
+	}{
+		{configured: "default", event: EventCompleted, want: "default"},
+		{configured: "", event: EventCompleted, want: ""},
+		{configured: "default", event: EventError, want: "high"},
+		{configured: "", event: EventNeedsInput, want: "high"},
+		{configured: "max", event: EventError, want: "max"},
+		{configured: "default", event: EventProgress, want: "low"},
+		{configured: "min", event: EventStarted, want: "min"},
+		{configured: "4", event: EventStarted, want: "low"},
+	}
+
+	for _, tt := range tests {
+		if got := ntfyPriority(tt.configured, tt.event); got != tt.want {
+			t.Errorf("ntfyPriority(%q, %q) = %q, want %q", tt.configured, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestNewSystemNotification_EventStyling(t *testing.T) {
+	cfg := config.DefaultConfig().Notification
+
+	n := newSystemNotification(cfg, Message{Title: "t", Event: EventNeedsInput})
+	if n.Urgency != "critical" {
+		t.Errorf("needs_input urgency = %q, want critical", n.Urgency)
+	}
+	if n.Icon != "dialog-question" {
+		t.Errorf("needs_input icon = %q, want dialog-question", n.Icon)
+	}
+
+	n = newSystemNotification(cfg, Message{Title: "t", Event: EventProgress})
+	if n.Urgency != "low" {
+		t.Errorf("progress urgency = %q, want low", n.Urgency)
+	}
+
+	cfg.Urgency = "low"
+	cfg.Icon = "utilities-terminal"
+	n = newSystemNotification(cfg, Message{Title: "t", Event: EventError})
+	if n.Urgency != "low" {
+		t.Errorf("explicit urgency overridden: got %q, want low", n.Urgency)
+	}
+	if n.Icon != "utilities-terminal" {
+		t.Errorf("configured icon overridden: got %q", n.Icon)
+	}
+}
+
+func TestNewSystemNotification_UnlabeledIsNeutral(t *testing.T) {
+	n := newSystemNotification(config.DefaultConfig().Notification, Message{Title: "needs permission"})
+	if n.Urgency != "normal" {
+		t.Errorf("unlabeled urgency = %q, want normal", n.Urgency)
+	}
+	if n.Icon != "" {
+		t.Errorf("unlabeled icon = %q, want none", n.Icon)
+	}
+}
+
+func TestPushContext_UnlabeledMessageHasNoEvent(t *testing.T) {
+	var payload map[string]any
+	var ntfyTags string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hook" {
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+		} else {
+			ntfyTags = r.Header.Get("Tags")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.URL = srv.URL + "/hook"
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+
+	if err := PushContext(context.Background(), cfg, Message{Title: "needs permission"}); err != nil {
+		t.Fatalf("PushContext() error = %v", err)
+	}
+	if _, ok := payload["event"]; ok {
+		t.Errorf("webhook payload event = %v, want none", payload["event"])
+	}
+	if ntfyTags != "" {
+		t.Errorf("ntfy tags = %q, want none", ntfyTags)
+	}
+}
@@ -0,0 +1,227 @@
+package notifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// groupStateTTL bounds how long a replace-in-place group is remembered. An
+// agent session that has been quiet longer than this starts a fresh bubble.
+const groupStateTTL = 24 * time.Hour
+
+const (
+	// groupLockWait bounds how long an update waits for another process
+	// to release the state file lock before giving up.
+	groupLockWait = time.Second
+	// groupLockStale is the age after which a lock file is assumed to be
+	// left behind by a crashed process and removed.
+	groupLockStale = 10 * time.Second
+)
+
+// groupState records the backend-assigned IDs of the last notification sent
+// for a group so the next one can replace it.
+type groupState struct {
+	DBusID           uint32    `json:"dbus_id,omitempty"`
+	DiscordMessageID string    `json:"discord_message_id,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type groupStore interface {
+	load(key string) groupState
+	update(key string, apply func(*groupState))
+}
+
+var (
+	groupStoreMu sync.Mutex
+	groups       groupStore = newFileGroupStore(defaultGroupStatePath())
+)
+
+// TrackGroupsInMemory keeps replace-in-place state in process memory instead
+// of the on-disk state file. The long-running server uses it; one-shot CLI
+// invocations need the file to remember IDs across runs.
+func TrackGroupsInMemory() {
+	groupStoreMu.Lock()
+	defer groupStoreMu.Unlock()
+	groups = newMemoryGroupStore()
+}
+
+func currentGroupStore() groupStore {
+	groupStoreMu.Lock()
+	defer groupStoreMu.Unlock()
+	return groups
+}
+
+// groupKey identifies notifications that should replace each other: the same
+// agent and session key. Messages without a session are never grouped, so
+// unrelated runs of the same agent do not overwrite each other.
+func groupKey(msg Message) string {
+	session := strings.TrimSpace(msg.Session)
+	if session == "" {
+		return ""
+	}
+	return strings.TrimSpace(msg.Agent) + "\x00" + session
+}
+
+// groupTag derives a short, charset-safe identifier from a group key for
+// backends with strict tag formats (ntfy sequence IDs, Windows toast tags).
+func groupTag(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "ding-ding-" + hex.EncodeToString(sum[:8])
+}
+
+type memoryGroupStore struct {
+	mu     sync.Mutex
+	states map[string]groupState
+}
+
+func newMemoryGroupStore() *memoryGroupStore {
+	return &memoryGroupStore{states: map[string]groupState{}}
+}
+
+func (s *memoryGroupStore) load(key string) groupState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.states[key]
+	if time.Since(state.UpdatedAt) > groupStateTTL {
+		return groupState{}
+	}
+	return state
+}
+
+func (s *memoryGroupStore) update(key string, apply func(*groupState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.states[key]
+	apply(&state)
+	state.UpdatedAt = time.Now()
+	s.states[key] = state
+}
+
+// fileGroupStore persists group state as JSON. Concurrent CLI invocations
+// serialize updates with a lock file next to it. Failures are non-fatal: the
+// worst case is a new notification instead of an in-place update.
+type fileGroupStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newFileGroupStore(path string) *fileGroupStore {
+	return &fileGroupStore{path: path}
+}
+
+func defaultGroupStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil || dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "ding-ding", "groups.json")
+}
+
+func (s *fileGroupStore) load(key string) groupState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.read()[key]
+	if time.Since(state.UpdatedAt) > groupStateTTL {
+		return groupState{}
+	}
+	return state
+}
+
+func (s *fileGroupStore) update(key string, apply func(*groupState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		DefaultLoggerFunc().Debug("notifier.group.state_write_failed", "error", err)
+		return
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		DefaultLoggerFunc().Debug("notifier.group.state_write_failed", "error", err)
+		return
+	}
+	defer unlock()
+
+	states := s.read()
+	for k, state := range states {
+		if time.Since(state.UpdatedAt) > groupStateTTL {
+			delete(states, k)
+		}
+	}
+
+	state := states[key]
+	apply(&state)
+	state.UpdatedAt = time.Now()
+	states[key] = state
+
+	data, err := json.Marshal(states)
+	if err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		DefaultLoggerFunc().Debug("notifier.group.state_write_failed", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		DefaultLoggerFunc().Debug("notifier.group.state_write_failed", "error", err)
+	}
+}
+
+// lock takes the cross-process lock on the state file by creating
+// path.lock exclusively, waiting up to groupLockWait for another holder.
+func (s *fileGroupStore) lock() (func(), error) {
+	lockPath := s.path + ".lock"
+	deadline := time.Now().Add(groupLockWait)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > groupLockStale {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("state file is locked by another process")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *fileGroupStore) read() map[string]groupState {
+	states := map[string]groupState{}
+	if s.path == "" {
+		return states
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			DefaultLoggerFunc().Debug("notifier.group.state_read_failed", "error", err)
+		}
+		return states
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		DefaultLoggerFunc().Debug("notifier.group.state_read_failed", "error", err)
+		return map[string]groupState{}
+	}
+	return states
+}
@@ -0,0 +1,235 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func useMemoryGroups(t *testing.T) *memoryGroupStore {
+	t.Helper()
+	orig := groups
+	store := newMemoryGroupStore()
+	groups = store
+	t.Cleanup(func() { groups = orig })
+	return store
+}
+
+func TestGroupKey(t *testing.T) {
+	if got := groupKey(Message{}); got != "" {
+		t.Errorf("expected empty key without agent or session, got %q", got)
+	}
+	if got := groupKey(Message{Agent: "claude"}); got != "" {
+		t.Errorf("expected empty key without a session, got %q", got)
+	}
+	a := groupKey(Message{Agent: "claude", Session: "s1"})
+	b := groupKey(Message{Agent: "claude", Session: "s2"})
+	if a == "" || a == b {
+		t.Errorf("expected distinct keys per session, got %q and %q", a, b)
+	}
+	if groupKey(Message{Agent: "claude", Session: "s1", Title: "other"}) != a {
+		t.Error("expected key to ignore title/body")
+	}
+}
+
+func TestGroupTag_IsStableAndCharsetSafe(t *testing.T) {
+	key := groupKey(Message{Agent: "claude code", Session: "ünïcode/session"})
+	tag := groupTag(key)
+	if tag != groupTag(key) {
+		t.Error("expected deterministic tag")
+	}
+	if !regexp.MustCompile(`^[-_A-Za-z0-9]{1,64}$`).MatchString(tag) {
+		t.Errorf("tag %q is not charset safe", tag)
+	}
+	if groupTag("") != "" {
+		t.Error("expected empty tag for empty key")
+	}
+}
+
+func TestFileGroupStore_RoundTripAndExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "groups.json")
+	store := newFileGroupStore(path)
+
+	store.update("k", func(state *groupState) { state.DBusID = 9 })
+	store.update("k", func(state *groupState) { state.DiscordMessageID = "m1" })
+
+	reopened := newFileGroupStore(path)
+	got := reopened.load("k")
+	if got.DBusID != 9 || got.DiscordMessageID != "m1" {
+		t.Errorf("unexpected state after reload: %+v", got)
+	}
+
+	stale, _ := json.Marshal(map[string]groupState{
+		"stale": {DBusID: 1, UpdatedAt: time.Now().Add(-2 * groupStateTTL)},
+	})
+	if err := os.WriteFile(path, stale, 0o600); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	if got := reopened.load("stale"); got.DBusID != 0 {
+		t.Errorf("expected expired state to be ignored, got %+v", got)
+	}
+
+	reopened.update("fresh", func(state *groupState) { state.DBusID = 2 })
+	if _, ok := reopened.read()["stale"]; ok {
+		t.Error("expected expired entries to be pruned on update")
+	}
+}
+
+func TestFileGroupStore_ConcurrentStoresKeepEveryUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.json")
+
+	// Separate stores stand in for separate CLI processes: only the lock
+	// file serializes them.
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			newFileGroupStore(path).update(fmt.Sprintf("k%d", i), func(state *groupState) { state.DBusID = uint32(i + 1) })
+		}()
+	}
+	wg.Wait()
+
+	if got := len(newFileGroupStore(path).read()); got != 20 {
+		t.Errorf("state file has %d groups, want 20", got)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestDispatch_ReplaceInPlaceDisabledLeavesGroupEmpty(t *testing.T) {
+	cfg := testConfig()
+	cfg.Notification.ReplaceInPlace = false
+	if got := withGroup(cfg, Message{Agent: "claude", Session: "s1"}).group; got != "" {
+		t.Errorf("expected no group when replace_in_place is off, got %q", got)
+	}
+	cfg.Notification.ReplaceInPlace = true
+	if got := withGroup(cfg, Message{Agent: "claude", Session: "s1"}).group; got == "" {
+		t.Error("expected group when replace_in_place is on")
+	}
+}
+
+func TestLinuxNotify_GroupedReusesReplacesID(t *testing.T) {
+	useMemoryGroups(t)
+	bus := &fakeNotificationsBus{id: 11}
+	setupFakeDBus(t, bus, nil)
+
+	n := SystemNotification{Title: "t", Group: "claude\x00s1"}
+	if err := linuxNotify(context.Background(), n); err != nil {
+		t.Fatalf("first notify: %v", err)
+	}
+	if bus.args[1] != uint32(0) {
+		t.Errorf("first replaces_id = %v, want 0", bus.args[1])
+	}
+
+	bus.id = 12
+	if err := linuxNotify(context.Background(), n); err != nil {
+		t.Fatalf("second notify: %v", err)
+	}
+	if bus.args[1] != uint32(11) {
+		t.Errorf("second replaces_id = %v, want 11", bus.args[1])
+	}
+
+	if err := linuxNotify(context.Background(), SystemNotification{Title: "ungrouped"}); err != nil {
+		t.Fatalf("ungrouped notify: %v", err)
+	}
+	if bus.args[1] != uint32(0) {
+		t.Errorf("ungrouped replaces_id = %v, want 0", bus.args[1])
+	}
+}
+
+func TestSendNtfy_GroupedSetsSequenceID(t *testing.T) {
+	var gotSequence string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotSequence = r.Header.Get("X-Sequence-ID")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	msg := Message{Title: "t", Agent: "claude", group: groupKey(Message{Agent: "claude", Session: "s1"})}
+	if err := sendNtfy(context.Background(), config.NtfyConfig{Server: srv.URL, Topic: "topic"}, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if gotSequence != groupTag(msg.group) {
+		t.Errorf("expected X-Sequence-ID %q, got %q", groupTag(msg.group), gotSequence)
+	}
+}
+
+func TestSendDiscord_GroupedEditsPreviousMessage(t *testing.T) {
+	useMemoryGroups(t)
+
+	var mu sync.Mutex
+	var calls []string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		mu.Unlock()
+		_, _ = io.ReadAll(r.Body)
+		switch r.Method {
+		case "POST":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"id":"111"}`)
+		case "PATCH":
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	cfg := config.DiscordConfig{WebhookURL: srv.URL + "/api/webhooks/1/abc"}
+	msg := Message{Title: "t", Agent: "claude", group: groupKey(Message{Agent: "claude", Session: "s1"})}
+
+	for i := 0; i < 2; i++ {
+		if err := sendDiscord(context.Background(), cfg, msg); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	want := []string{
+		"POST /api/webhooks/1/abc?wait=true",
+		"PATCH /api/webhooks/1/abc/messages/111?",
+	}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestSendDiscord_GroupedDeletedMessageFallsBackToPost(t *testing.T) {
+	store := useMemoryGroups(t)
+	key := groupKey(Message{Agent: "claude", Session: "s1"})
+	store.update(key, func(state *groupState) { state.DiscordMessageID = "gone" })
+
+	var methods []string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == "PATCH" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"222"}`)
+	})
+
+	msg := Message{Title: "t", Agent: "claude", group: key}
+	ctx, attempts := withAttemptCounter(context.Background())
+	if err := sendDiscord(ctx, config.DiscordConfig{WebhookURL: srv.URL}, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if strings.Join(methods, ",") != "PATCH,POST" {
+		t.Errorf("expected PATCH then POST, got %v", methods)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2 for the fallback post", got)
+	}
+	if got := store.load(key).DiscordMessageID; got != "222" {
+		t.Errorf("expected stored message id 222, got %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// httpClientFor returns the client for a backend's effective http settings.
+// Tests replace it to route requests to a test server.
+var httpClientFor = cachedHTTPClient
+
+// httpClients holds one client per distinct http config, so each backend
+// keeps its connections and TLS setup across notifications in server mode.
+var (
+	httpClientsMu sync.Mutex
+	httpClients   = map[httpClientKey]*http.Client{}
+)
+
+// httpClientKey is an http config by value: InsecureSkipVerify is a pointer,
+// so equal configs loaded separately would otherwise get separate clients.
+type httpClientKey struct {
+	config.HTTPConfig
+	skipVerify bool
+}
+
+func cachedHTTPClient(cfg config.HTTPConfig) (*http.Client, error) {
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+
+	key := httpClientKey{HTTPConfig: cfg, skipVerify: cfg.SkipVerify()}
+	key.InsecureSkipVerify = nil
+	if client, ok := httpClients[key]; ok {
+		return client, nil
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	httpClients[key] = client
+	return client, nil
+}
+
+// newHTTPClient builds a client with the configured proxy, trust roots,
+// client certificate and timeouts.
+func newHTTPClient(cfg config.HTTPConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.ConnectTimeoutSeconds > 0 {
+		dialer := &net.Dialer{Timeout: time.Duration(cfg.ConnectTimeoutSeconds) * time.Second, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = dialer.Timeout
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca_file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s contains no PEM certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.SkipVerify() {
+		DefaultLoggerFunc().Warn("notifier.http.insecure_skip_verify", "proxy_present", cfg.Proxy != "", "ca_file_present", cfg.CAFile != "")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}, nil
+}
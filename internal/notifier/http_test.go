@@ -0,0 +1,189 @@
+package notifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewHTTPClient_CAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	plain, err := newHTTPClient(config.HTTPConfig{TimeoutSeconds: 5})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	if _, err := plain.Get(srv.URL); err == nil {
+		t.Fatal("expected an untrusted certificate error without ca_file")
+	}
+
+	caFile := writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	client, err := newHTTPClient(config.HTTPConfig{CAFile: caFile, TimeoutSeconds: 5})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with ca_file failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewHTTPClient_ClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client, err := newHTTPClient(config.HTTPConfig{
+		CAFile:         writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw),
+		CertFile:       writePEM(t, "client.pem", "CERTIFICATE", der),
+		KeyFile:        writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER),
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with a client certificate, got %d", resp.StatusCode)
+	}
+}
+
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	var gotTarget string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTarget = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client, err := newHTTPClient(config.HTTPConfig{Proxy: proxy.URL, TimeoutSeconds: 5})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	resp, err := client.Get("http://ntfy.internal.example/topic")
+	if err != nil {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+	resp.Body.Close()
+	if gotTarget != "http://ntfy.internal.example/topic" {
+		t.Fatalf("proxy saw %q, want the absolute target URL", gotTarget)
+	}
+}
+
+func TestNewHTTPClient_Settings(t *testing.T) {
+	skip := true
+	client, err := newHTTPClient(config.HTTPConfig{InsecureSkipVerify: &skip, TimeoutSeconds: 7, ConnectTimeoutSeconds: 3})
+	if err != nil {
+		t.Fatalf("newHTTPClient() error = %v", err)
+	}
+	if client.Timeout != 7*time.Second {
+		t.Errorf("Timeout = %v, want 7s", client.Timeout)
+	}
+	transport := client.Transport.(*http.Transport)
+	if !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("expected InsecureSkipVerify to be set")
+	}
+	if transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("TLSHandshakeTimeout = %v, want 3s", transport.TLSHandshakeTimeout)
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, cfg := range map[string]config.HTTPConfig{
+		"missing ca":   {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"non-pem ca":   {CAFile: notPEM},
+		"missing cert": {CertFile: "missing.pem", KeyFile: "missing-key.pem"},
+	} {
+		if _, err := newHTTPClient(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCachedHTTPClient_ReusesClientPerConfig(t *testing.T) {
+	a, err := cachedHTTPClient(config.HTTPConfig{TimeoutSeconds: 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := cachedHTTPClient(config.HTTPConfig{TimeoutSeconds: 11})
+	c, _ := cachedHTTPClient(config.HTTPConfig{TimeoutSeconds: 12})
+	if a != b {
+		t.Error("expected the same client for equal configs")
+	}
+	if a == c {
+		t.Error("expected separate clients for different configs")
+	}
+
+	skipA, skipB := true, true
+	d, _ := cachedHTTPClient(config.HTTPConfig{TimeoutSeconds: 11, InsecureSkipVerify: &skipA})
+	e, _ := cachedHTTPClient(config.HTTPConfig{TimeoutSeconds: 11, InsecureSkipVerify: &skipB})
+	if d != e {
+		t.Error("expected the same client for equal insecure_skip_verify values")
+	}
+	if d == a {
+		t.Error("expected separate clients with and without insecure_skip_verify")
+	}
+
+	_, err = cachedHTTPClient(config.HTTPConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	if err == nil || !strings.Contains(err.Error(), "ca_file") {
+		t.Fatalf("expected ca_file error, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretServiceName   = "org.freedesktop.secrets"
+	secretServicePath   = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceOpen   = "org.freedesktop.Secret.Service.OpenSession"
+	secretServiceSearch = "org.freedesktop.Secret.Service.SearchItems"
+	secretItemGetSecret = "org.freedesktop.Secret.Item.GetSecret"
+	secretSessionClose  = "org.freedesktop.Secret.Session.Close"
+)
+
+// secretServiceBus is the subset of a session bus connection used to read
+// the keyring, so tests can substitute a fake Secret Service.
+type secretServiceBus interface {
+	Call(ctx context.Context, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call
+	Close() error
+}
+
+var secretServiceConnect = connectSecretService
+
+// secretServiceSecret is the Secret struct of the Secret Service API.
+type secretServiceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+type sessionSecretService struct {
+	conn *dbus.Conn
+}
+
+func connectSecretService() (secretServiceBus, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connect session bus: %w", err)
+	}
+	return &sessionSecretService{conn: conn}, nil
+}
+
+func (s *sessionSecretService) Call(ctx context.Context, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	return s.conn.Object(secretServiceName, path).CallWithContext(ctx, method, 0, args...)
+}
+
+func (s *sessionSecretService) Close() error {
+	return s.conn.Close()
+}
+
+// keyringSecret reads the item with the attributes service and username
+// from the Secret Service (GNOME Keyring, KWallet), the attributes that
+// secret-tool and most keyring libraries store. Locked items are reported
+// rather than unlocked, since unlocking needs an interactive prompt.
+func keyringSecret(ctx context.Context, service, account string) (string, error) {
+	bus, err := secretServiceConnect()
+	if err != nil {
+		return "", fmt.Errorf("keyring: %w", err)
+	}
+	defer bus.Close()
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := bus.Call(ctx, secretServicePath, secretServiceOpen, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", fmt.Errorf("keyring: open session: %w", err)
+	}
+	defer bus.Call(ctx, session, secretSessionClose)
+
+	attributes := map[string]string{"service": service, "username": account}
+	var unlocked, locked []dbus.ObjectPath
+	if err := bus.Call(ctx, secretServicePath, secretServiceSearch, attributes).Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("keyring: search items: %w", err)
+	}
+	if len(unlocked) == 0 {
+		if len(locked) > 0 {
+			return "", fmt.Errorf("keyring item %s/%s is locked; unlock the keyring first", service, account)
+		}
+		return "", fmt.Errorf("keyring item %s/%s not found", service, account)
+	}
+
+	var secret secretServiceSecret
+	if err := bus.Call(ctx, unlocked[0], secretItemGetSecret, session).Store(&secret); err != nil {
+		return "", fmt.Errorf("keyring: read item %s/%s: %w", service, account, err)
+	}
+	return string(secret.Value), nil
+}
@@ -0,0 +1,497 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+const (
+	mqttPacketConnect    byte = 0x10
+	mqttPacketConnack    byte = 0x20
+	mqttPacketPublish    byte = 0x30
+	mqttPacketPuback     byte = 0x40
+	mqttPacketPingreq    byte = 0xC0
+	mqttPacketPingresp   byte = 0xD0
+	mqttPacketDisconnect byte = 0xE0
+)
+
+var mqttTimeout = 15 * time.Second
+var mqttKeepAlive = 60 * time.Second
+
+var (
+	mqttPersistMu  sync.Mutex
+	mqttPersistent bool
+	mqttSessions   = map[mqttSessionKey]*mqttSession{}
+)
+
+// mqttSessionKey identifies a persistent connection. A broker allows one
+// connection per client ID, so configs that differ only in what they
+// publish (topic, QoS, retain, template) share it instead of taking the
+// client ID over from each other.
+type mqttSessionKey struct {
+	broker   string
+	clientID string
+}
+
+// mqttMessage is one publish; everything that is not part of the
+// connection.
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+}
+
+// KeepConnectionsOpen makes connection-oriented backends (MQTT) reuse a single
+// connection across notifications instead of connecting per message, and
+// keeps D-Bus connections open so notification action buttons can be
+// answered. The long-running server enables it; CLI invocations stay one-shot.
+func KeepConnectionsOpen() {
+	mqttPersistMu.Lock()
+	defer mqttPersistMu.Unlock()
+	mqttPersistent = true
+	setDBusActionHandling(true)
+}
+
+// CloseConnections disconnects any persistent backend connections and returns
+// to one-shot mode.
+func CloseConnections() {
+	mqttPersistMu.Lock()
+	defer mqttPersistMu.Unlock()
+	for key, session := range mqttSessions {
+		session.close()
+		delete(mqttSessions, key)
+	}
+	mqttPersistent = false
+	setDBusActionHandling(false)
+}
+
+func sendMQTT(ctx context.Context, cfg config.MQTTConfig, msg Message) error {
+	msg, err := applyTemplate("mqtt", cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	publish := mqttMessage{
+		topic:   mqttTopic(cfg.Topic, msg),
+		payload: payload,
+		qos:     byte(cfg.QoS),
+		retain:  cfg.Retain,
+	}
+
+	mqttPersistMu.Lock()
+	if !mqttPersistent {
+		mqttPersistMu.Unlock()
+
+		session := newMQTTSession(cfg, false)
+		defer session.close()
+		return session.publish(ctx, publish)
+	}
+
+	// New credentials for the same broker and client ID replace the
+	// connection; the broker would drop the old one anyway.
+	key := mqttSessionKey{broker: cfg.Broker, clientID: mqttClientID(cfg)}
+	session, ok := mqttSessions[key]
+	if ok && (session.username != cfg.Username || session.password != cfg.Password) {
+		session.close()
+		ok = false
+	}
+	if !ok {
+		session = newMQTTSession(cfg, true)
+		mqttSessions[key] = session
+	}
+	mqttPersistMu.Unlock()
+
+	return session.publish(ctx, publish)
+}
+
+// mqttClientID is the configured client ID, or one unique to this process.
+func mqttClientID(cfg config.MQTTConfig) string {
+	if cfg.ClientID != "" {
+		return cfg.ClientID
+	}
+	return fmt.Sprintf("ding-ding-%d", os.Getpid())
+}
+
+func mqttTopic(tmpl string, msg Message) string {
+	agent := mqttTopicSegment(msg.Agent)
+	if agent == "" {
+		agent = "unknown"
+	}
+	event := string(effectiveEvent(msg))
+	if event == "" {
+		event = "unknown"
+	}
+
+	return strings.NewReplacer(
+		"{agent}", agent,
+		"{event}", event,
+	).Replace(tmpl)
+}
+
+// mqttTopicSegment strips characters that would split or wildcard a topic.
+func mqttTopicSegment(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '+', '#', 0:
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// mqttSession is a minimal MQTT 3.1.1 publisher. Only the packets needed to
+// publish at QoS 0/1 are implemented; the session never subscribes, so every
+// inbound packet is a direct response to the last request.
+type mqttSession struct {
+	broker    string
+	clientID  string
+	username  string
+	password  string
+	keepAlive bool
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	packetID uint16
+	stopPing chan struct{}
+}
+
+func newMQTTSession(cfg config.MQTTConfig, keepAlive bool) *mqttSession {
+	return &mqttSession{
+		broker:    cfg.Broker,
+		clientID:  mqttClientID(cfg),
+		username:  cfg.Username,
+		password:  cfg.Password,
+		keepAlive: keepAlive,
+	}
+}
+
+func (s *mqttSession) publish(ctx context.Context, msg mqttMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reused := s.conn != nil
+	if err := s.publishLocked(ctx, msg); err != nil {
+		s.closeLocked()
+		if !reused {
+			return err
+		}
+		// A persistent connection may have been dropped by the broker while
+		// idle; retry once on a fresh connection.
+		countRetry(ctx)
+		if err := s.publishLocked(ctx, msg); err != nil {
+			s.closeLocked()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *mqttSession) publishLocked(ctx context.Context, msg mqttMessage) error {
+	if s.conn == nil {
+		if err := s.connectLocked(ctx); err != nil {
+			return err
+		}
+	}
+	defer abortOnDone(ctx, s.conn)()
+
+	s.packetID++
+	if s.packetID == 0 {
+		s.packetID = 1
+	}
+
+	header := mqttPacketPublish | msg.qos<<1
+	if msg.retain {
+		header |= 0x01
+	}
+
+	var body []byte
+	body = appendMQTTString(body, msg.topic)
+	if msg.qos > 0 {
+		body = binary.BigEndian.AppendUint16(body, s.packetID)
+	}
+	body = append(body, msg.payload...)
+
+	_ = s.conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if err := writeMQTTPacket(s.conn, header, body); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	if msg.qos == 0 {
+		return nil
+	}
+
+	packetType, ack, err := readMQTTPacket(s.reader)
+	if err != nil {
+		return fmt.Errorf("read puback: %w", err)
+	}
+	if packetType != mqttPacketPuback || len(ack) < 2 {
+		return fmt.Errorf("unexpected packet 0x%02x waiting for puback", packetType)
+	}
+	if id := binary.BigEndian.Uint16(ack); id != s.packetID {
+		return fmt.Errorf("puback for packet %d, want %d", id, s.packetID)
+	}
+
+	return nil
+}
+
+func (s *mqttSession) connectLocked(ctx context.Context) error {
+	// Sessions keep the configured password, so a reference is resolved on
+	// every connect and a rotated secret is picked up.
+	password, err := resolveSecret(ctx, s.password)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+
+	conn, err := dialMQTT(ctx, s.broker)
+	if err != nil {
+		return err
+	}
+	defer abortOnDone(ctx, conn)()
+	reader := bufio.NewReader(conn)
+
+	flags := byte(0x02) // clean session
+	if s.username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	body = append(body, 0x04, flags)
+	keepAliveSeconds := uint16(0)
+	if s.keepAlive {
+		keepAliveSeconds = uint16(mqttKeepAlive / time.Second)
+	}
+	body = binary.BigEndian.AppendUint16(body, keepAliveSeconds)
+	body = appendMQTTString(body, s.clientID)
+	if flags&0x80 != 0 {
+		body = appendMQTTString(body, s.username)
+	}
+	if flags&0x40 != 0 {
+		body = appendMQTTString(body, password)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if err := writeMQTTPacket(conn, mqttPacketConnect, body); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	packetType, ack, err := readMQTTPacket(reader)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("read connack: %w", err)
+	}
+	if packetType != mqttPacketConnack || len(ack) < 2 {
+		_ = conn.Close()
+		return fmt.Errorf("unexpected packet 0x%02x waiting for connack", packetType)
+	}
+	if code := ack[1]; code != 0 {
+		_ = conn.Close()
+		return fmt.Errorf("broker refused connection: %s", mqttConnackReason(code))
+	}
+
+	s.conn = conn
+	s.reader = reader
+
+	if s.keepAlive && keepAliveSeconds > 0 {
+		s.stopPing = make(chan struct{})
+		go s.pingLoop(s.stopPing)
+	}
+
+	return nil
+}
+
+func (s *mqttSession) pingLoop(stop chan struct{}) {
+	ticker := time.NewTicker(mqttKeepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.conn == nil {
+				s.mu.Unlock()
+				return
+			}
+			if err := s.pingLocked(); err != nil {
+				s.closeLocked()
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *mqttSession) pingLocked() error {
+	_ = s.conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if err := writeMQTTPacket(s.conn, mqttPacketPingreq, nil); err != nil {
+		return err
+	}
+	packetType, _, err := readMQTTPacket(s.reader)
+	if err != nil {
+		return err
+	}
+	if packetType != mqttPacketPingresp {
+		return fmt.Errorf("unexpected packet 0x%02x waiting for pingresp", packetType)
+	}
+	return nil
+}
+
+func (s *mqttSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *mqttSession) closeLocked() {
+	if s.stopPing != nil {
+		close(s.stopPing)
+		s.stopPing = nil
+	}
+	if s.conn == nil {
+		return
+	}
+	_ = s.conn.SetDeadline(time.Now().Add(mqttTimeout))
+	_ = writeMQTTPacket(s.conn, mqttPacketDisconnect, nil)
+	_ = s.conn.Close()
+	s.conn = nil
+	s.reader = nil
+}
+
+func dialMQTT(ctx context.Context, broker string) (net.Conn, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("parse broker url: %w", err)
+	}
+
+	useTLS := false
+	defaultPort := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS = true
+		defaultPort = "8883"
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	if useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", addr, err)
+	}
+
+	return conn, nil
+}
+
+func mqttConnackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("return code %d", code)
+	}
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func writeMQTTPacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	packet = appendMQTTRemainingLength(packet, len(body))
+	packet = append(packet, body...)
+	_, err := w.Write(packet)
+	return err
+}
+
+func appendMQTTRemainingLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := 0
+	multiplier := 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header & 0xF0, body, nil
+}
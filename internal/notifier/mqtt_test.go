@@ -0,0 +1,339 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+type mqttPublished struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+}
+
+// mqttBroker is a minimal in-process MQTT 3.1.1 broker that accepts
+// connections, acknowledges publishes and records what it received.
+type mqttBroker struct {
+	ln          net.Listener
+	connackCode byte
+
+	mu    sync.Mutex
+	state mqttBrokerState
+}
+
+type mqttBrokerState struct {
+	connects    int
+	clientID    string
+	username    string
+	password    string
+	keepAlive   uint16
+	published   []mqttPublished
+	disconnects int
+}
+
+func startMQTTBroker(t *testing.T) *mqttBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	broker := &mqttBroker{ln: ln}
+	go broker.serve()
+	t.Cleanup(func() {
+		_ = ln.Close()
+		CloseConnections()
+	})
+	return broker
+}
+
+func (b *mqttBroker) url() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func (b *mqttBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *mqttBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		first, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+		flags := first[0] & 0x0F
+		packetType, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+
+		switch packetType {
+		case mqttPacketConnect:
+			b.recordConnect(body)
+			_ = writeMQTTPacket(conn, mqttPacketConnack, []byte{0x00, b.connackCode})
+			if b.connackCode != 0 {
+				return
+			}
+		case mqttPacketPublish:
+			qos := (flags >> 1) & 0x03
+			topicLen := int(binary.BigEndian.Uint16(body))
+			topic := string(body[2 : 2+topicLen])
+			rest := body[2+topicLen:]
+			var id []byte
+			if qos > 0 {
+				id, rest = rest[:2], rest[2:]
+			}
+			b.mu.Lock()
+			b.state.published = append(b.state.published, mqttPublished{topic: topic, payload: rest, qos: qos, retain: flags&0x01 != 0})
+			b.mu.Unlock()
+			if qos > 0 {
+				_ = writeMQTTPacket(conn, mqttPacketPuback, id)
+			}
+		case mqttPacketPingreq:
+			_ = writeMQTTPacket(conn, mqttPacketPingresp, nil)
+		case mqttPacketDisconnect:
+			b.mu.Lock()
+			b.state.disconnects++
+			b.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (b *mqttBroker) recordConnect(body []byte) {
+	readString := func(p []byte) (string, []byte) {
+		n := int(binary.BigEndian.Uint16(p))
+		return string(p[2 : 2+n]), p[2+n:]
+	}
+
+	_, rest := readString(body) // protocol name
+	flags := rest[1]
+	keepAlive := binary.BigEndian.Uint16(rest[2:4])
+	rest = rest[4:]
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state.connects++
+	b.state.keepAlive = keepAlive
+	b.state.clientID, rest = readString(rest)
+	if flags&0x80 != 0 {
+		b.state.username, rest = readString(rest)
+	}
+	if flags&0x40 != 0 {
+		b.state.password, _ = readString(rest)
+	}
+}
+
+func (b *mqttBroker) snapshot() mqttBrokerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.state
+	state.published = append([]mqttPublished(nil), b.state.published...)
+	return state
+}
+
+func waitForMQTT(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for broker state")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSendMQTT_QoS1PublishesJSON(t *testing.T) {
+	broker := startMQTTBroker(t)
+	cfg := config.MQTTConfig{
+		Broker:   broker.url(),
+		Topic:    "home/{agent}/{event}",
+		QoS:      1,
+		Retain:   true,
+		ClientID: "test-client",
+		Username: "user",
+		Password: "pass",
+	}
+	msg := Message{Title: "hello", Body: "world", Agent: "claude", Event: EventCompleted}
+
+	if err := sendMQTT(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	waitForMQTT(t, func() bool { return broker.snapshot().disconnects == 1 })
+	got := broker.snapshot()
+
+	if got.clientID != "test-client" || got.username != "user" || got.password != "pass" {
+		t.Errorf("unexpected connect identity %q/%q/%q", got.clientID, got.username, got.password)
+	}
+	if got.keepAlive != 0 {
+		t.Errorf("expected one-shot connection without keepalive, got %d", got.keepAlive)
+	}
+	if len(got.published) != 1 {
+		t.Fatalf("expected 1 publish, got %d", len(got.published))
+	}
+
+	pub := got.published[0]
+	if pub.topic != "home/claude/completed" {
+		t.Errorf("expected topic %q, got %q", "home/claude/completed", pub.topic)
+	}
+	if pub.qos != 1 || !pub.retain {
+		t.Errorf("expected qos=1 retain=true, got qos=%d retain=%v", pub.qos, pub.retain)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(pub.payload, &decoded); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if decoded.Title != "hello" || decoded.Body != "world" || decoded.Agent != "claude" {
+		t.Errorf("unexpected payload %+v", decoded)
+	}
+}
+
+func TestSendMQTT_OneShotConnectsPerMessage(t *testing.T) {
+	broker := startMQTTBroker(t)
+	cfg := config.MQTTConfig{Broker: broker.url(), Topic: "t"}
+
+	for i := 0; i < 2; i++ {
+		if err := sendMQTT(context.Background(), cfg, Message{Title: "t"}); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	waitForMQTT(t, func() bool { return broker.snapshot().disconnects == 2 })
+	if got := broker.snapshot().connects; got != 2 {
+		t.Errorf("expected 2 connects in one-shot mode, got %d", got)
+	}
+}
+
+func TestSendMQTT_PersistentReusesConnection(t *testing.T) {
+	broker := startMQTTBroker(t)
+	KeepConnectionsOpen()
+	cfg := config.MQTTConfig{Broker: broker.url(), Topic: "t", QoS: 1}
+
+	for i := 0; i < 3; i++ {
+		if err := sendMQTT(context.Background(), cfg, Message{Title: "t"}); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	got := broker.snapshot()
+	if got.connects != 1 {
+		t.Errorf("expected a single connection in persistent mode, got %d", got.connects)
+	}
+	if len(got.published) != 3 {
+		t.Errorf("expected 3 publishes, got %d", len(got.published))
+	}
+	if got.keepAlive == 0 {
+		t.Error("expected keepalive on persistent connection")
+	}
+
+	CloseConnections()
+	waitForMQTT(t, func() bool { return broker.snapshot().disconnects == 1 })
+}
+
+func TestSendMQTT_PersistentSharesConnectionAcrossPublishSettings(t *testing.T) {
+	broker := startMQTTBroker(t)
+	KeepConnectionsOpen()
+	base := config.MQTTConfig{Broker: broker.url(), Topic: "t"}
+	other := base
+	other.Topic = "ding/{agent}"
+	other.QoS = 1
+	other.Retain = true
+	other.Template = config.TemplateConfig{Title: "[{{.Agent}}] {{.Title}}"}
+
+	for _, cfg := range []config.MQTTConfig{base, other, base} {
+		if err := sendMQTT(context.Background(), cfg, Message{Title: "t", Agent: "claude"}); err != nil {
+			t.Fatalf("sendMQTT() error = %v", err)
+		}
+	}
+
+	// QoS 0 publishes are not acknowledged, so wait for the broker.
+	waitForMQTT(t, func() bool { return len(broker.snapshot().published) == 3 })
+	got := broker.snapshot()
+	if got.connects != 1 {
+		t.Errorf("expected one shared connection, got %d connects", got.connects)
+	}
+	if len(got.published) != 3 || got.published[1].topic != "ding/claude" || got.published[1].qos != 1 || !got.published[1].retain {
+		t.Errorf("expected per-publish topic, QoS and retain, got %+v", got.published)
+	}
+
+	// New credentials replace the connection instead of opening a second
+	// one with the same client ID.
+	other.Username = "bot"
+	if err := sendMQTT(context.Background(), other, Message{Title: "t"}); err != nil {
+		t.Fatalf("sendMQTT() error = %v", err)
+	}
+	waitForMQTT(t, func() bool { return broker.snapshot().disconnects == 1 })
+	if got := broker.snapshot(); got.connects != 2 || got.username != "bot" {
+		t.Errorf("expected a reconnect as bot, got %d connects as %q", got.connects, got.username)
+	}
+}
+
+func TestSendMQTT_ConnectionRefusedByBroker(t *testing.T) {
+	broker := startMQTTBroker(t)
+	broker.connackCode = 5
+
+	err := sendMQTT(context.Background(), config.MQTTConfig{Broker: broker.url(), Topic: "t"}, Message{Title: "t"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if want := "not authorized"; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %q", want, err.Error())
+	}
+}
+
+func TestMQTTTopic_SanitizesPlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent string
+		event Event
+		want  string
+	}{
+		{name: "plain agent", agent: "claude", event: EventCompleted, want: "ding-ding/claude/completed"},
+		{name: "empty agent", agent: "", event: EventCompleted, want: "ding-ding/unknown/completed"},
+		{name: "wildcards stripped", agent: "a/b+#", event: EventCompleted, want: "ding-ding/a_b__/completed"},
+		{name: "unlabeled event", agent: "claude", want: "ding-ding/claude/unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mqttTopic("ding-ding/{agent}/{event}", Message{Agent: tt.agent, Event: tt.event})
+			if got != tt.want {
+				t.Errorf("mqttTopic() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMQTTRemainingLength_RoundTrip(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151} {
+		encoded := appendMQTTRemainingLength([]byte{mqttPacketPublish}, n)
+		encoded = append(encoded, make([]byte, n)...)
+		packetType, body, err := readMQTTPacket(bufio.NewReader(strings.NewReader(string(encoded))))
+		if err != nil {
+			t.Fatalf("n=%d: %v", n, err)
+		}
+		if packetType != mqttPacketPublish || len(body) != n {
+			t.Errorf("n=%d: got type 0x%02x len %d", n, packetType, len(body))
+		}
+	}
+}
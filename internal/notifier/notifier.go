@@ -1,10 +1,11 @@
 package notifier
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,27 +16,48 @@ import (
 	"github.com/Digni/ding-ding/internal/logging"
 )
 
-var httpClient = &http.Client{Timeout: 15 * time.Second}
-
-var errForcePushNoBackends = errors.New("force push requested but no push backends are enabled (ntfy, discord, webhook)")
+var errForcePushNoBackends = errors.New("force push requested but no push backends are enabled (ntfy, discord, webhook, email, teams, mqtt)")
 
 // Test hooks — exported for cross-package test stubbing (internal/ boundary prevents public leakage).
-var IdleDurationFunc = idle.Duration
+var IdleDurationFunc = idle.DurationContext
 var TerminalFocusedFunc = focus.TerminalFocused
 var ProcessInFocusedTerminalFunc = focus.ProcessInFocusedTerminal
-var TerminalFocusStateFunc = focus.TerminalFocusState
-var ProcessFocusStateFunc = focus.ProcessFocusState
+var TerminalFocusStateFunc = focus.TerminalFocusStateContext
+var ProcessFocusStateFunc = focus.ProcessFocusStateContext
 var SystemNotifyFunc = systemNotify
 var DefaultLoggerFunc = slog.Default
 
 // Message represents a notification to be sent.
 type Message struct {
-	Title       string `json:"title"`
-	Body        string `json:"body"`
-	Agent       string `json:"agent,omitempty"`        // e.g. "claude", "opencode"
-	PID         int    `json:"pid,omitempty"`          // caller's PID for focus detection in server mode
-	RequestID   string `json:"request_id,omitempty"`   // server correlation id for request-scoped tracing
-	OperationID string `json:"operation_id,omitempty"` // lifecycle correlation id shared across components
+	Title       string       `json:"title"`
+	Body        string       `json:"body"`
+	Agent       string       `json:"agent,omitempty"`        // e.g. "claude", "opencode"
+	PID         int          `json:"pid,omitempty"`          // caller's PID for focus detection in server mode
+	RequestID   string       `json:"request_id,omitempty"`   // server correlation id for request-scoped tracing
+	OperationID string       `json:"operation_id,omitempty"` // lifecycle correlation id shared across components
+	Session     string       `json:"session,omitempty"`      // agent session key; groups replace-in-place notifications
+	Event       Event        `json:"event,omitempty"`        // completed, needs_input, error, progress, started
+	ExitCode    *int         `json:"exit_code,omitempty"`    // agent/task exit status when known
+	DurationMs  int64        `json:"duration_ms,omitempty"`  // how long the agent task ran, when known
+	Priority    Priority     `json:"priority,omitempty"`     // low, normal, high, urgent; mapped per backend
+	Cwd         string       `json:"cwd,omitempty"`          // agent working directory
+	Repo        string       `json:"repo,omitempty"`         // git repository name containing Cwd
+	Branch      string       `json:"branch,omitempty"`       // git branch (or short commit when detached)
+	Hostname    string       `json:"hostname,omitempty"`     // machine the agent runs on
+	URL         string       `json:"url,omitempty"`          // opened when the notification is clicked
+	Actions     []Action     `json:"actions,omitempty"`      // labeled link buttons
+	Replies     []string     `json:"replies,omitempty"`      // reply options answered through ntfy.reply_topic
+	Ntfy        *NtfyOptions `json:"ntfy,omitempty"`         // per-message ntfy features
+
+	// group is the replace-in-place key, set during dispatch when
+	// notification.replace_in_place is enabled.
+	group string
+	// tier is the routing tier that triggered delivery, for templates.
+	tier string
+}
+
+func (m Message) duration() time.Duration {
+	return time.Duration(m.DurationMs) * time.Millisecond
 }
 
 // NotifyOptions controls forced notification behavior.
@@ -45,32 +67,50 @@ type NotifyOptions struct {
 	// ForceLocal sends the local/system notification even when focus suppression
 	// would normally silence it.
 	ForceLocal bool
+	// DryRun runs detection and every routing rule but sends nothing; the
+	// report carries the plan and the reasons behind it.
+	DryRun bool
 }
 
 // resolveIdleState determines whether the user is idle using the configured
 // threshold. If idle detection fails, FallbackPolicy governs the result:
 // "idle" treats the user as idle; anything else (including "active") treats
-// the user as active. Returns (userIdle, idleTime).
-func resolveIdleState(cfg config.Config, logger *slog.Logger) (userIdle bool, idleTime time.Duration) {
+// the user as active. Returns (userIdle, idleTime) and why, for explain.
+func resolveIdleState(ctx context.Context, cfg config.Config, logger *slog.Logger) (userIdle bool, idleTime time.Duration, reason string) {
 	threshold := time.Duration(cfg.Idle.ThresholdSeconds) * time.Second
 	if threshold == 0 {
 		logger.Warn("notifier.idle.threshold_zero")
-		return false, 0
+		return false, 0, "idle.threshold_seconds is 0, so the user always counts as active"
 	}
 
-	dur, err := IdleDurationFunc()
+	dur, err := IdleDurationFunc(ctx)
 	if err != nil {
 		switch cfg.Idle.FallbackPolicy {
 		case "idle":
 			logger.Warn("notifier.idle.detect_failed", "fallback_policy", "idle", "error", err)
-			return true, 0
+			return true, 0, fmt.Sprintf("idle detection failed (%v); fallback_policy idle treats the user as idle", err)
 		default:
 			logger.Warn("notifier.idle.detect_failed", "fallback_policy", "active", "error", err)
-			return false, 0
+			return false, 0, fmt.Sprintf("idle detection failed (%v); fallback_policy active treats the user as active", err)
 		}
 	}
 
-	return dur >= threshold, dur
+	if dur >= threshold {
+		return true, dur, fmt.Sprintf("idle for %s, at or past the %s threshold: user is idle", dur.Round(time.Second), threshold)
+	}
+	return false, dur, fmt.Sprintf("idle for %s, under the %s threshold: user is active", dur.Round(time.Second), threshold)
+}
+
+// focusReason explains a focus detection result for explain output.
+func focusReason(state focus.State) string {
+	switch {
+	case !state.Known:
+		return "focus could not be determined, so the terminal counts as focused"
+	case state.Focused:
+		return "the agent's terminal is focused"
+	default:
+		return "the agent's terminal is not focused"
+	}
 }
 
 // Notify handles CLI invocations with 3-tier logic:
@@ -79,11 +119,29 @@ func resolveIdleState(cfg config.Config, logger *slog.Logger) (userIdle bool, id
 //	Active + terminal unfocused → system notification only
 //	Idle                        → system notification + push
 func Notify(cfg config.Config, msg Message) error {
-	return NotifyWithOptions(cfg, msg, NotifyOptions{})
+	return NotifyContext(context.Background(), cfg, msg)
+}
+
+// NotifyContext is Notify with cancellation; see NotifyWithOptionsContext.
+func NotifyContext(ctx context.Context, cfg config.Config, msg Message) error {
+	_, err := NotifyWithOptionsContext(ctx, cfg, msg, NotifyOptions{})
+	return err
+}
+
+// NotifyWithOptions handles CLI invocations with optional force behavior and
+// reports how the notification was routed and delivered. The report is
+// filled in even when an error is returned.
+func NotifyWithOptions(cfg config.Config, msg Message, opts NotifyOptions) (DeliveryReport, error) {
+	return NotifyWithOptionsContext(context.Background(), cfg, msg, opts)
 }
 
-// NotifyWithOptions handles CLI invocations with optional force behavior.
-func NotifyWithOptions(cfg config.Config, msg Message, opts NotifyOptions) error {
+// NotifyWithOptionsContext is NotifyWithOptions with cancellation: ending
+// ctx stops idle and focus detection and in-flight pushes. The whole
+// delivery is also bounded by notification.delivery_timeout_seconds.
+func NotifyWithOptionsContext(ctx context.Context, cfg config.Config, msg Message, opts NotifyOptions) (DeliveryReport, error) {
+	ctx, cancel := withDeliveryDeadline(ctx, cfg)
+	defer cancel()
+
 	start := time.Now()
 	operationID := logging.NewOperationID()
 	logger := DefaultLoggerFunc().With("operation_id", operationID, "entrypoint", "cli", "agent", msg.Agent)
@@ -94,30 +152,44 @@ func NotifyWithOptions(cfg config.Config, msg Message, opts NotifyOptions) error
 	}
 	logger.Info("notifier.notify.started", messageMetadata(msg)...)
 
-	userIdle, idleTime := resolveIdleState(cfg, logger)
-	focused := false
+	var route routing
+	var idleReason string
+	route.userIdle, route.idleTime, idleReason = resolveIdleState(ctx, cfg, logger)
+	route.explain = append(route.explain, idleReason)
 	if cfg.Notification.SuppressWhenFocused {
-		focusState := TerminalFocusStateFunc()
-		focused = focusState.Focused || !focusState.Known
-	}
-	logger.Info("notifier.notify.routing", "user_idle", userIdle, "idle_ms", idleTime.Milliseconds(), "focused", focused, "force_push", opts.ForcePush, "force_local", opts.ForceLocal, "suppress_when_focused", cfg.Notification.SuppressWhenFocused)
-
-	err := dispatchNotification(cfg, msg, userIdle, idleTime, focused, opts, logger)
-	status := "ok"
-	if err != nil {
-		status = "error"
-		logger.Error("notifier.notify.error", "status", status, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+		focusState := TerminalFocusStateFunc(ctx)
+		route.focused = focusState.Focused || !focusState.Known
+		route.explain = append(route.explain, focusReason(focusState))
+	} else {
+		route.explain = append(route.explain, "notification.suppress_when_focused is off, so focus is not checked")
 	}
-	logger.Info("notifier.notify.completed", "status", status, "duration_ms", time.Since(start).Milliseconds())
+	logger.Info("notifier.notify.routing", "user_idle", route.userIdle, "idle_ms", route.idleTime.Milliseconds(), "focused", route.focused, "force_push", opts.ForcePush, "force_local", opts.ForceLocal, "dry_run", opts.DryRun, "suppress_when_focused", cfg.Notification.SuppressWhenFocused)
 
-	return err
+	return finishNotify(ctx, cfg, msg, route, opts, logger, start)
 }
 
 // NotifyRemote handles HTTP server invocations. If the caller provides a PID,
 // focus detection uses that PID's process tree to check if the agent's
 // terminal is focused. Without a PID, focus detection is skipped and a
-// system notification is always sent.
-func NotifyRemote(cfg config.Config, msg Message) error {
+// system notification is always sent. Like NotifyWithOptions, it returns a
+// delivery report alongside any error.
+func NotifyRemote(cfg config.Config, msg Message) (DeliveryReport, error) {
+	return NotifyRemoteContext(context.Background(), cfg, msg)
+}
+
+// NotifyRemoteContext is NotifyRemote with cancellation, e.g. by the HTTP
+// request's context or server shutdown. The delivery is also bounded by
+// notification.delivery_timeout_seconds.
+func NotifyRemoteContext(ctx context.Context, cfg config.Config, msg Message) (DeliveryReport, error) {
+	return NotifyRemoteWithOptionsContext(ctx, cfg, msg, NotifyOptions{})
+}
+
+// NotifyRemoteWithOptionsContext is NotifyRemoteContext with NotifyOptions,
+// e.g. for a dry run requested over HTTP.
+func NotifyRemoteWithOptionsContext(ctx context.Context, cfg config.Config, msg Message, opts NotifyOptions) (DeliveryReport, error) {
+	ctx, cancel := withDeliveryDeadline(ctx, cfg)
+	defer cancel()
+
 	start := time.Now()
 	requestID := logging.EnsureRequestID(msg.RequestID)
 	operationID := strings.TrimSpace(msg.OperationID)
@@ -129,93 +201,191 @@ func NotifyRemote(cfg config.Config, msg Message) error {
 	msg.RequestID = requestID
 	msg.OperationID = operationID
 
+	// The server runs elsewhere in the process tree, so resolve the caller's
+	// working directory from its PID when it did not send one.
+	cwd := msg.Cwd
+	if cwd == "" && msg.PID > 0 {
+		if resolved, err := processCwdFunc(msg.PID); err == nil {
+			cwd = resolved
+		} else {
+			logger.Debug("notifier.context.cwd_unavailable", "error", err)
+		}
+	}
+	msg = WithContext(msg, cwd)
+
 	if msg.Title == "" {
 		msg.Title = "ding ding!"
 	}
 	logger.Info("notifier.notify.started", messageMetadata(msg)...)
 
-	userIdle, idleTime := resolveIdleState(cfg, logger)
+	var route routing
+	var idleReason string
+	route.userIdle, route.idleTime, idleReason = resolveIdleState(ctx, cfg, logger)
+	route.explain = append(route.explain, idleReason)
 
 	// If the caller sent a PID, we can check focus for their terminal
-	focused := false
-	if msg.PID > 0 && cfg.Notification.SuppressWhenFocused {
-		focusState := ProcessFocusStateFunc(msg.PID)
-		focused = focusState.Focused || !focusState.Known
+	switch {
+	case !cfg.Notification.SuppressWhenFocused:
+		route.explain = append(route.explain, "notification.suppress_when_focused is off, so focus is not checked")
+	case msg.PID <= 0:
+		route.explain = append(route.explain, "the request has no pid, so focus is not checked")
+	default:
+		focusState := ProcessFocusStateFunc(ctx, msg.PID)
+		route.focused = focusState.Focused || !focusState.Known
+		route.explain = append(route.explain, focusReason(focusState))
 	}
-	logger.Info("notifier.notify.routing", "user_idle", userIdle, "idle_ms", idleTime.Milliseconds(), "focused", focused, "force_push", false, "force_local", false, "suppress_when_focused", cfg.Notification.SuppressWhenFocused)
+	logger.Info("notifier.notify.routing", "user_idle", route.userIdle, "idle_ms", route.idleTime.Milliseconds(), "focused", route.focused, "force_push", opts.ForcePush, "force_local", opts.ForceLocal, "dry_run", opts.DryRun, "suppress_when_focused", cfg.Notification.SuppressWhenFocused)
+
+	return finishNotify(ctx, cfg, msg, route, opts, logger, start)
+}
+
+// routing is the idle and focus detection outcome a notification is
+// dispatched on, with the reasons behind it.
+type routing struct {
+	userIdle bool
+	idleTime time.Duration
+	focused  bool
+	explain  []string
+}
 
-	err := dispatchNotification(cfg, msg, userIdle, idleTime, focused, NotifyOptions{}, logger)
+func finishNotify(ctx context.Context, cfg config.Config, msg Message, route routing, opts NotifyOptions, logger *slog.Logger, start time.Time) (DeliveryReport, error) {
+	report, err := dispatchNotification(ctx, cfg, msg, route, opts, logger)
+	report.finish(start, err)
 	status := "ok"
+	if opts.DryRun {
+		status = "dry_run"
+	}
 	if err != nil {
 		status = "error"
-		logger.Error("notifier.notify.error", "status", status, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+		logger.Error("notifier.notify.error", "status", status, "duration_ms", time.Since(start).Milliseconds(), "partial_failure", report.PartialFailure, "remote_failed", report.RemoteFailed, "error", err)
 	}
 	logger.Info("notifier.notify.completed", "status", status, "duration_ms", time.Since(start).Milliseconds())
 
-	return err
+	return report, err
 }
 
-func dispatchNotification(cfg config.Config, msg Message, userIdle bool, idleTime time.Duration, focused bool, opts NotifyOptions, logger *slog.Logger) error {
+func dispatchNotification(ctx context.Context, cfg config.Config, msg Message, route routing, opts NotifyOptions, logger *slog.Logger) (DeliveryReport, error) {
+	msg.Event = effectiveEvent(msg)
+	msg = withGroup(cfg, msg)
+	userIdle, idleTime, focused := route.userIdle, route.idleTime, route.focused
 	threshold := time.Duration(cfg.Idle.ThresholdSeconds) * time.Second
 	var localErr error
 	forcePushNoBackends := opts.ForcePush && !hasEnabledPushBackends(cfg)
+	report := DeliveryReport{
+		OperationID: msg.OperationID,
+		UserIdle:    userIdle,
+		IdleMs:      idleTime.Milliseconds(),
+		Focused:     focused,
+		Explain:     route.explain,
+	}
+	if opts.DryRun {
+		report.Plan = &DeliveryPlan{Backends: []string{}}
+	}
+	push := func() error {
+		if opts.DryRun {
+			report.Plan.Backends = pushTargetLabels(cfg)
+			report.Explain = append(report.Explain, explainBackends(cfg)...)
+			return nil
+		}
+		var err error
+		report.Backends, err = pushAll(ctx, cfg, msg)
+		return err
+	}
 
 	// Tier 1: user is active and looking at the agent terminal — do nothing
 	if !userIdle && focused && !opts.ForceLocal {
+		report.Tier = tierFocused
 		if !opts.ForcePush {
 			logger.Info("notifier.notify.suppressed", "reason", "focused_active", "idle_ms", idleTime.Milliseconds())
-			return nil
+			report.Suppressed = true
+			report.Explain = append(report.Explain, "active user looking at the agent's terminal: everything is suppressed")
+			return report, nil
 		}
 
 		if forcePushNoBackends {
-			return errForcePushNoBackends
+			report.Explain = append(report.Explain, "--push was requested but no push backend is enabled")
+			return report, errForcePushNoBackends
 		}
 
 		logger.Info("notifier.notify.force_push", "reason", "focused_active", "idle_ms", idleTime.Milliseconds())
-		return pushAll(cfg, msg)
+		report.Explain = append(report.Explain, "the terminal is focused, so there is no local notification, but --push forces push backends")
+		msg.tier = tierFocused
+		return report, push()
+	}
+
+	msg.tier = tierActive
+	if userIdle {
+		msg.tier = tierIdle
 	}
+	report.Tier = msg.tier
 
 	shouldSendLocal := !opts.ForcePush || opts.ForceLocal
 
 	// Tier 2 & 3: send system notification (user isn't looking at the terminal)
-	if shouldSendLocal {
-		if err := SystemNotifyFunc(msg.Title, msg.Body); err != nil {
+	switch {
+	case !shouldSendLocal:
+		report.Explain = append(report.Explain, "--push without --test-local skips the local notification")
+	case opts.DryRun:
+		report.Plan.Local = true
+		report.Explain = append(report.Explain, localReason(userIdle, focused))
+	default:
+		report.Explain = append(report.Explain, localReason(userIdle, focused))
+		if err := sendSystemNotification(ctx, cfg.Notification, msg); err != nil {
 			logger.Warn("notifier.notify.system_failed", "error", err)
+			report.LocalError = err.Error()
 			if opts.ForceLocal {
 				localErr = fmt.Errorf("system notification: %w", err)
 			}
+		} else {
+			report.LocalSent = true
 		}
 	}
 
 	// Tier 2: user is active but on a different window — no push needed unless forced
 	if !userIdle && !opts.ForcePush {
 		logger.Info("notifier.notify.push_skipped", "reason", "user_active", "idle_ms", idleTime.Milliseconds(), "threshold_ms", threshold.Milliseconds())
-		return localErr
+		report.Explain = append(report.Explain, "the user is active, so push backends are skipped (use --push to force them)")
+		return report, localErr
 	}
 
 	if forcePushNoBackends {
+		report.Explain = append(report.Explain, "--push was requested but no push backend is enabled")
 		if localErr != nil {
-			return errors.Join(localErr, errForcePushNoBackends)
+			return report, errors.Join(localErr, errForcePushNoBackends)
 		}
-		return errForcePushNoBackends
+		return report, errForcePushNoBackends
 	}
 
 	if opts.ForcePush && !userIdle {
 		logger.Info("notifier.notify.force_push", "reason", "user_active", "idle_ms", idleTime.Milliseconds(), "threshold_ms", threshold.Milliseconds())
+		report.Explain = append(report.Explain, "the user is active, but --push forces push backends")
 	} else {
 		// Tier 3: user is idle — send push notifications
 		logger.Info("notifier.notify.push_idle", "idle_ms", idleTime.Milliseconds(), "threshold_ms", threshold.Milliseconds())
+		report.Explain = append(report.Explain, "the user is idle, so push backends fire")
 	}
 
-	pushErr := pushAll(cfg, msg)
+	pushErr := push()
 	if localErr != nil {
 		if pushErr != nil {
-			return errors.Join(localErr, pushErr)
+			return report, errors.Join(localErr, pushErr)
 		}
-		return localErr
+		return report, localErr
 	}
 
-	return pushErr
+	return report, pushErr
+}
+
+// localReason explains why the local notification fires.
+func localReason(userIdle, focused bool) string {
+	switch {
+	case focused && !userIdle:
+		return "--test-local forces the local notification despite the focused terminal"
+	case userIdle:
+		return "the user is idle, so the local notification fires"
+	default:
+		return "the user is active in another window, so the local notification fires"
+	}
 }
 
 func messageMetadata(msg Message) []any {
@@ -225,73 +395,204 @@ func messageMetadata(msg Message) []any {
 		"title_bytes", len(msg.Title),
 		"body_bytes", len(msg.Body),
 		"message_pid", msg.PID,
+		"session_present", strings.TrimSpace(msg.Session) != "",
+		"event", string(msg.Event),
+		"exit_code_present", msg.ExitCode != nil,
+		"duration_ms", msg.DurationMs,
+		"priority", string(msg.Priority),
+		"cwd_present", msg.Cwd != "",
+		"repo_present", msg.Repo != "",
+		"url_present", msg.URL != "",
+		"actions", len(msg.Actions),
+		"replies", len(msg.Replies),
 		"request_id_present", strings.TrimSpace(msg.RequestID) != "",
 		"operation_id_present", strings.TrimSpace(msg.OperationID) != "",
 	}
 }
 
 func hasEnabledPushBackends(cfg config.Config) bool {
-	return cfg.Ntfy.Enabled || cfg.Discord.Enabled || cfg.Webhook.Enabled
+	return cfg.Ntfy.Enabled || cfg.Discord.Enabled || cfg.Webhook.Enabled || cfg.Email.Enabled || cfg.Teams.Enabled || cfg.MQTT.Enabled
 }
 
 // Push sends to all configured remote backends regardless of idle/focus state.
 func Push(cfg config.Config, msg Message) error {
+	return PushContext(context.Background(), cfg, msg)
+}
+
+// PushContext is Push with cancellation, bounded by
+// notification.delivery_timeout_seconds.
+func PushContext(ctx context.Context, cfg config.Config, msg Message) error {
+	ctx, cancel := withDeliveryDeadline(ctx, cfg)
+	defer cancel()
+
 	if msg.Title == "" {
 		msg.Title = "ding ding!"
 	}
-	return pushAll(cfg, msg)
+	msg.Event = effectiveEvent(msg)
+	msg.tier = tierDirect
+	_, err := pushAll(ctx, cfg, withGroup(cfg, msg))
+	return err
 }
 
-func pushAll(cfg config.Config, msg Message) error {
-	type pushTarget struct {
-		label string
-		send  func() error
+// withDeliveryDeadline bounds a delivery by
+// notification.delivery_timeout_seconds; zero leaves ctx unbounded.
+func withDeliveryDeadline(ctx context.Context, cfg config.Config) (context.Context, context.CancelFunc) {
+	if cfg.Notification.DeliveryTimeoutSeconds <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, time.Duration(cfg.Notification.DeliveryTimeoutSeconds)*time.Second)
+}
 
-	var targets []pushTarget
+func withGroup(cfg config.Config, msg Message) Message {
+	if cfg.Notification.ReplaceInPlace {
+		msg.group = groupKey(msg)
+	}
+	return msg
+}
+
+type pushTarget struct {
+	label string
+	send  func(context.Context) error
+}
+
+// pushBackend is an enabled push backend that is not yet bound to a
+// message.
+type pushBackend struct {
+	label string
+	send  func(context.Context, Message) error
+}
+
+// pushBackends lists the enabled push backends in dispatch order.
+func pushBackends(cfg config.Config) []pushBackend {
+	var backends []pushBackend
 	if cfg.Ntfy.Enabled {
-		targets = append(targets, pushTarget{
+		backends = append(backends, pushBackend{
 			label: "ntfy",
-			send:  func() error { return sendNtfy(cfg.Ntfy, msg) },
+			send:  func(ctx context.Context, msg Message) error { return sendNtfy(ctx, cfg.Ntfy, msg) },
 		})
 	}
 	if cfg.Discord.Enabled {
-		targets = append(targets, pushTarget{
+		backends = append(backends, pushBackend{
 			label: "discord",
-			send:  func() error { return sendDiscord(cfg.Discord, msg) },
+			send:  func(ctx context.Context, msg Message) error { return sendDiscord(ctx, cfg.Discord, msg) },
 		})
 	}
 	if cfg.Webhook.Enabled {
-		targets = append(targets, pushTarget{
+		backends = append(backends, pushBackend{
 			label: "webhook",
-			send:  func() error { return sendWebhook(cfg.Webhook, msg) },
+			send:  func(ctx context.Context, msg Message) error { return sendWebhook(ctx, cfg.Webhook, msg) },
+		})
+	}
+	if cfg.Email.Enabled {
+		backends = append(backends, pushBackend{
+			label: "email",
+			send:  func(ctx context.Context, msg Message) error { return sendEmail(ctx, cfg.Email, msg) },
+		})
+	}
+	if cfg.Teams.Enabled {
+		backends = append(backends, pushBackend{
+			label: "teams",
+			send:  func(ctx context.Context, msg Message) error { return sendTeams(ctx, cfg.Teams, msg) },
 		})
 	}
+	if cfg.MQTT.Enabled {
+		backends = append(backends, pushBackend{
+			label: "mqtt",
+			send:  func(ctx context.Context, msg Message) error { return sendMQTT(ctx, cfg.MQTT, msg) },
+		})
+	}
+	return backends
+}
+
+// target binds the backend to msg.
+func (b pushBackend) target(msg Message) pushTarget {
+	return pushTarget{
+		label: b.label,
+		send:  func(ctx context.Context) error { return b.send(ctx, msg) },
+	}
+}
 
-	errCh := make(chan error, len(targets))
+// pushTargets lists the enabled push backends in dispatch order, each
+// bound to msg.
+func pushTargets(cfg config.Config, msg Message) []pushTarget {
+	var targets []pushTarget
+	for _, backend := range pushBackends(cfg) {
+		targets = append(targets, backend.target(msg))
+	}
+	return targets
+}
+
+// pushBackendNames are all push backends, in dispatch order.
+var pushBackendNames = []string{"ntfy", "discord", "webhook", "email", "teams", "mqtt"}
+
+func pushTargetLabels(cfg config.Config) []string {
+	labels := []string{}
+	for _, backend := range pushBackends(cfg) {
+		labels = append(labels, backend.label)
+	}
+	return labels
+}
+
+// explainBackends says which push backends a push would reach.
+func explainBackends(cfg config.Config) []string {
+	enabled := pushTargetLabels(cfg)
+	var disabled []string
+	for _, name := range pushBackendNames {
+		if !slices.Contains(enabled, name) {
+			disabled = append(disabled, name)
+		}
+	}
+
+	var reasons []string
+	if len(enabled) == 0 {
+		reasons = append(reasons, "no push backend is enabled")
+	} else {
+		reasons = append(reasons, "enabled push backends: "+strings.Join(enabled, ", "))
+	}
+	if len(disabled) > 0 {
+		reasons = append(reasons, "disabled in config: "+strings.Join(disabled, ", "))
+	}
+	return reasons
+}
+
+// pushAll sends to every enabled backend concurrently and reports each
+// result in a stable order; the error joins the labelled failures.
+func pushAll(ctx context.Context, cfg config.Config, msg Message) ([]BackendResult, error) {
+	return runTargets(ctx, pushTargets(cfg.WithBackendHTTP(), msg))
+}
+
+// runTargets runs each target concurrently and collects the results in
+// target order.
+func runTargets(ctx context.Context, targets []pushTarget) ([]BackendResult, error) {
+	results := make([]BackendResult, len(targets))
+	errs := make([]error, len(targets))
 	var wg sync.WaitGroup
-	for _, target := range targets {
-		target := target
+	for i, target := range targets {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := target.send(); err != nil {
-				errCh <- fmt.Errorf("%s: %w", target.label, err)
-			}
+			results[i], errs[i] = runTarget(ctx, target)
 		}()
 	}
-
 	wg.Wait()
-	close(errCh)
 
-	var errs []error
-	for err := range errCh {
-		errs = append(errs, err)
-	}
+	return results, errors.Join(errs...)
+}
 
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+// runTarget sends to one target and times it; the error is labelled.
+func runTarget(ctx context.Context, target pushTarget) (BackendResult, error) {
+	sendCtx, attempts := withAttemptCounter(ctx)
+	start := time.Now()
+	err := target.send(sendCtx)
+	result := BackendResult{
+		Backend:   target.label,
+		OK:        err == nil,
+		Attempts:  int(attempts.Load()),
+		LatencyMs: time.Since(start).Milliseconds(),
 	}
-
-	return nil
+	if err != nil {
+		result.Error = err.Error()
+		return result, fmt.Errorf("%s: %w", target.label, err)
+	}
+	return result, nil
 }
@@ -2,6 +2,7 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +24,7 @@ type stubState struct {
 	systemNotifyCalls  int
 	systemNotifyTitle  string
 	systemNotifyBody   string
+	soundPlays         int
 }
 
 // setupStubs replaces the package-level function vars with controllable stubs
@@ -41,28 +43,40 @@ func setupStubsWithFocusState(t *testing.T, idleDur time.Duration, idleErr error
 	origFocusState := TerminalFocusStateFunc
 	origProcessState := ProcessFocusStateFunc
 	origSystem := SystemNotifyFunc
-	origHTTP := httpClient
+	origPlaySound := playSoundFunc
+	origHTTP := httpClientFor
+	origGroups := groups
+	origProcessCwd := processCwdFunc
 
 	t.Cleanup(func() {
+		groups = origGroups
+		processCwdFunc = origProcessCwd
 		IdleDurationFunc = origIdle
 		TerminalFocusedFunc = origFocused
 		ProcessInFocusedTerminalFunc = origProcess
 		TerminalFocusStateFunc = origFocusState
 		ProcessFocusStateFunc = origProcessState
 		SystemNotifyFunc = origSystem
-		httpClient = origHTTP
+		playSoundFunc = origPlaySound
+		httpClientFor = origHTTP
 	})
 
-	IdleDurationFunc = func() (time.Duration, error) { return idleDur, idleErr }
+	groups = newMemoryGroupStore()
+	processCwdFunc = func(pid int) (string, error) { return "", errors.New("no procfs in tests") }
+	IdleDurationFunc = func(context.Context) (time.Duration, error) { return idleDur, idleErr }
 	TerminalFocusedFunc = func() bool { return focused }
 	ProcessInFocusedTerminalFunc = func(pid int) bool { return focused }
-	TerminalFocusStateFunc = func() focus.State { return focus.State{Focused: focused, Known: known} }
-	ProcessFocusStateFunc = func(pid int) focus.State { return focus.State{Focused: focused, Known: known} }
-	SystemNotifyFunc = func(title, body string) error {
+	TerminalFocusStateFunc = func(context.Context) focus.State { return focus.State{Focused: focused, Known: known} }
+	ProcessFocusStateFunc = func(_ context.Context, pid int) focus.State { return focus.State{Focused: focused, Known: known} }
+	SystemNotifyFunc = func(_ context.Context, n SystemNotification) error {
 		state.systemNotifyCalled = true
 		state.systemNotifyCalls++
-		state.systemNotifyTitle = title
-		state.systemNotifyBody = body
+		state.systemNotifyTitle = n.Title
+		state.systemNotifyBody = n.Body
+		return nil
+	}
+	playSoundFunc = func(context.Context) error {
+		state.soundPlays++
 		return nil
 	}
 
@@ -124,7 +138,7 @@ func TestResolveIdleState_ZeroThreshold(t *testing.T) {
 	cfg := testConfig()
 	cfg.Idle.ThresholdSeconds = 0
 
-	idle, dur := resolveIdleState(cfg, slog.Default())
+	idle, dur, _ := resolveIdleState(context.Background(), cfg, slog.Default())
 	if idle {
 		t.Error("expected userIdle=false for zero threshold")
 	}
@@ -137,7 +151,7 @@ func TestResolveIdleState_BelowThreshold(t *testing.T) {
 	setupStubs(t, 100*time.Second, nil, false)
 	cfg := testConfig() // threshold=300s
 
-	idle, dur := resolveIdleState(cfg, slog.Default())
+	idle, dur, _ := resolveIdleState(context.Background(), cfg, slog.Default())
 	if idle {
 		t.Error("expected userIdle=false when below threshold")
 	}
@@ -150,7 +164,7 @@ func TestResolveIdleState_AtThreshold(t *testing.T) {
 	setupStubs(t, 300*time.Second, nil, false)
 	cfg := testConfig() // threshold=300s
 
-	idle, dur := resolveIdleState(cfg, slog.Default())
+	idle, dur, _ := resolveIdleState(context.Background(), cfg, slog.Default())
 	if !idle {
 		t.Error("expected userIdle=true when at threshold (inclusive)")
 	}
@@ -163,7 +177,7 @@ func TestResolveIdleState_AboveThreshold(t *testing.T) {
 	setupStubs(t, 600*time.Second, nil, false)
 	cfg := testConfig() // threshold=300s
 
-	idle, dur := resolveIdleState(cfg, slog.Default())
+	idle, dur, _ := resolveIdleState(context.Background(), cfg, slog.Default())
 	if !idle {
 		t.Error("expected userIdle=true when above threshold")
 	}
@@ -177,7 +191,7 @@ func TestResolveIdleState_ErrorFallbackActive(t *testing.T) {
 	cfg := testConfig()
 	cfg.Idle.FallbackPolicy = "active"
 
-	idle, dur := resolveIdleState(cfg, slog.Default())
+	idle, dur, _ := resolveIdleState(context.Background(), cfg, slog.Default())
 	if idle {
 		t.Error("expected userIdle=false for fallback=active")
 	}
@@ -191,7 +205,7 @@ func TestResolveIdleState_ErrorFallbackIdle(t *testing.T) {
 	cfg := testConfig()
 	cfg.Idle.FallbackPolicy = "idle"
 
-	idle, dur := resolveIdleState(cfg, slog.Default())
+	idle, dur, _ := resolveIdleState(context.Background(), cfg, slog.Default())
 	if !idle {
 		t.Error("expected userIdle=true for fallback=idle")
 	}
@@ -336,9 +350,9 @@ func TestNotifyWithOptions_ForcePush_DoesNotDoubleSendWhenIdle(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -364,9 +378,9 @@ func TestNotifyWithOptions_ForcePush_ActiveFocusedSkipsLocal(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -395,9 +409,9 @@ func TestNotifyWithOptions_ForcePush_ActiveUnfocusedSendsPushOnly(t *testing.T)
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -416,7 +430,7 @@ func TestNotifyWithOptions_ForcePush_NoBackendsEnabledReturnsError(t *testing.T)
 	state := setupStubs(t, 10*time.Second, nil, false)
 	cfg := testConfig()
 
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true})
 	if err == nil {
 		t.Fatal("expected error when ForcePush is set without enabled push backends")
 	}
@@ -431,12 +445,12 @@ func TestNotifyWithOptions_ForcePush_NoBackendsEnabledReturnsError(t *testing.T)
 func TestNotifyWithOptions_ForcePushAndForceLocal_NoBackendsEnabledReturnsJoinedError(t *testing.T) {
 	setupStubs(t, 10*time.Second, nil, false)
 
-	SystemNotifyFunc = func(title, body string) error {
+	SystemNotifyFunc = func(_ context.Context, n SystemNotification) error {
 		return errors.New("system notify failed")
 	}
 
 	cfg := testConfig()
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true, ForceLocal: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true, ForceLocal: true})
 	if err == nil {
 		t.Fatal("expected error when ForcePush is set without enabled push backends")
 	}
@@ -452,7 +466,7 @@ func TestNotifyWithOptions_ForceLocal_ActiveFocusedSendsSystemOnly(t *testing.T)
 	state := setupStubs(t, 10*time.Second, nil, true)
 	cfg := testConfig()
 
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForceLocal: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForceLocal: true})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -467,12 +481,12 @@ func TestNotifyWithOptions_ForceLocal_ActiveFocusedSendsSystemOnly(t *testing.T)
 func TestNotifyWithOptions_ForceLocal_SystemNotifyErrorPropagated(t *testing.T) {
 	setupStubs(t, 10*time.Second, nil, true)
 
-	SystemNotifyFunc = func(title, body string) error {
+	SystemNotifyFunc = func(_ context.Context, n SystemNotification) error {
 		return errors.New("system notify failed")
 	}
 
 	cfg := testConfig()
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForceLocal: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForceLocal: true})
 	if err == nil {
 		t.Fatal("expected error when ForceLocal is set and system notify fails")
 	}
@@ -495,9 +509,9 @@ func TestNotifyWithOptions_ForcePushAndForceLocal_ActiveFocusedSendsBoth(t *test
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true, ForceLocal: true})
+	_, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{ForcePush: true, ForceLocal: true})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -528,7 +542,7 @@ func TestNotify_DefaultTitle(t *testing.T) {
 func TestNotify_SystemNotifyErrorDoesNotBlock(t *testing.T) {
 	setupStubs(t, 600*time.Second, nil, false)
 
-	SystemNotifyFunc = func(title, body string) error {
+	SystemNotifyFunc = func(_ context.Context, n SystemNotification) error {
 		return errors.New("system notify failed")
 	}
 
@@ -543,7 +557,7 @@ func TestNotify_SystemNotifyErrorDoesNotBlock(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
 	err := Notify(cfg, Message{Title: "test", Body: "body"})
 	if err != nil {
@@ -567,7 +581,7 @@ func TestNotify_PushError_Propagated(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
 	err := Notify(cfg, Message{Title: "test", Body: "body"})
 	if err == nil {
@@ -596,7 +610,7 @@ func TestNotifyRemote_Tier1_ActiveFocusedWithPID(t *testing.T) {
 	state := setupStubs(t, 10*time.Second, nil, true)
 	cfg := testConfig()
 
-	err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
+	_, err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -610,7 +624,7 @@ func TestNotifyRemote_Tier2_NoPID(t *testing.T) {
 	state := setupStubs(t, 10*time.Second, nil, true)
 	cfg := testConfig()
 
-	err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 0})
+	_, err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 0})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -623,7 +637,7 @@ func TestNotifyRemote_Tier3_Idle(t *testing.T) {
 	state := setupStubs(t, 600*time.Second, nil, false)
 	cfg := testConfig()
 
-	err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
+	_, err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -638,7 +652,7 @@ func TestNotifyRemote_SuppressFocusDisabled(t *testing.T) {
 	cfg := testConfig()
 	cfg.Notification.SuppressWhenFocused = false
 
-	err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
+	_, err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -651,7 +665,7 @@ func TestNotifyRemote_ActiveUncertainFocusWithPID_TreatedAsFocused(t *testing.T)
 	state := setupStubsWithFocusState(t, 10*time.Second, nil, false, false)
 	cfg := testConfig()
 
-	err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
+	_, err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: 1234})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -664,7 +678,7 @@ func TestNotifyRemote_DefaultTitle(t *testing.T) {
 	state := setupStubs(t, 10*time.Second, nil, false)
 	cfg := testConfig()
 
-	err := NotifyRemote(cfg, Message{Title: "", Body: "body", PID: 1234})
+	_, err := NotifyRemote(cfg, Message{Title: "", Body: "body", PID: 1234})
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -688,14 +702,14 @@ func TestNotifyRemote_NoPID_DoesNotCallProcessFocusCheck(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			processFocusCalled := false
-			ProcessFocusStateFunc = func(pid int) focus.State {
+			ProcessFocusStateFunc = func(_ context.Context, pid int) focus.State {
 				processFocusCalled = true
 				return focus.State{Focused: true, Known: true}
 			}
 
 			state.systemNotifyCalled = false
 
-			err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: tt.pid})
+			_, err := NotifyRemote(cfg, Message{Title: "test", Body: "body", PID: tt.pid})
 			if err != nil {
 				t.Fatalf("expected nil error, got %v", err)
 			}
@@ -725,7 +739,7 @@ func TestPush_BypassesIdleFocus(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
 	err := Push(cfg, Message{Title: "test", Body: "body"})
 	if err != nil {
@@ -747,7 +761,7 @@ func TestPush_DefaultTitle(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
 	err := Push(cfg, Message{Title: "", Body: "body"})
 	if err != nil {
@@ -765,7 +779,7 @@ func TestPushAll_NoBackends(t *testing.T) {
 	cfg := testConfig()
 	// All backends disabled by default.
 
-	err := pushAll(cfg, Message{Title: "test", Body: "body"})
+	_, err := pushAll(context.Background(), cfg, Message{Title: "test", Body: "body"})
 	if err != nil {
 		t.Fatalf("expected nil when no backends enabled, got %v", err)
 	}
@@ -783,9 +797,9 @@ func TestPushAll_SingleBackendSuccess(t *testing.T) {
 	cfg.Ntfy.Enabled = true
 	cfg.Ntfy.Server = srv.URL
 	cfg.Ntfy.Topic = "test"
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := pushAll(cfg, Message{Title: "test", Body: "body"})
+	_, err := pushAll(context.Background(), cfg, Message{Title: "test", Body: "body"})
 	if err != nil {
 		t.Fatalf("expected nil for successful ntfy, got %v", err)
 	}
@@ -824,11 +838,12 @@ func TestPushAll_EnabledBackendsRunConcurrently(t *testing.T) {
 	cfg.Ntfy.Topic = "test"
 	cfg.Discord.Enabled = true
 	cfg.Discord.WebhookURL = discordSrv.URL
-	httpClient = &http.Client{Timeout: time.Second}
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return &http.Client{Timeout: time.Second}, nil }
 
 	done := make(chan error, 1)
 	go func() {
-		done <- pushAll(cfg, Message{Title: "test", Body: "body"})
+		_, err := pushAll(context.Background(), cfg, Message{Title: "test", Body: "body"})
+		done <- err
 	}()
 
 	seen := map[string]bool{}
@@ -882,9 +897,9 @@ func TestPushAll_PartialFailure(t *testing.T) {
 	cfg.Ntfy.Topic = "test"
 	cfg.Discord.Enabled = true
 	cfg.Discord.WebhookURL = fmt.Sprintf("%s/discord", srv.URL)
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := pushAll(cfg, Message{Title: "test", Body: "body"})
+	_, err := pushAll(context.Background(), cfg, Message{Title: "test", Body: "body"})
 	if err == nil {
 		t.Fatal("expected error when ntfy fails")
 	}
@@ -918,9 +933,9 @@ func TestPushAll_AllFail(t *testing.T) {
 	cfg.Discord.WebhookURL = fmt.Sprintf("%s/discord", srv.URL)
 	cfg.Webhook.Enabled = true
 	cfg.Webhook.URL = fmt.Sprintf("%s/webhook", srv.URL)
-	httpClient = srv.Client()
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 
-	err := pushAll(cfg, Message{Title: "test", Body: "body"})
+	_, err := pushAll(context.Background(), cfg, Message{Title: "test", Body: "body"})
 	if err == nil {
 		t.Fatal("expected error when all backends fail")
 	}
@@ -930,3 +945,74 @@ func TestPushAll_AllFail(t *testing.T) {
 		}
 	}
 }
+
+// ─── cancellation ────────────────────────────────────────────────────────────
+
+func blockingNtfyConfig(t *testing.T) (config.Config, <-chan struct{}) {
+	t.Helper()
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	cfg := testConfig()
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
+	return cfg, arrived
+}
+
+func TestPushContext_CancelStopsInFlightPush(t *testing.T) {
+	setupStubs(t, 0, nil, false)
+	cfg, arrived := blockingNtfyConfig(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	err := PushContext(ctx, cfg, Message{Title: "test", Body: "body"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNotifyRemoteContext_DeliveryTimeoutBoundsPush(t *testing.T) {
+	setupStubs(t, 10*time.Minute, nil, false)
+	cfg, _ := blockingNtfyConfig(t)
+	cfg.Notification.DeliveryTimeoutSeconds = 1
+
+	start := time.Now()
+	_, err := NotifyRemoteContext(context.Background(), cfg, Message{Title: "test", Body: "body"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("delivery took %v, want it bounded by the 1s deadline", elapsed)
+	}
+}
+
+func TestResolveIdleState_ReceivesContext(t *testing.T) {
+	setupStubs(t, 0, nil, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var got error
+	IdleDurationFunc = func(ctx context.Context) (time.Duration, error) {
+		got = ctx.Err()
+		return 0, got
+	}
+	resolveIdleState(ctx, testConfig(), DefaultLoggerFunc())
+	if !errors.Is(got, context.Canceled) {
+		t.Fatalf("idle detection saw ctx error %v, want context.Canceled", got)
+	}
+}
@@ -1,36 +1,150 @@
 package notifier
 
 import (
+	"cmp"
+	"context"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/Digni/ding-ding/internal/config"
 )
 
-func sendNtfy(cfg config.NtfyConfig, msg Message) error {
+func sendNtfy(ctx context.Context, cfg config.NtfyConfig, msg Message) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	msg, err = applyTemplate("ntfy", cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+	opts := msg.Ntfy
+	if opts == nil {
+		opts = &NtfyOptions{}
+	}
+
 	url := fmt.Sprintf("%s/%s", strings.TrimRight(cfg.Server, "/"), cfg.Topic)
 
-	req, err := http.NewRequest("POST", url, strings.NewReader(msg.Body))
+	// A file attachment is uploaded as the request body with PUT, so the
+	// message text moves to the Message header.
+	method, body := "POST", io.Reader(strings.NewReader(msg.Body))
+	var size int64
+	if opts.File != "" {
+		f, err := os.Open(opts.File)
+		if err != nil {
+			return fmt.Errorf("open attachment: %w", err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("open attachment: %w", err)
+		}
+		method, body, size = "PUT", f, info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
 
-	req.Header.Set("Title", msg.Title)
+	req.Header.Set("Title", ntfyHeaderValue(msg.Title))
 
-	if cfg.Priority != "" {
-		req.Header.Set("Priority", cfg.Priority)
+	if opts.File != "" {
+		req.ContentLength = size
+		filename := opts.Filename
+		if filename == "" {
+			filename = filepath.Base(opts.File)
+		}
+		req.Header.Set("Filename", ntfyHeaderValue(filename))
+		if msg.Body != "" {
+			req.Header.Set("Message", ntfyHeaderValue(msg.Body))
+		}
 	}
 
+	priority := ntfyPriority(cfg.Priority, msg.Event)
+	if mapped, ok := mapPriority(cfg.PriorityMap, msg.Priority); ok && mapped != "" {
+		priority = mapped
+	}
+	if priority != "" {
+		req.Header.Set("Priority", priority)
+	}
+
+	var tags []string
+	if tag := styleFor(msg.Event).ntfyTag; tag != "" {
+		tags = append(tags, tag)
+	}
+	tags = append(tags, cfg.Tags...)
+	tags = append(tags, opts.Tags...)
+	// The agent and workspace tags come from the message, where a comma
+	// would split them into two tags.
 	if msg.Agent != "" {
-		req.Header.Set("Tags", msg.Agent)
+		tags = append(tags, ntfyTag(msg.Agent))
+	}
+	if where := workspaceLabel(msg); where != "" {
+		tags = append(tags, ntfyTag(where))
+	}
+	if len(tags) > 0 {
+		req.Header.Set("Tags", ntfyHeaderValue(strings.Join(tags, ",")))
+	}
+
+	markdown := cfg.Markdown
+	if opts.Markdown != nil {
+		markdown = *opts.Markdown
+	}
+	if markdown {
+		req.Header.Set("Markdown", "yes")
 	}
 
-	if cfg.Token != "" {
-		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	if click := cmp.Or(msg.URL, cfg.Click); click != "" {
+		req.Header.Set("Click", click)
+	}
+	if icon := cmp.Or(opts.Icon, cfg.Icon); icon != "" {
+		req.Header.Set("Icon", icon)
+	}
+	if opts.Attach != "" {
+		req.Header.Set("Attach", opts.Attach)
+		if opts.Filename != "" {
+			req.Header.Set("Filename", ntfyHeaderValue(opts.Filename))
+		}
+	}
+	if email := cmp.Or(opts.Email, cfg.Email); email != "" {
+		req.Header.Set("Email", email)
+	}
+	if delay := cmp.Or(opts.Delay, cfg.Delay); delay != "" {
+		req.Header.Set("Delay", delay)
+	}
+	if actions := ntfyActions(cfg, msg); actions != "" {
+		req.Header.Set("Actions", ntfyHeaderValue(actions))
 	}
 
-	resp, err := httpClient.Do(req)
+	// Publishing with the same sequence ID updates the earlier notification
+	// on subscribed devices instead of adding a new one.
+	if tag := groupTag(msg.group); tag != "" {
+		req.Header.Set("X-Sequence-ID", tag)
+	}
+
+	if !cfg.Cache {
+		req.Header.Set("Cache", "no")
+	}
+	if !cfg.Firebase {
+		req.Header.Set("Firebase", "no")
+	}
+
+	if auth := ntfyAuthorization(cfg); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	client, err := httpClientFor(cfg.HTTP)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("send request: %w", err)
 	}
@@ -42,3 +156,111 @@ func sendNtfy(cfg config.NtfyConfig, msg Message) error {
 
 	return nil
 }
+
+// ntfyAuthorization returns the Authorization header value for the
+// configured access token or basic-auth credentials.
+func ntfyAuthorization(cfg config.NtfyConfig) string {
+	switch {
+	case cfg.Token != "":
+		return "Bearer " + cfg.Token
+	case cfg.Username != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password))
+	}
+	return ""
+}
+
+// ntfyHeaderValue RFC 2047-encodes values that cannot be sent verbatim in
+// an HTTP header, such as multi-line message bodies; ntfy decodes them.
+func ntfyHeaderValue(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return mime.BEncoding.Encode("UTF-8", s)
+		}
+	}
+	return s
+}
+
+// ntfyTag replaces the commas that separate tags in the Tags header.
+func ntfyTag(s string) string {
+	return strings.ReplaceAll(s, ",", "_")
+}
+
+// ntfyMaxActions is the number of action buttons ntfy displays.
+const ntfyMaxActions = 3
+
+// ntfyActions renders reply and view actions in ntfy's header format:
+// "http, Label, URL, ...; view, Label, URL". Labels and URLs containing
+// separators are quoted. Reply buttons come first since ntfy shows only three.
+func ntfyActions(cfg config.NtfyConfig, msg Message) string {
+	var parts []string
+
+	// Reply buttons publish "<operation_id> <reply>" to the reply topic,
+	// where the server's subscription picks it up. Without an operation ID
+	// (one-shot CLI sends) nobody could collect the answer. The button is
+	// part of the message, so it only ever carries the reply-only token,
+	// never the credentials used to publish.
+	if cfg.ReplyTopic != "" && msg.OperationID != "" {
+		replyURL := fmt.Sprintf("%s/%s", strings.TrimRight(cfg.Server, "/"), cfg.ReplyTopic)
+		for _, reply := range msg.Replies {
+			part := fmt.Sprintf("http, %s, %s, method=POST, body=%s %s, clear=true", actionLabel(reply), replyURL, msg.OperationID, reply)
+			if cfg.ReplyToken != "" {
+				part += ", headers.Authorization=Bearer " + cfg.ReplyToken
+			}
+			parts = append(parts, part)
+		}
+	}
+
+	for _, action := range msg.Actions {
+		label := action.Label
+		if strings.ContainsAny(label, ",;\"'") {
+			label = `"` + strings.ReplaceAll(label, `"`, `'`) + `"`
+		}
+		parts = append(parts, fmt.Sprintf("view, %s, %s", label, ntfyActionURL(action.URL)))
+	}
+
+	if len(parts) > ntfyMaxActions {
+		parts = parts[:ntfyMaxActions]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ntfyActionURL quotes a URL containing separators of the Actions header. A
+// double quote would end the quoted value, so it is percent-encoded, which
+// leaves the URL unchanged.
+func ntfyActionURL(u string) string {
+	u = strings.ReplaceAll(u, `"`, "%22")
+	if strings.ContainsAny(u, ",;'") {
+		return `"` + u + `"`
+	}
+	return u
+}
+
+var ntfyPriorityLevels = map[string]int{
+	"min": 1, "1": 1,
+	"low": 2, "2": 2,
+	"default": 3, "3": 3,
+	"high": 4, "4": 4,
+	"max": 5, "urgent": 5, "5": 5,
+}
+
+// ntfyPriority adjusts the configured priority by event: urgent events are
+// raised to at least "high" and quiet events lowered to at most "low".
+func ntfyPriority(configured string, event Event) string {
+	style := styleFor(event)
+	if !style.urgent && !style.quiet {
+		return configured
+	}
+
+	level, ok := ntfyPriorityLevels[strings.ToLower(strings.TrimSpace(configured))]
+	if !ok {
+		level = ntfyPriorityLevels["default"]
+	}
+
+	switch {
+	case style.urgent && level < ntfyPriorityLevels["high"]:
+		return "high"
+	case style.quiet && level > ntfyPriorityLevels["low"]:
+		return "low"
+	}
+	return configured
+}
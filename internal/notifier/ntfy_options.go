@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NtfyOptions are per-message ntfy features. Fields left empty fall back to
+// the ntfy config section; Tags are added to the configured tags.
+type NtfyOptions struct {
+	Tags     []string `json:"tags,omitempty"`     // extra tags; emoji shortcodes render as emojis
+	Markdown *bool    `json:"markdown,omitempty"` // render the body as Markdown
+	Icon     string   `json:"icon,omitempty"`     // notification icon URL
+	Attach   string   `json:"attach,omitempty"`   // attachment URL
+	File     string   `json:"file,omitempty"`     // local file uploaded as the attachment (CLI only)
+	Filename string   `json:"filename,omitempty"` // attachment name shown by ntfy
+	Email    string   `json:"email,omitempty"`    // forward to this address
+	Delay    string   `json:"delay,omitempty"`    // schedule delivery, e.g. "30m" or "tomorrow, 10am"
+}
+
+// ValidateNtfyOptions checks per-message ntfy options. Link fields follow the
+// same http/https rule as message URLs.
+func ValidateNtfyOptions(opts *NtfyOptions) error {
+	if opts == nil {
+		return nil
+	}
+	for _, tag := range opts.Tags {
+		if tag == "" || strings.ContainsAny(tag, ",\r\n") {
+			return fmt.Errorf("invalid ntfy tag %q: tags must be non-empty without commas", tag)
+		}
+	}
+	if opts.Icon != "" {
+		if err := validateLinkURL(opts.Icon); err != nil {
+			return fmt.Errorf("invalid ntfy icon: %w", err)
+		}
+	}
+	if opts.Attach != "" {
+		if err := validateLinkURL(opts.Attach); err != nil {
+			return fmt.Errorf("invalid ntfy attachment: %w", err)
+		}
+		if opts.File != "" {
+			return fmt.Errorf("invalid ntfy attachment: use either an attachment URL or a file, not both")
+		}
+	}
+	if opts.Email != "" && (!strings.Contains(opts.Email, "@") || strings.ContainsAny(opts.Email, " \r\n")) {
+		return fmt.Errorf("invalid ntfy email %q", opts.Email)
+	}
+	for _, value := range []string{opts.Filename, opts.Delay} {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid ntfy option %q: line breaks are not allowed", value)
+		}
+	}
+	return nil
+}
+
+// ParseNtfyAttachment sorts a CLI attachment argument into a URL or a local
+// file to upload.
+func ParseNtfyAttachment(value string) (attach, file string) {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return value, ""
+	}
+	return "", value
+}
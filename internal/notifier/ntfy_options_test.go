@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNtfyOptions(t *testing.T) {
+	valid := &NtfyOptions{
+		Tags:   []string{"warning", "skull"},
+		Icon:   "https://example.com/icon.png",
+		Attach: "https://example.com/report.pdf",
+		Email:  "phil@example.com",
+		Delay:  "tomorrow, 10am",
+	}
+	if err := ValidateNtfyOptions(valid); err != nil {
+		t.Fatalf("ValidateNtfyOptions() error = %v, want nil", err)
+	}
+	if err := ValidateNtfyOptions(nil); err != nil {
+		t.Fatalf("ValidateNtfyOptions(nil) error = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name string
+		opts NtfyOptions
+		want string
+	}{
+		{name: "tag with comma", opts: NtfyOptions{Tags: []string{"a,b"}}, want: "invalid ntfy tag"},
+		{name: "icon scheme", opts: NtfyOptions{Icon: "file:///icon.png"}, want: "invalid ntfy icon"},
+		{name: "attach scheme", opts: NtfyOptions{Attach: "ftp://example.com/x"}, want: "invalid ntfy attachment"},
+		{name: "attach and file", opts: NtfyOptions{Attach: "https://example.com/x", File: "x"}, want: "not both"},
+		{name: "email", opts: NtfyOptions{Email: "nobody"}, want: "invalid ntfy email"},
+		{name: "header injection", opts: NtfyOptions{Delay: "30m\r\nX-Evil: 1"}, want: "line breaks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNtfyOptions(&tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ValidateNtfyOptions() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNtfyAttachment(t *testing.T) {
+	if attach, file := ParseNtfyAttachment("https://example.com/a.png"); attach != "https://example.com/a.png" || file != "" {
+		t.Errorf("URL: got attach=%q file=%q", attach, file)
+	}
+	if attach, file := ParseNtfyAttachment("./build.log"); attach != "" || file != "./build.log" {
+		t.Errorf("path: got attach=%q file=%q", attach, file)
+	}
+}
@@ -0,0 +1,132 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// Reply is an answer published to ntfy.reply_topic by a reply button.
+type Reply struct {
+	OperationID string    `json:"operation_id"`
+	Reply       string    `json:"reply"`
+	Time        time.Time `json:"time"`
+}
+
+// ntfyStreamClientFor returns the ntfy backend's client without an overall
+// timeout: the subscription is a single long-lived response. Tests replace it.
+var ntfyStreamClientFor = func(cfg config.HTTPConfig) (*http.Client, error) {
+	client, err := httpClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stream := *client
+	stream.Timeout = 0
+	return &stream, nil
+}
+
+var (
+	ntfyReplyMinBackoff = time.Second
+	ntfyReplyMaxBackoff = time.Minute
+)
+
+// SubscribeNtfyReplies follows ntfy.reply_topic over ntfy's JSON stream and
+// passes each reply to handle until ctx ends. Dropped streams reconnect with
+// backoff and resume after the last message seen.
+func SubscribeNtfyReplies(ctx context.Context, cfg config.NtfyConfig, handle func(Reply)) {
+	logger := DefaultLoggerFunc().With("topic", cfg.ReplyTopic)
+	logger.Info("notifier.ntfy.replies.subscribed")
+
+	since := ""
+	backoff := ntfyReplyMinBackoff
+	for {
+		last, err := streamNtfyReplies(ctx, cfg, since, handle)
+		if last != "" {
+			since = last
+			backoff = ntfyReplyMinBackoff
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Warn("notifier.ntfy.replies.disconnected", "error", err, "retry_ms", backoff.Milliseconds())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, ntfyReplyMaxBackoff)
+	}
+}
+
+func streamNtfyReplies(ctx context.Context, cfg config.NtfyConfig, since string, handle func(Reply)) (lastID string, err error) {
+	cfg, err = resolveSecrets(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	streamURL := fmt.Sprintf("%s/%s/json", strings.TrimRight(cfg.Server, "/"), cfg.ReplyTopic)
+	if since != "" {
+		streamURL += "?since=" + url.QueryEscape(since)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	if auth := ntfyAuthorization(cfg); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	client, err := ntfyStreamClientFor(cfg.HTTP)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("subscribe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("ntfy returned status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event struct {
+			ID      string `json:"id"`
+			Time    int64  `json:"time"`
+			Event   string `json:"event"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Event != "message" {
+			continue
+		}
+		lastID = event.ID
+		if reply, ok := parseNtfyReply(event.Message, time.Unix(event.Time, 0)); ok {
+			handle(reply)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return lastID, fmt.Errorf("read stream: %w", err)
+	}
+	return lastID, errors.New("stream closed")
+}
+
+// parseNtfyReply reads the "<operation_id> <reply>" body published by reply
+// buttons. Anything else on the topic is ignored.
+func parseNtfyReply(body string, at time.Time) (Reply, bool) {
+	fields := strings.Fields(body)
+	if len(fields) != 2 {
+		return Reply{}, false
+	}
+	return Reply{OperationID: fields[0], Reply: fields[1], Time: at}, true
+}
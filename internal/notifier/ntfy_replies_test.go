@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestParseNtfyReply(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	got, ok := parseNtfyReply("op-1 allow\n", at)
+	if !ok || got.OperationID != "op-1" || got.Reply != "allow" || !got.Time.Equal(at) {
+		t.Fatalf("parseNtfyReply() = %+v, %v", got, ok)
+	}
+	for _, body := range []string{"", "allow", "op-1 allow now"} {
+		if _, ok := parseNtfyReply(body, at); ok {
+			t.Errorf("parseNtfyReply(%q) ok = true, want false", body)
+		}
+	}
+}
+
+func TestSubscribeNtfyReplies_ReconnectsAfterLastMessage(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RequestURI()+" "+r.Header.Get("Authorization"))
+		n := len(requests)
+		mu.Unlock()
+
+		fmt.Fprintln(w, `{"id":"o1","time":1700000000,"event":"open","topic":"replies"}`)
+		fmt.Fprintln(w, `{"id":"k1","time":1700000001,"event":"keepalive","topic":"replies"}`)
+		fmt.Fprintf(w, `{"id":"m%d","time":1700000002,"event":"message","topic":"replies","message":"op-%d deny"}`+"\n", n, n)
+		fmt.Fprintln(w, `{"id":"x","time":1700000003,"event":"message","topic":"replies","message":"unrelated chat message"}`)
+	}))
+	defer srv.Close()
+
+	origClient, origBackoff := ntfyStreamClientFor, ntfyReplyMinBackoff
+	t.Cleanup(func() { ntfyStreamClientFor, ntfyReplyMinBackoff = origClient, origBackoff })
+	ntfyStreamClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
+	ntfyReplyMinBackoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan Reply, 8)
+	done := make(chan struct{})
+	go func() {
+		SubscribeNtfyReplies(ctx, config.NtfyConfig{Server: srv.URL, ReplyTopic: "replies", Token: "tk"}, func(r Reply) { got <- r })
+		close(done)
+	}()
+
+	for i := 1; i <= 2; i++ {
+		select {
+		case reply := <-got:
+			if want := fmt.Sprintf("op-%d", i); reply.OperationID != want || reply.Reply != "deny" {
+				t.Fatalf("reply %d = %+v, want %s deny", i, reply, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for reply %d", i)
+		}
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests[0] != "/replies/json Bearer tk" {
+		t.Errorf("first request = %q", requests[0])
+	}
+	if requests[1] != "/replies/json?since=x Bearer tk" {
+		t.Errorf("reconnect request = %q, want to resume after the last message", requests[1])
+	}
+}
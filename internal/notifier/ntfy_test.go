@@ -1,25 +1,29 @@
 package notifier
 
 import (
+	"context"
 	"io"
+	"mime"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/Digni/ding-ding/internal/config"
 )
 
-// setupHTTPTest starts a test HTTP server using handler, swaps the package-level
-// httpClient to the server's client (so requests are routed to it), and restores
-// both on test cleanup.
+// setupHTTPTest starts a test HTTP server using handler, swaps httpClientFor to
+// return the server's client (so requests are routed to it), and restores both
+// on test cleanup.
 func setupHTTPTest(t *testing.T, handler http.HandlerFunc) *httptest.Server {
 	t.Helper()
 	srv := httptest.NewServer(handler)
-	origClient := httpClient
-	httpClient = srv.Client()
+	origClient := httpClientFor
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
 	t.Cleanup(func() {
-		httpClient = origClient
+		httpClientFor = origClient
 		srv.Close()
 	})
 	return srv
@@ -43,7 +47,7 @@ func TestSendNtfy_Success(t *testing.T) {
 	}
 	msg := Message{Title: "hello", Body: "world"}
 
-	err := sendNtfy(cfg, msg)
+	err := sendNtfy(context.Background(), cfg, msg)
 	if err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
@@ -76,7 +80,7 @@ func TestSendNtfy_WithPriority(t *testing.T) {
 	}
 	msg := Message{Title: "t", Body: "b"}
 
-	if err := sendNtfy(cfg, msg); err != nil {
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	if gotPriority != "high" {
@@ -99,7 +103,7 @@ func TestSendNtfy_WithToken(t *testing.T) {
 	}
 	msg := Message{Title: "t", Body: "b"}
 
-	if err := sendNtfy(cfg, msg); err != nil {
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	if gotAuth != "Bearer secret" {
@@ -121,7 +125,7 @@ func TestSendNtfy_WithAgent(t *testing.T) {
 	}
 	msg := Message{Title: "t", Body: "b", Agent: "claude"}
 
-	if err := sendNtfy(cfg, msg); err != nil {
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	if gotTags != "claude" {
@@ -140,7 +144,7 @@ func TestSendNtfy_ServerError(t *testing.T) {
 	}
 	msg := Message{Title: "t", Body: "b"}
 
-	err := sendNtfy(cfg, msg)
+	err := sendNtfy(context.Background(), cfg, msg)
 	if err == nil {
 		t.Fatal("expected an error, got nil")
 	}
@@ -171,7 +175,7 @@ func TestSendNtfy_NoOptionalHeaders(t *testing.T) {
 	}
 	msg := Message{Title: "t", Body: "b", Agent: ""}
 
-	if err := sendNtfy(cfg, msg); err != nil {
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	if priorityPresent {
@@ -184,3 +188,232 @@ func TestSendNtfy_NoOptionalHeaders(t *testing.T) {
 		t.Errorf("expected Tags header to be absent, got %q", gotTags)
 	}
 }
+
+func TestSendNtfy_WithLinks(t *testing.T) {
+	var gotClick, gotActions string
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotClick = r.Header.Get("Click")
+		gotActions = r.Header.Get("Actions")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.NtfyConfig{Server: srv.URL, Topic: "topic"}
+	msg := Message{
+		Title: "t",
+		Body:  "b",
+		URL:   "https://example.com/run/1",
+		Actions: []Action{
+			{Label: "Logs", URL: "https://example.com/logs"},
+			{Label: "Approve, then merge", URL: "https://example.com/pr"},
+		},
+	}
+
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if gotClick != msg.URL {
+		t.Errorf("expected Click header %q, got %q", msg.URL, gotClick)
+	}
+	want := `view, Logs, https://example.com/logs; view, "Approve, then merge", https://example.com/pr`
+	if gotActions != want {
+		t.Errorf("expected Actions header %q, got %q", want, gotActions)
+	}
+}
+
+func TestSendNtfy_EscapesGeneratedHeaders(t *testing.T) {
+	var got http.Header
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.NtfyConfig{Server: srv.URL, Topic: "topic"}
+	msg := Message{
+		Title:  "Café build\nfinished",
+		Body:   "b",
+		Agent:  "claude",
+		Repo:   "ding-ding",
+		Branch: "feat,x",
+		Actions: []Action{
+			{Label: "Search", URL: "https://example.com/q?a=1,2;b='c'"},
+			{Label: "Quote", URL: `https://example.com/"x"`},
+		},
+	}
+
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	title, err := new(mime.WordDecoder).DecodeHeader(got.Get("Title"))
+	if err != nil || title != msg.Title {
+		t.Errorf("Title header = %q decodes to %q (err=%v), want %q", got.Get("Title"), title, err, msg.Title)
+	}
+	if tags := strings.Split(got.Get("Tags"), ","); len(tags) != 2 || tags[0] != "claude" || strings.Contains(tags[1], ",") {
+		t.Errorf("Tags header = %q, want the agent and one workspace tag", got.Get("Tags"))
+	}
+	want := `view, Search, "https://example.com/q?a=1,2;b='c'"; view, Quote, https://example.com/%22x%22`
+	if got.Get("Actions") != want {
+		t.Errorf("Actions header = %q, want %q", got.Get("Actions"), want)
+	}
+}
+
+func TestSendNtfy_ReplyButtons(t *testing.T) {
+	var gotActions string
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotActions = r.Header.Get("Actions")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.NtfyConfig{Server: srv.URL + "/", Topic: "topic", ReplyTopic: "replies", Token: "tk", ReplyToken: "tk_reply"}
+	msg := Message{
+		Title:       "t",
+		Body:        "Needs input",
+		OperationID: "op-1",
+		Replies:     []string{"allow", "deny"},
+		Actions:     []Action{{Label: "Logs", URL: "https://example.com/logs"}, {Label: "Dropped", URL: "https://example.com"}},
+	}
+
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	want := "http, Allow, " + srv.URL + "/replies, method=POST, body=op-1 allow, clear=true, headers.Authorization=Bearer tk_reply; " +
+		"http, Deny, " + srv.URL + "/replies, method=POST, body=op-1 deny, clear=true, headers.Authorization=Bearer tk_reply; " +
+		"view, Logs, https://example.com/logs"
+	if gotActions != want {
+		t.Errorf("Actions header =\n%q\nwant\n%q", gotActions, want)
+	}
+
+	// Without a reply token the buttons publish anonymously; the publishing
+	// credentials are never put in the message.
+	for _, auth := range []config.NtfyConfig{
+		{Token: "tk_publish"},
+		{Username: "phil", Password: "s3cret"},
+	} {
+		auth.Server, auth.Topic, auth.ReplyTopic = srv.URL, "topic", "replies"
+		if err := sendNtfy(context.Background(), auth, msg); err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+		if strings.Contains(gotActions, "Authorization") || strings.Contains(gotActions, "tk_publish") ||
+			strings.Contains(gotActions, ntfyAuthorization(auth)) {
+			t.Errorf("Actions header carries the publishing credentials: %q", gotActions)
+		}
+	}
+
+	// Without an operation ID there is nobody to collect the answer.
+	msg.OperationID = ""
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if strings.Contains(gotActions, "http,") {
+		t.Errorf("expected no reply buttons without an operation ID, got %q", gotActions)
+	}
+}
+
+func TestSendNtfy_PublishOptions(t *testing.T) {
+	var got http.Header
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.NtfyConfig{
+		Server:   srv.URL,
+		Topic:    "topic",
+		Username: "phil",
+		Password: "secret",
+		Tags:     []string{"robot"},
+		Icon:     "https://example.com/config.png",
+		Click:    "https://example.com/dashboard",
+		Email:    "config@example.com",
+		Delay:    "1h",
+		Cache:    false,
+		Firebase: true,
+	}
+	markdown := true
+	msg := Message{
+		Title: "t",
+		Body:  "**done**",
+		Event: EventCompleted,
+		Agent: "claude",
+		Ntfy: &NtfyOptions{
+			Tags:     []string{"tada"},
+			Markdown: &markdown,
+			Icon:     "https://example.com/msg.png",
+			Attach:   "https://example.com/report.pdf",
+			Filename: "report.pdf",
+			Delay:    "30m",
+		},
+	}
+
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	for header, want := range map[string]string{
+		"Tags":          "white_check_mark,robot,tada,claude",
+		"Markdown":      "yes",
+		"Icon":          "https://example.com/msg.png",
+		"Click":         "https://example.com/dashboard",
+		"Attach":        "https://example.com/report.pdf",
+		"Filename":      "report.pdf",
+		"Email":         "config@example.com",
+		"Delay":         "30m",
+		"Cache":         "no",
+		"Firebase":      "",
+		"Authorization": "Basic cGhpbDpzZWNyZXQ=",
+	} {
+		if got.Get(header) != want {
+			t.Errorf("%s header = %q, want %q", header, got.Get(header), want)
+		}
+	}
+}
+
+func TestSendNtfy_FileUpload(t *testing.T) {
+	var gotMethod, gotFilename, gotMessage, gotBody string
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotFilename = r.Header.Get("Filename")
+		gotMessage = r.Header.Get("Message")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	path := filepath.Join(t.TempDir(), "build.log")
+	if err := os.WriteFile(path, []byte("log contents"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.NtfyConfig{Server: srv.URL, Topic: "topic", Cache: true, Firebase: true}
+	msg := Message{Title: "t", Body: "Build failed\nsee log", Ntfy: &NtfyOptions{File: path}}
+
+	if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if gotMethod != http.MethodPut {
+		t.Errorf("expected PUT, got %s", gotMethod)
+	}
+	if gotFilename != "build.log" {
+		t.Errorf("expected Filename build.log, got %q", gotFilename)
+	}
+	if gotBody != "log contents" {
+		t.Errorf("expected file contents as body, got %q", gotBody)
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(gotMessage)
+	if err != nil || decoded != msg.Body {
+		t.Errorf("expected encoded Message header %q, got %q (%v)", msg.Body, decoded, err)
+	}
+}
+
+func TestSendNtfy_MissingAttachmentFile(t *testing.T) {
+	cfg := config.NtfyConfig{Server: "http://127.0.0.1:1", Topic: "topic"}
+	msg := Message{Title: "t", Ntfy: &NtfyOptions{File: filepath.Join(t.TempDir(), "missing")}}
+
+	err := sendNtfy(context.Background(), cfg, msg)
+	if err == nil || !strings.Contains(err.Error(), "open attachment") {
+		t.Fatalf("expected open attachment error, got: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority is the sender's urgency for a single message. Backends translate
+// it through their configured priority maps.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities lists every recognized priority from least to most urgent.
+var Priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// ParsePriority validates a priority name. The empty string is accepted and
+// means the message carries no priority, so backends fall back to their
+// configured defaults and event styling.
+func ParsePriority(value string) (Priority, error) {
+	normalized := Priority(strings.ToLower(strings.TrimSpace(value)))
+	if normalized == "" {
+		return "", nil
+	}
+	for _, priority := range Priorities {
+		if normalized == priority {
+			return normalized, nil
+		}
+	}
+
+	names := make([]string, len(Priorities))
+	for i, priority := range Priorities {
+		names[i] = string(priority)
+	}
+	return "", fmt.Errorf("invalid priority %q (expected one of %s)", value, strings.Join(names, ", "))
+}
+
+// mapPriority looks up a message priority in a backend's priority map.
+// ok is false when the message has no priority or the map has no entry.
+func mapPriority(m map[string]string, priority Priority) (string, bool) {
+	if priority == "" {
+		return "", false
+	}
+	value, ok := m[string(priority)]
+	return value, ok
+}
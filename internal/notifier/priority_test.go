@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestParsePriority(t *testing.T) {
+	for input, want := range map[string]Priority{
+		"":         "",
+		"low":      PriorityLow,
+		" Normal ": PriorityNormal,
+		"high":     PriorityHigh,
+		"URGENT":   PriorityUrgent,
+	} {
+		got, err := ParsePriority(input)
+		if err != nil {
+			t.Errorf("ParsePriority(%q) returned error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParsePriority(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	if _, err := ParsePriority("critical"); err == nil || !strings.Contains(err.Error(), `invalid priority "critical"`) {
+		t.Errorf("ParsePriority(critical) error = %v, want invalid priority error", err)
+	}
+}
+
+func TestSendNtfy_MessagePriorityUsesPriorityMap(t *testing.T) {
+	var got []string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Priority"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.DefaultConfig().Ntfy
+	cfg.Server = srv.URL
+	cfg.PriorityMap["high"] = "max"
+
+	for _, msg := range []Message{
+		{Title: "t", Priority: PriorityLow, Event: EventError},
+		{Title: "t", Priority: PriorityHigh},
+		{Title: "t"},
+	} {
+		if err := sendNtfy(context.Background(), cfg, msg); err != nil {
+			t.Fatalf("sendNtfy(context.Background(), ) error = %v", err)
+		}
+	}
+
+	want := []string{"low", "max", "high"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Priority headers = %v, want %v", got, want)
+	}
+}
+
+func TestNewSystemNotification_MessagePriority(t *testing.T) {
+	cfg := config.DefaultConfig().Notification
+
+	n := newSystemNotification(cfg, Message{Title: "t", Priority: PriorityUrgent, Event: EventProgress})
+	if n.Urgency != "critical" {
+		t.Errorf("urgent urgency = %q, want critical", n.Urgency)
+	}
+	if n.Scenario != "urgent" {
+		t.Errorf("urgent scenario = %q, want urgent", n.Scenario)
+	}
+
+	n = newSystemNotification(cfg, Message{Title: "t", Priority: PriorityLow, Event: EventError})
+	if n.Urgency != "low" {
+		t.Errorf("low priority should override the error event, got urgency %q", n.Urgency)
+	}
+	if n.Scenario != "" {
+		t.Errorf("low scenario = %q, want none", n.Scenario)
+	}
+}
+
+func TestSendDiscord_PriorityMention(t *testing.T) {
+	var payloads []map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &payload)
+		payloads = append(payloads, payload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cfg := config.DefaultConfig().Discord
+	cfg.WebhookURL = srv.URL
+	cfg.PriorityMentions["urgent"] = "@here"
+	cfg.PriorityMentions["high"] = "<@&42>"
+
+	for _, msg := range []Message{
+		{Title: "t", Body: "b", Priority: PriorityUrgent},
+		{Title: "t", Body: "b", Priority: PriorityHigh},
+		{Title: "t", Body: "b <@1>", Priority: PriorityNormal},
+	} {
+		if err := sendDiscord(context.Background(), cfg, msg); err != nil {
+			t.Fatalf("sendDiscord(context.Background(), ) error = %v", err)
+		}
+	}
+
+	if content := payloads[0]["content"].(string); !strings.HasPrefix(content, "@here ") {
+		t.Errorf("urgent content = %q, want @here prefix", content)
+	}
+	if parse := payloads[0]["allowed_mentions"].(map[string]any)["parse"]; len(parse.([]any)) != 1 {
+		t.Errorf("urgent allowed_mentions.parse = %v, want [everyone]", parse)
+	}
+
+	if content := payloads[1]["content"].(string); !strings.HasPrefix(content, "<@&42> ") {
+		t.Errorf("high content = %q, want role mention prefix", content)
+	}
+	roles := payloads[1]["allowed_mentions"].(map[string]any)["roles"].([]any)
+	if len(roles) != 1 || roles[0] != "42" {
+		t.Errorf("high allowed_mentions.roles = %v, want [42]", roles)
+	}
+
+	// Without a priority mention nothing may ping, including <@1> in the body.
+	allowed, _ := payloads[2]["allowed_mentions"].(map[string]any)
+	if parse, _ := allowed["parse"].([]any); allowed == nil || len(parse) != 0 || len(allowed) != 1 {
+		t.Errorf("normal priority allowed_mentions = %v, want {parse: []}", payloads[2]["allowed_mentions"])
+	}
+}
+
+func TestSendWebhook_PriorityMap(t *testing.T) {
+	var got Message
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.WebhookConfig{URL: srv.URL, PriorityMap: map[string]string{"urgent": "sev1"}}
+
+	if err := sendWebhook(context.Background(), cfg, Message{Title: "t", Priority: PriorityUrgent}); err != nil {
+		t.Fatalf("sendWebhook(context.Background(), ) error = %v", err)
+	}
+	if got.Priority != "sev1" {
+		t.Errorf("mapped priority = %q, want sev1", got.Priority)
+	}
+
+	if err := sendWebhook(context.Background(), cfg, Message{Title: "t", Priority: PriorityHigh}); err != nil {
+		t.Fatalf("sendWebhook(context.Background(), ) error = %v", err)
+	}
+	if got.Priority != PriorityHigh {
+		t.Errorf("unmapped priority = %q, want high", got.Priority)
+	}
+}
@@ -0,0 +1,253 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// SystemProvider is a way the local notification can be shown, such as the
+// freedesktop D-Bus service or notify-send on Linux.
+type SystemProvider struct {
+	Name string
+	// Note says when the provider matters, if not always.
+	Note string
+	// Probe checks the provider without showing anything.
+	Probe func(ctx context.Context) (string, error)
+}
+
+// SystemProviders lists the local notification providers for this platform,
+// in the order they are tried.
+func SystemProviders() []SystemProvider {
+	switch runtime.GOOS {
+	case "linux":
+		return []SystemProvider{
+			{Name: "dbus", Probe: probeDBusNotifications},
+			{Name: "notify-send", Note: "fallback when D-Bus fails", Probe: lookPathProbe("notify-send")},
+		}
+	case "darwin":
+		return []SystemProvider{{Name: "osascript", Probe: lookPathProbe("osascript")}}
+	case "windows":
+		return []SystemProvider{{Name: "powershell", Probe: lookPathProbe("powershell")}}
+	default:
+		return nil
+	}
+}
+
+// probeDBusNotifications asks the notification server to identify itself.
+func probeDBusNotifications(ctx context.Context) (string, error) {
+	session, err := dbusConnect()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	var name, vendor, version, spec string
+	call := session.CallWithContext(ctx, dbusNotificationsName+".GetServerInformation", 0)
+	if err := call.Store(&name, &vendor, &version, &spec); err != nil {
+		return "", fmt.Errorf("no notification server on the session bus: %w", err)
+	}
+	return fmt.Sprintf("%s %s (%s)", name, version, vendor), nil
+}
+
+func lookPathProbe(name string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			return "", fmt.Errorf("%s not found in PATH", name)
+		}
+		return path, nil
+	}
+}
+
+// ProbeBackends checks that each enabled push backend is reachable and
+// accepts its credentials, without sending a notification.
+func ProbeBackends(ctx context.Context, cfg config.Config) []BackendResult {
+	results, _ := runTargets(ctx, probeTargets(cfg.WithBackendHTTP()))
+	return results
+}
+
+func probeTargets(cfg config.Config) []pushTarget {
+	var targets []pushTarget
+	if cfg.Ntfy.Enabled {
+		targets = append(targets, pushTarget{
+			label: "ntfy",
+			send:  func(ctx context.Context) error { return probeNtfy(ctx, cfg.Ntfy) },
+		})
+	}
+	if cfg.Discord.Enabled {
+		targets = append(targets, pushTarget{
+			label: "discord",
+			send:  func(ctx context.Context) error { return probeDiscord(ctx, cfg.Discord) },
+		})
+	}
+	if cfg.Webhook.Enabled {
+		targets = append(targets, pushTarget{
+			label: "webhook",
+			send:  func(ctx context.Context) error { return probeWebhook(ctx, cfg.Webhook) },
+		})
+	}
+	if cfg.Email.Enabled {
+		targets = append(targets, pushTarget{
+			label: "email",
+			send:  func(ctx context.Context) error { return probeEmail(ctx, cfg.Email) },
+		})
+	}
+	if cfg.Teams.Enabled {
+		targets = append(targets, pushTarget{
+			label: "teams",
+			send:  func(ctx context.Context) error { return probeTeams(ctx, cfg.Teams) },
+		})
+	}
+	if cfg.MQTT.Enabled {
+		targets = append(targets, pushTarget{
+			label: "mqtt",
+			send:  func(ctx context.Context) error { return probeMQTT(ctx, cfg.MQTT) },
+		})
+	}
+	return targets
+}
+
+// probeNtfy uses ntfy's topic auth endpoint, which checks the credentials
+// without publishing.
+func probeNtfy(ctx context.Context, cfg config.NtfyConfig) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/%s/auth", strings.TrimRight(cfg.Server, "/"), cfg.Topic)
+	header := http.Header{}
+	if auth := ntfyAuthorization(cfg); auth != "" {
+		header.Set("Authorization", auth)
+	}
+	status, err := probeHTTP(ctx, cfg.HTTP, http.MethodGet, url, header)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("ntfy auth check returned status %d", status)
+	}
+	return nil
+}
+
+// probeDiscord fetches the webhook, which Discord answers only while the
+// webhook exists and its token is valid.
+func probeDiscord(ctx context.Context, cfg config.DiscordConfig) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	status, err := probeHTTP(ctx, cfg.HTTP, http.MethodGet, cfg.WebhookURL, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("discord webhook lookup returned status %d", status)
+	}
+	return nil
+}
+
+// probeWebhook sends HEAD with the configured headers. Endpoints often
+// reject HEAD itself, so only auth failures, a missing route and server
+// errors count.
+func probeWebhook(ctx context.Context, cfg config.WebhookConfig) error {
+	header := http.Header{}
+	for name, value := range cfg.Headers {
+		resolved, err := resolveWebhookValue(ctx, value)
+		if err != nil {
+			return fmt.Errorf("webhook header %s: %w", name, err)
+		}
+		header.Set(name, resolved)
+	}
+	status, err := probeHTTP(ctx, cfg.HTTP, http.MethodHead, cfg.URL, header)
+	if err != nil {
+		return err
+	}
+	if probeRejected(status) || status == http.StatusNotFound {
+		return fmt.Errorf("webhook returned status %d", status)
+	}
+	return nil
+}
+
+// probeTeams sends HEAD to the workflow URL; like probeWebhook, a rejected
+// method still proves the endpoint is reachable.
+func probeTeams(ctx context.Context, cfg config.TeamsConfig) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	status, err := probeHTTP(ctx, cfg.HTTP, http.MethodHead, cfg.WebhookURL, nil)
+	if err != nil {
+		return err
+	}
+	if probeRejected(status) {
+		return fmt.Errorf("teams returned status %d", status)
+	}
+	return nil
+}
+
+func probeRejected(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden || status >= 500
+}
+
+// probeHTTP sends a bodiless request and returns the response status.
+// Errors omit the URL, which can hold a webhook token.
+func probeHTTP(ctx context.Context, httpCfg config.HTTPConfig, method, url string, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", withoutURL(err))
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	client, err := httpClientFor(httpCfg)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send request: %w", withoutURL(err))
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// probeEmail connects and authenticates, then quits before MAIL FROM.
+func probeEmail(ctx context.Context, cfg config.EmailConfig) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	client, stop, err := dialSMTP(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	defer client.Close()
+
+	if auth := emailAuth(cfg); auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+	}
+	return client.Quit()
+}
+
+// probeMQTT connects with the configured credentials and disconnects.
+func probeMQTT(ctx context.Context, cfg config.MQTTConfig) error {
+	session := newMQTTSession(cfg, false)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if err := session.connectLocked(ctx); err != nil {
+		return err
+	}
+	session.closeLocked()
+	return nil
+}
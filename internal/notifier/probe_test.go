@@ -0,0 +1,146 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestProbeBackends_ChecksWithoutSending(t *testing.T) {
+	var requests []string
+	var mu sync.Mutex
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/test/auth":
+			if r.Header.Get("Authorization") != "Bearer tk_secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		case "/webhook":
+			if r.Header.Get("X-Token") != "s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case "/teams":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	t.Setenv("PROBE_TOKEN", "s3cret")
+
+	broker := startMQTTBroker(t)
+	smtp := startSMTPStub(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+	cfg.Ntfy.Token = "tk_secret"
+	cfg.Discord.Enabled = true
+	cfg.Discord.WebhookURL = srv.URL + "/api/webhooks/1/token"
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.URL = srv.URL + "/webhook"
+	cfg.Webhook.Headers = map[string]string{"X-Token": "env:PROBE_TOKEN"}
+	cfg.Teams.Enabled = true
+	cfg.Teams.WebhookURL = srv.URL + "/teams"
+	cfg.Email = stubEmailConfig(smtp)
+	cfg.Email.Auth = "plain"
+	cfg.Email.Username = "user"
+	cfg.Email.Password = "pass"
+	cfg.MQTT.Enabled = true
+	cfg.MQTT.Broker = broker.url()
+	cfg.MQTT.Topic = "t"
+
+	results := ProbeBackends(context.Background(), cfg)
+
+	want := map[string]bool{"ntfy": true, "discord": true, "webhook": true, "email": true, "teams": false, "mqtt": true}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), results)
+	}
+	for _, result := range results {
+		if result.OK != want[result.Backend] {
+			t.Errorf("%s: ok=%v error=%q, want ok=%v", result.Backend, result.OK, result.Error, want[result.Backend])
+		}
+	}
+	if teams := results[4]; teams.Backend != "teams" || !strings.Contains(teams.Error, "502") {
+		t.Errorf("unexpected teams result: %+v", teams)
+	}
+
+	for _, request := range requests {
+		if strings.HasPrefix(request, "POST") || strings.HasPrefix(request, "PUT") {
+			t.Errorf("probe published: %s", request)
+		}
+	}
+	smtp.mu.Lock()
+	defer smtp.mu.Unlock()
+	if smtp.data != "" || smtp.mailFrom != "" {
+		t.Errorf("probe sent mail: from=%q", smtp.mailFrom)
+	}
+	if len(smtp.authLines) == 0 {
+		t.Error("expected the probe to authenticate")
+	}
+	waitForMQTT(t, func() bool { return broker.snapshot().disconnects == 1 })
+	if state := broker.snapshot(); state.connects != 1 || len(state.published) != 0 {
+		t.Errorf("unexpected broker state: %+v", state)
+	}
+}
+
+func TestProbeBackends_ReportsRejectedCredentials(t *testing.T) {
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	broker := startMQTTBroker(t)
+	broker.connackCode = 4
+
+	cfg := config.DefaultConfig()
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+	cfg.MQTT.Enabled = true
+	cfg.MQTT.Broker = broker.url()
+	cfg.MQTT.Topic = "t"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results := ProbeBackends(ctx, cfg)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if ntfy := results[0]; ntfy.OK || !strings.Contains(ntfy.Error, "401") {
+		t.Errorf("unexpected ntfy result: %+v", ntfy)
+	}
+	if mqtt := results[1]; mqtt.OK || !strings.Contains(mqtt.Error, "bad username or password") {
+		t.Errorf("unexpected mqtt result: %+v", mqtt)
+	}
+}
+
+func TestProbeDBusNotifications_BoundedByContext(t *testing.T) {
+	bus := &fakeNotificationsBus{hang: true}
+	setupFakeDBus(t, bus, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := probeDBusNotifications(ctx); err == nil {
+		t.Fatal("probeDBusNotifications() error = nil, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("hung notification server held the probe for %v", elapsed)
+	}
+	if !bus.isClosed() {
+		t.Error("expected connection to be closed")
+	}
+}
@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// DeliveryReport describes how a notification was routed and what each
+// channel did with it.
+type DeliveryReport struct {
+	OperationID string `json:"operation_id"`
+	// Tier is the routing outcome: "focused", "active", "idle" or "direct".
+	Tier string `json:"tier"`
+	// Suppressed is set when the focused agent terminal swallowed the
+	// notification and no channel fired.
+	Suppressed bool  `json:"suppressed"`
+	UserIdle   bool  `json:"user_idle"`
+	IdleMs     int64 `json:"idle_ms"`
+	Focused    bool  `json:"focused"`
+	LocalSent  bool  `json:"local_sent"`
+	// LocalError is the system notification failure, if one was attempted.
+	LocalError string          `json:"local_error,omitempty"`
+	Backends   []BackendResult `json:"backends"`
+	// PartialFailure is set when some channels failed but at least one
+	// push backend delivered.
+	PartialFailure bool `json:"partial_failure"`
+	// RemoteFailed is set when the local notification fired but every push
+	// backend failed, so nothing reached a user away from this machine.
+	RemoteFailed bool  `json:"remote_failed"`
+	DurationMs   int64 `json:"duration_ms"`
+	// Plan is set for a dry run: what would have been sent. Nothing is.
+	Plan *DeliveryPlan `json:"plan,omitempty"`
+	// Explain lists, in order, the detection results and routing rules
+	// that decided the outcome.
+	Explain []string `json:"explain,omitempty"`
+}
+
+// DeliveryPlan is what a dry run would have sent.
+type DeliveryPlan struct {
+	Local    bool     `json:"local"`
+	Backends []string `json:"backends"`
+}
+
+// BackendResult is one push backend's outcome.
+type BackendResult struct {
+	Backend string `json:"backend"`
+	OK      bool   `json:"ok"`
+	// Attempts counts the deliveries tried: more than one when MQTT
+	// reconnects a dropped session or Discord posts anew after failing to
+	// edit a deleted message.
+	Attempts  int    `json:"attempts"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	// Skipped says why the self test could not exercise the channel on
+	// this machine; the result still counts as OK.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// finish records the overall outcome once dispatch has returned.
+func (r *DeliveryReport) finish(start time.Time, err error) {
+	r.DurationMs = time.Since(start).Milliseconds()
+	if r.Backends == nil {
+		r.Backends = []BackendResult{}
+	}
+	if err == nil {
+		return
+	}
+	for _, backend := range r.Backends {
+		if backend.OK {
+			r.PartialFailure = true
+			return
+		}
+	}
+	r.RemoteFailed = r.LocalSent
+}
+
+type attemptsKey struct{}
+
+// withAttemptCounter lets a backend report retries made within one send.
+func withAttemptCounter(ctx context.Context) (context.Context, *atomic.Int32) {
+	attempts := &atomic.Int32{}
+	attempts.Store(1)
+	return context.WithValue(ctx, attemptsKey{}, attempts), attempts
+}
+
+// countRetry records another attempt at the current send.
+func countRetry(ctx context.Context) {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*atomic.Int32); ok {
+		attempts.Add(1)
+	}
+}
@@ -0,0 +1,172 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestNotifyWithOptions_ReportsRoutingAndBackends(t *testing.T) {
+	state := setupStubs(t, 10*time.Minute, nil, false)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/webhook") {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.URL = srv.URL + "/webhook"
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
+
+	report, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "webhook") {
+		t.Fatalf("expected webhook error, got %v", err)
+	}
+	if !state.systemNotifyCalled {
+		t.Fatal("expected system notification")
+	}
+
+	if report.OperationID == "" {
+		t.Error("expected operation id in report")
+	}
+	if report.Tier != tierIdle || !report.UserIdle || report.IdleMs != (10*time.Minute).Milliseconds() {
+		t.Errorf("unexpected routing: tier=%q user_idle=%v idle_ms=%d", report.Tier, report.UserIdle, report.IdleMs)
+	}
+	if !report.LocalSent || report.LocalError != "" {
+		t.Errorf("expected local notification sent, got sent=%v error=%q", report.LocalSent, report.LocalError)
+	}
+	if !report.PartialFailure {
+		t.Error("expected partial failure with ntfy delivered and webhook failed")
+	}
+	if len(report.Backends) != 2 {
+		t.Fatalf("expected 2 backend results, got %+v", report.Backends)
+	}
+	ntfy, webhook := report.Backends[0], report.Backends[1]
+	if ntfy.Backend != "ntfy" || !ntfy.OK || ntfy.Attempts != 1 || ntfy.Error != "" {
+		t.Errorf("unexpected ntfy result: %+v", ntfy)
+	}
+	if webhook.Backend != "webhook" || webhook.OK || webhook.Attempts != 1 || !strings.Contains(webhook.Error, "502") {
+		t.Errorf("unexpected webhook result: %+v", webhook)
+	}
+}
+
+func TestNotifyRemote_ReportsSuppression(t *testing.T) {
+	setupStubs(t, 10*time.Second, nil, true)
+
+	report, err := NotifyRemote(testConfig(), Message{Title: "test", Body: "body", PID: 1234})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if report.Tier != tierFocused || !report.Suppressed || !report.Focused || report.LocalSent {
+		t.Errorf("unexpected report for a focused terminal: %+v", report)
+	}
+	if report.Backends == nil || len(report.Backends) != 0 {
+		t.Errorf("expected an empty backend list, got %#v", report.Backends)
+	}
+}
+
+func TestDeliveryReport_FinishFlagsPartialFailure(t *testing.T) {
+	failed := DeliveryReport{Backends: []BackendResult{{Backend: "ntfy", Error: "boom"}}}
+	failed.finish(time.Now(), errors.New("ntfy: boom"))
+	if failed.PartialFailure || failed.RemoteFailed {
+		t.Error("expected no partial or remote failure when nothing delivered")
+	}
+
+	partial := DeliveryReport{Backends: []BackendResult{{Backend: "ntfy", Error: "boom"}, {Backend: "discord", OK: true}}}
+	partial.finish(time.Now(), errors.New("ntfy: boom"))
+	if !partial.PartialFailure || partial.RemoteFailed {
+		t.Error("expected partial failure when one backend delivered")
+	}
+
+	local := DeliveryReport{LocalSent: true, Backends: []BackendResult{{Backend: "ntfy"}}}
+	local.finish(time.Now(), errors.New("ntfy: boom"))
+	if local.PartialFailure || !local.RemoteFailed {
+		t.Errorf("expected remote failure when only the local notification delivered, got %+v", local)
+	}
+
+	both := DeliveryReport{LocalSent: true, Backends: []BackendResult{{Backend: "ntfy", Error: "boom"}, {Backend: "discord", OK: true}}}
+	both.finish(time.Now(), errors.New("ntfy: boom"))
+	if !both.PartialFailure || both.RemoteFailed {
+		t.Errorf("expected partial failure when the local notification and one backend delivered, got %+v", both)
+	}
+}
+
+func TestAttemptCounter_CountsRetries(t *testing.T) {
+	countRetry(context.Background())
+
+	ctx, attempts := withAttemptCounter(context.Background())
+	countRetry(ctx)
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestNotifyWithOptions_DryRunSendsNothing(t *testing.T) {
+	state := setupStubs(t, 10*time.Minute, nil, false)
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+	httpClientFor = func(config.HTTPConfig) (*http.Client, error) { return srv.Client(), nil }
+
+	report, err := NotifyWithOptions(cfg, Message{Title: "test", Body: "body"}, NotifyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if hits != 0 || state.systemNotifyCalled {
+		t.Fatalf("dry run sent something: %d push requests, system notification %v", hits, state.systemNotifyCalled)
+	}
+	if report.Plan == nil || !report.Plan.Local || len(report.Plan.Backends) != 1 || report.Plan.Backends[0] != "ntfy" {
+		t.Fatalf("unexpected plan: %+v", report.Plan)
+	}
+	if report.Tier != tierIdle || report.LocalSent || len(report.Backends) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	explain := strings.Join(report.Explain, "\n")
+	for _, want := range []string{"user is idle", "not focused", "enabled push backends: ntfy", "disabled in config: discord"} {
+		if !strings.Contains(explain, want) {
+			t.Errorf("explain missing %q:\n%s", want, explain)
+		}
+	}
+}
+
+func TestNotifyWithOptions_ExplainsSuppression(t *testing.T) {
+	setupStubs(t, 10*time.Second, nil, true)
+
+	report, err := NotifyWithOptions(testConfig(), Message{Title: "test", Body: "body"}, NotifyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !report.Suppressed || report.Plan.Local || len(report.Plan.Backends) != 0 {
+		t.Fatalf("expected a suppressed, empty plan, got %+v (plan %+v)", report, report.Plan)
+	}
+	explain := strings.Join(report.Explain, "\n")
+	for _, want := range []string{"under the 5m0s threshold", "terminal is focused", "suppressed"} {
+		if !strings.Contains(explain, want) {
+			t.Errorf("explain missing %q:\n%s", want, explain)
+		}
+	}
+}
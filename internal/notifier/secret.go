@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// resolveSecret resolves a secret-valued config field at send time:
+// "env:NAME" reads an environment variable, "file:/path" a file,
+// "cmd:command" the output of a shell command and "keyring:service/account"
+// the OS keyring. "literal:" escapes a value that starts like a reference;
+// other values are used as-is. File contents and command output are
+// trimmed. Errors name the reference, never the resolved value.
+func resolveSecret(ctx context.Context, value string) (string, error) {
+	if literal, ok := strings.CutPrefix(value, config.LiteralPrefix); ok {
+		return literal, nil
+	}
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	switch scheme {
+	case "env":
+		resolved, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return resolved, nil
+	case "file":
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "cmd":
+		return runSecretCommand(ctx, ref)
+	case "keyring":
+		service, account, ok := strings.Cut(ref, "/")
+		if !ok || service == "" || account == "" {
+			return "", fmt.Errorf("keyring reference %q must be keyring:service/account", value)
+		}
+		if runtime.GOOS != "linux" {
+			return "", fmt.Errorf("keyring references are not supported on %s", runtime.GOOS)
+		}
+		return keyringSecret(ctx, service, account)
+	}
+	return value, nil
+}
+
+// secretCommandStderrMax bounds how much of a secret command's standard
+// error is kept for its error message.
+const secretCommandStderrMax = 512
+
+// runSecretCommand runs command with the platform shell and returns its
+// trimmed standard output, e.g. "pass show ntfy". On failure the error
+// carries the start of standard error, never standard output.
+func runSecretCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	stderr := &boundedBuffer{max: secretCommandStderrMax}
+	cmd.Stdout = &stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("secret command %q: %w%s", command, err, stderr.detail())
+	}
+	resolved := strings.TrimSpace(stdout.String())
+	if resolved == "" {
+		return "", fmt.Errorf("secret command %q printed nothing%s", command, stderr.detail())
+	}
+	return resolved, nil
+}
+
+// boundedBuffer keeps the first max bytes written to it and discards the
+// rest, so a chatty command cannot grow an error message without bound.
+type boundedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// detail formats the captured output for an error message: ": <output>",
+// or nothing when there was none.
+func (b *boundedBuffer) detail() string {
+	output := strings.TrimSpace(strings.ToValidUTF8(b.buf.String(), ""))
+	if output == "" {
+		return ""
+	}
+	return ": " + output
+}
+
+// resolveSecrets returns a backend config with every string field tagged
+// secret:"true" resolved by resolveSecret.
+func resolveSecrets[T any](ctx context.Context, cfg T) (T, error) {
+	v := reflect.ValueOf(&cfg).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Tag.Get("secret") != "true" || field.Type.Kind() != reflect.String {
+			continue
+		}
+		resolved, err := resolveSecret(ctx, v.Field(i).String())
+		if err != nil {
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			return cfg, fmt.Errorf("%s: %w", name, err)
+		}
+		v.Field(i).SetString(resolved)
+	}
+	return cfg, nil
+}
+
+// withoutURL drops the request URL from a transport error, for backends
+// whose URL is itself the secret, such as Discord and Teams webhooks. Parse
+// errors carry the URL the same way.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
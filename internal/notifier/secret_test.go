@@ -0,0 +1,220 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Digni/ding-ding/internal/config"
+	"github.com/godbus/dbus/v5"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("DING_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "literal-token", want: "literal-token"},
+		{value: "https://discord.com/api/webhooks/1/abc", want: "https://discord.com/api/webhooks/1/abc"},
+		{value: "env:DING_TEST_SECRET", want: "from-env"},
+		{value: "file:" + secretFile, want: "from-file"},
+		{value: "literal:env:DING_TEST_SECRET", want: "env:DING_TEST_SECRET"},
+		{value: "literal:cmd:rm -rf /", want: "cmd:rm -rf /"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			value string
+			want  string
+		}{value: "cmd:printf ' from-cmd\\n'", want: "from-cmd"})
+	}
+	for _, tt := range tests {
+		got, err := resolveSecret(context.Background(), tt.value)
+		if err != nil {
+			t.Errorf("resolveSecret(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveSecret(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSecret_ErrorsNameReferenceOnly(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "env:DING_TEST_UNSET", want: "environment variable DING_TEST_UNSET is not set"},
+		{value: "keyring:ding-ding", want: "must be keyring:service/account"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests,
+			struct{ value, want string }{value: "cmd:printf lea; printf ked; exit 3", want: "exit status 3"},
+			struct{ value, want string }{value: "cmd:true", want: "printed nothing"},
+			struct{ value, want string }{value: "cmd:printf lea; printf ked; echo 'pass: store locked' >&2; exit 1", want: "exit status 1: pass: store locked"},
+		)
+	}
+	for _, tt := range tests {
+		_, err := resolveSecret(context.Background(), tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("resolveSecret(%q) error = %v, want %q", tt.value, err, tt.want)
+			continue
+		}
+		if strings.Contains(err.Error(), "leaked") {
+			t.Errorf("resolveSecret(%q) error includes command output: %v", tt.value, err)
+		}
+	}
+}
+
+func TestRunSecretCommand_BoundsStderr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	_, err := runSecretCommand(context.Background(), "head -c 4096 /dev/zero | tr '\\0' x >&2; exit 1")
+	if err == nil {
+		t.Fatal("runSecretCommand() error = nil, want failure")
+	}
+	kept := strings.Repeat("x", secretCommandStderrMax)
+	if !strings.HasSuffix(err.Error(), ": "+kept) {
+		t.Errorf("error = %.80q..., want it to end with %d bytes of stderr", err, secretCommandStderrMax)
+	}
+}
+
+func TestResolveSecrets_OnlyTaggedFields(t *testing.T) {
+	t.Setenv("DING_TEST_TOKEN", "tk_live")
+	cfg := config.NtfyConfig{Topic: "env:DING_TEST_TOKEN", Token: "env:DING_TEST_TOKEN"}
+
+	got, err := resolveSecrets(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("resolveSecrets() error = %v", err)
+	}
+	if got.Token != "tk_live" || got.Topic != "env:DING_TEST_TOKEN" {
+		t.Errorf("resolveSecrets() token/topic = %q/%q", got.Token, got.Topic)
+	}
+
+	cfg.Password = "env:DING_TEST_UNSET"
+	if _, err := resolveSecrets(context.Background(), cfg); err == nil || !strings.HasPrefix(err.Error(), "password: ") {
+		t.Errorf("resolveSecrets() error = %v, want it to name the password field", err)
+	}
+}
+
+func TestSendNtfy_ResolvesTokenReference(t *testing.T) {
+	t.Setenv("DING_TEST_NTFY_TOKEN", "tk_from_env")
+	var gotAuth string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.NtfyConfig{Server: srv.URL, Topic: "topic", Token: "env:DING_TEST_NTFY_TOKEN"}
+	if err := sendNtfy(context.Background(), cfg, Message{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("sendNtfy() error = %v", err)
+	}
+	if gotAuth != "Bearer tk_from_env" {
+		t.Errorf("Authorization = %q, want Bearer tk_from_env", gotAuth)
+	}
+}
+
+func TestSendDiscord_ErrorOmitsWebhookURL(t *testing.T) {
+	t.Setenv("DING_TEST_DISCORD_URL", "http://127.0.0.1:1/api/webhooks/1/s3cr3t")
+	cfg := config.DiscordConfig{WebhookURL: "env:DING_TEST_DISCORD_URL"}
+
+	err := sendDiscord(context.Background(), cfg, Message{Title: "t", Body: "b"})
+	if err == nil {
+		t.Fatal("sendDiscord() error = nil, want connection error")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("sendDiscord() error leaks the webhook token: %v", err)
+	}
+}
+
+// fakeSecretService answers Secret Service calls from a map of
+// "service/account" to secret.
+type fakeSecretService struct {
+	items  map[string]string
+	locked bool
+	calls  []string
+	closed bool
+}
+
+func (f *fakeSecretService) Call(_ context.Context, path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	f.calls = append(f.calls, method)
+	switch method {
+	case secretServiceOpen:
+		return &dbus.Call{Body: []interface{}{dbus.MakeVariant(""), dbus.ObjectPath("/org/freedesktop/secrets/session/1")}}
+	case secretServiceSearch:
+		attrs := args[0].(map[string]string)
+		key := attrs["service"] + "/" + attrs["username"]
+		var found []dbus.ObjectPath
+		if _, ok := f.items[key]; ok {
+			found = []dbus.ObjectPath{dbus.ObjectPath("/org/freedesktop/secrets/collection/login/" + strings.ReplaceAll(key, "/", "_"))}
+		}
+		if f.locked {
+			return &dbus.Call{Body: []interface{}{[]dbus.ObjectPath{}, found}}
+		}
+		return &dbus.Call{Body: []interface{}{found, []dbus.ObjectPath{}}}
+	case secretItemGetSecret:
+		for key, value := range f.items {
+			if strings.HasSuffix(string(path), strings.ReplaceAll(key, "/", "_")) {
+				return &dbus.Call{Body: []interface{}{secretServiceSecret{
+					Session:     args[0].(dbus.ObjectPath),
+					Value:       []byte(value),
+					ContentType: "text/plain",
+				}}}
+			}
+		}
+		return &dbus.Call{Err: errors.New("no such object")}
+	}
+	return &dbus.Call{}
+}
+
+func (f *fakeSecretService) Close() error {
+	f.closed = true
+	return nil
+}
+
+func setupFakeSecretService(t *testing.T, fake *fakeSecretService) {
+	t.Helper()
+	origConnect := secretServiceConnect
+	t.Cleanup(func() { secretServiceConnect = origConnect })
+	secretServiceConnect = func() (secretServiceBus, error) { return fake, nil }
+}
+
+func TestKeyringSecret_ReadsSecretService(t *testing.T) {
+	fake := &fakeSecretService{items: map[string]string{"ding-ding/ntfy": "tk_keyring"}}
+	setupFakeSecretService(t, fake)
+
+	got, err := keyringSecret(context.Background(), "ding-ding", "ntfy")
+	if err != nil {
+		t.Fatalf("keyringSecret() error = %v", err)
+	}
+	if got != "tk_keyring" {
+		t.Errorf("keyringSecret() = %q, want tk_keyring", got)
+	}
+	if last := fake.calls[len(fake.calls)-1]; last != secretSessionClose || !fake.closed {
+		t.Errorf("session not closed: calls %v, bus closed %v", fake.calls, fake.closed)
+	}
+}
+
+func TestKeyringSecret_MissingAndLockedItems(t *testing.T) {
+	setupFakeSecretService(t, &fakeSecretService{items: map[string]string{}})
+	if _, err := keyringSecret(context.Background(), "ding-ding", "ntfy"); err == nil || !strings.Contains(err.Error(), "ding-ding/ntfy not found") {
+		t.Errorf("missing item error = %v", err)
+	}
+
+	setupFakeSecretService(t, &fakeSecretService{items: map[string]string{"ding-ding/ntfy": "tk"}, locked: true})
+	if _, err := keyringSecret(context.Background(), "ding-ding", "ntfy"); err == nil || !strings.Contains(err.Error(), "is locked") {
+		t.Errorf("locked item error = %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// playSoundFunc plays the notification sound for the sound channel. Tests
+// replace it to run silently.
+var playSoundFunc = playNotificationSound
+
+// errNoSoundPlayer means this machine has nothing to play the sound with,
+// as on most headless hosts. The self test skips the sound channel then.
+var errNoSoundPlayer = errors.New("no sound player found")
+
+// SelfTestChannels are the channels SelfTest can exercise, in test order.
+var SelfTestChannels = slices.Concat([]string{"local", "sound"}, pushBackendNames)
+
+// SelfTest sends a labelled test message through each channel on its own,
+// one after another: the local notification, the notification sound, then
+// every enabled push backend. Idle and focus routing is skipped. A non-empty
+// channel tests only that channel, which must be enabled. The sound channel is
+// reported as skipped, not failed, when no sound player is installed.
+func SelfTest(ctx context.Context, cfg config.Config, channel string) ([]BackendResult, error) {
+	targets := selfTestTargets(cfg.WithBackendHTTP())
+	if channel != "" {
+		i := slices.IndexFunc(targets, func(target pushTarget) bool { return target.label == channel })
+		switch {
+		case i >= 0:
+			targets = targets[i : i+1]
+		case slices.Contains(SelfTestChannels, channel):
+			return nil, fmt.Errorf("%s is not enabled in the config", channel)
+		default:
+			return nil, fmt.Errorf("unknown channel %q (want %s)", channel, strings.Join(SelfTestChannels, ", "))
+		}
+	}
+
+	logger := DefaultLoggerFunc()
+	results := make([]BackendResult, 0, len(targets))
+	for _, target := range targets {
+		sendCtx, cancel := withDeliveryDeadline(ctx, cfg)
+		result, err := runTarget(sendCtx, target)
+		cancel()
+		switch {
+		case errors.Is(err, errNoSoundPlayer):
+			result.OK, result.Skipped, result.Error = true, result.Error, ""
+			logger.Info("notifier.selftest.skipped", "channel", target.label, "reason", result.Skipped)
+		case err != nil:
+			logger.Warn("notifier.selftest.failed", "channel", target.label, "latency_ms", result.LatencyMs, "error", err)
+		default:
+			logger.Info("notifier.selftest.sent", "channel", target.label, "latency_ms", result.LatencyMs)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func selfTestTargets(cfg config.Config) []pushTarget {
+	targets := []pushTarget{{
+		label: "local",
+		send: func(ctx context.Context) error {
+			return sendSystemNotification(ctx, cfg.Notification, selfTestMessage("Test message via the local notification."))
+		},
+	}}
+	if cfg.Sound.Enabled {
+		targets = append(targets, pushTarget{label: "sound", send: playSoundFunc})
+	}
+	// Each backend gets a message naming it, so arrivals are easy to match.
+	for _, backend := range pushBackends(cfg) {
+		targets = append(targets, backend.target(selfTestMessage(fmt.Sprintf("Test message via %s.", backend.label))))
+	}
+	return targets
+}
+
+func selfTestMessage(body string) Message {
+	return Message{Title: "ding-ding test", Body: body, Event: EventCompleted}
+}
+
+// playNotificationSound plays the platform's notification sound: the
+// freedesktop message-new-instant sound on Linux, Glass on macOS and the
+// default notification sound on Windows. It returns errNoSoundPlayer when
+// Linux has neither canberra-gtk-play nor paplay, or the platform has no
+// player at all.
+func playNotificationSound(ctx context.Context) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		switch {
+		case hasCommand("canberra-gtk-play"):
+			cmd = exec.CommandContext(ctx, "canberra-gtk-play", "--id", "message-new-instant")
+		case hasCommand("paplay"):
+			cmd = exec.CommandContext(ctx, "paplay", "/usr/share/sounds/freedesktop/stereo/message-new-instant.oga")
+		default:
+			return fmt.Errorf("%w (install canberra-gtk-play or paplay)", errNoSoundPlayer)
+		}
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "afplay", "/System/Library/Sounds/Glass.aiff")
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-Command",
+			`(New-Object Media.SoundPlayer "$env:WINDIR\Media\Windows Notify System Generic.wav").PlaySync()`)
+	default:
+		return fmt.Errorf("%w on %s", errNoSoundPlayer, runtime.GOOS)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("play notification sound with %s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfTest_SendsThroughEachChannel(t *testing.T) {
+	state := setupStubs(t, 0, nil, true)
+
+	var bodies []string
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.URL.Path+": "+string(body))
+		if r.URL.Path == "/webhook" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := testConfig()
+	cfg.Sound.Enabled = true
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = srv.URL
+	cfg.Ntfy.Topic = "test"
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.URL = srv.URL + "/webhook"
+
+	results, err := SelfTest(context.Background(), cfg, "")
+	if err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+
+	var got []string
+	for _, result := range results {
+		got = append(got, result.Backend)
+	}
+	if strings.Join(got, ",") != "local,sound,ntfy,webhook" {
+		t.Fatalf("channels = %v, want local,sound,ntfy,webhook", got)
+	}
+	for _, result := range results[:3] {
+		if !result.OK || result.Attempts != 1 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	}
+	if webhook := results[3]; webhook.OK || !strings.Contains(webhook.Error, "500") {
+		t.Errorf("unexpected webhook result: %+v", webhook)
+	}
+
+	// Focus would suppress a real notification; the self test ignores it.
+	if state.systemNotifyCalls != 1 || state.soundPlays != 1 {
+		t.Errorf("system notifications = %d, sounds = %d, want 1 each", state.systemNotifyCalls, state.soundPlays)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], "via ntfy") || !strings.Contains(bodies[1], "via webhook") {
+		t.Errorf("push bodies = %q", bodies)
+	}
+}
+
+func TestSelfTest_SingleChannel(t *testing.T) {
+	state := setupStubs(t, 10*time.Minute, nil, false)
+	cfg := testConfig()
+
+	results, err := SelfTest(context.Background(), cfg, "local")
+	if err != nil || len(results) != 1 || results[0].Backend != "local" || !results[0].OK {
+		t.Fatalf("SelfTest(local) = %+v, %v", results, err)
+	}
+	if state.systemNotifyCalls != 1 {
+		t.Errorf("system notifications = %d, want 1", state.systemNotifyCalls)
+	}
+
+	if _, err := SelfTest(context.Background(), cfg, "discord"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("SelfTest(discord) error = %v, want not enabled", err)
+	}
+	if _, err := SelfTest(context.Background(), cfg, "pager"); err == nil || !strings.Contains(err.Error(), "unknown channel") {
+		t.Errorf("SelfTest(pager) error = %v, want unknown channel", err)
+	}
+
+	cfg.Sound.Enabled = false
+	if _, err := SelfTest(context.Background(), cfg, "sound"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("SelfTest(sound) error = %v, want not enabled", err)
+	}
+}
+
+func TestSelfTest_SkipsSoundWithoutPlayer(t *testing.T) {
+	setupStubs(t, 0, nil, false)
+	playSoundFunc = func(context.Context) error {
+		return fmt.Errorf("%w (install canberra-gtk-play or paplay)", errNoSoundPlayer)
+	}
+	cfg := testConfig()
+	cfg.Sound.Enabled = true
+
+	results, err := SelfTest(context.Background(), cfg, "sound")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("SelfTest(sound) = %+v, %v", results, err)
+	}
+	if got := results[0]; !got.OK || got.Error != "" || got.Skipped != "no sound player found (install canberra-gtk-play or paplay)" {
+		t.Errorf("sound result = %+v, want OK and skipped", got)
+	}
+
+	playSoundFunc = func(context.Context) error { return errors.New("play notification sound with paplay: exit status 1") }
+	results, err = SelfTest(context.Background(), cfg, "sound")
+	if err != nil || len(results) != 1 || results[0].OK || results[0].Skipped != "" {
+		t.Errorf("SelfTest(sound) with a failing player = %+v, %v, want failure", results, err)
+	}
+}
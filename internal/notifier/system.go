@@ -1,10 +1,13 @@
 package notifier
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"runtime"
 	"strings"
+
+	"github.com/Digni/ding-ding/internal/config"
 )
 
 func xmlEscape(s string) string {
@@ -16,27 +19,140 @@ func xmlEscape(s string) string {
 	return s
 }
 
-func systemNotify(title, body string) error {
+// SystemNotification is a local/system notification and its display hints.
+// Hints that a platform cannot express are ignored there.
+type SystemNotification struct {
+	Title string
+	Body  string
+	// Urgency is low, normal or critical.
+	Urgency string
+	// ExpireTimeoutMs follows the freedesktop convention: -1 lets the
+	// notification server decide, 0 never expires.
+	ExpireTimeoutMs int
+	Icon            string
+	Category        string
+	// Group is the replace-in-place key; notifications sharing it replace
+	// each other where the platform supports it.
+	Group string
+	// Scenario is the Windows toast scenario (reminder, alarm, urgent, ...).
+	Scenario string
+	// URL is opened when the notification is clicked; Actions become buttons.
+	URL     string
+	Actions []Action
+}
+
+func newSystemNotification(cfg config.NotificationConfig, msg Message) SystemNotification {
+	style := styleFor(msg.Event)
+
+	// The configured icon wins; otherwise the event picks a themed icon.
+	icon := cfg.Icon
+	if icon == "" {
+		icon = style.icon
+	}
+
+	// An explicit low/critical urgency is respected; the default "normal"
+	// follows the event so blocked or failed agents stand out.
+	urgency := cfg.Urgency
+	if urgency == "" || strings.EqualFold(urgency, "normal") {
+		switch {
+		case style.urgent:
+			urgency = "critical"
+		case style.quiet:
+			urgency = "low"
+		}
+	}
+	// A per-message priority overrides both the configured urgency and the
+	// event adjustment.
+	if mapped, ok := mapPriority(cfg.PriorityUrgency, msg.Priority); ok {
+		urgency = mapped
+	}
+	scenario, _ := mapPriority(cfg.PriorityScenario, msg.Priority)
+
+	// Name the agent and repository so notifications from parallel sessions
+	// are distinguishable at a glance; a title template owns the whole title.
+	title := msg.Title
+	if label := contextLabel(msg); label != "" && cfg.Template.Title == "" {
+		title = fmt.Sprintf("%s (%s)", msg.Title, label)
+	}
+
+	return SystemNotification{
+		Title:           title,
+		Body:            msg.Body,
+		Urgency:         urgency,
+		ExpireTimeoutMs: cfg.ExpireTimeoutMs,
+		Icon:            icon,
+		Category:        cfg.Category,
+		Group:           msg.group,
+		Scenario:        scenario,
+		URL:             msg.URL,
+		Actions:         msg.Actions,
+	}
+}
+
+func sendSystemNotification(ctx context.Context, cfg config.NotificationConfig, msg Message) error {
+	msg, err := applyTemplate("notification", cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+	return SystemNotifyFunc(ctx, newSystemNotification(cfg, msg))
+}
+
+func systemNotify(ctx context.Context, n SystemNotification) error {
+	title, body := n.Title, n.Body
+
 	switch runtime.GOOS {
 	case "linux":
-		return exec.Command("notify-send", "--app-name=ding-ding", title, body).Run()
+		return linuxNotify(ctx, n)
 	case "darwin":
 		script := `display notification (item 1 of argv) with title (item 2 of argv)`
-		return exec.Command("osascript", "-e", "on run argv", "-e", script, "-e", "end run", body, title).Run()
+		return exec.CommandContext(ctx, "osascript", "-e", "on run argv", "-e", script, "-e", "end run", body, title).Run()
 	case "windows":
 		escTitle := strings.ReplaceAll(xmlEscape(title), "%", "%%")
 		escBody := strings.ReplaceAll(xmlEscape(body), "%", "%%")
+		tagLine := ""
+		if tag := groupTag(n.Group); tag != "" {
+			// Toasts with the same Tag and Group replace each other in Action Center.
+			tagLine = fmt.Sprintf("$toast.Tag = '%s'\n$toast.Group = 'ding-ding'\n", tag)
+		}
 		ps := fmt.Sprintf(`
 [Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
 [Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
-$template = '<toast><visual><binding template="ToastText02"><text id="1">%s</text><text id="2">%s</text></binding></visual></toast>'
+$template = '%s'
 $xml = New-Object Windows.Data.Xml.Dom.XmlDocument
 $xml.LoadXml($template)
 $toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
-[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("ding-ding").Show($toast)
-`, escTitle, escBody)
-		return exec.Command("powershell", "-Command", ps).Run()
+%s[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("ding-ding").Show($toast)
+`, toastXML(n, escTitle, escBody), tagLine)
+		return exec.CommandContext(ctx, "powershell", "-Command", ps).Run()
 	default:
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
 }
+
+// toastXML builds the Windows toast document. Links use protocol
+// activation, so Windows opens them in the default browser without a
+// running ding-ding process.
+func toastXML(n SystemNotification, escTitle, escBody string) string {
+	attrs := ""
+	if n.Scenario != "" && n.Scenario != "default" {
+		attrs += fmt.Sprintf(` scenario="%s"`, xmlEscape(n.Scenario))
+	}
+	if n.URL != "" {
+		attrs += fmt.Sprintf(` activationType="protocol" launch="%s"`, xmlEscape(n.URL))
+	}
+
+	actions := ""
+	if len(n.Actions) > 0 {
+		var b strings.Builder
+		b.WriteString("<actions>")
+		for _, action := range n.Actions {
+			fmt.Fprintf(&b, `<action content="%s" activationType="protocol" arguments="%s"/>`,
+				xmlEscape(action.Label), xmlEscape(action.URL))
+		}
+		b.WriteString("</actions>")
+		actions = b.String()
+	}
+
+	return fmt.Sprintf(`<toast%s><visual><binding template="ToastText02"><text id="1">%s</text><text id="2">%s</text></binding></visual>%s</toast>`,
+		attrs, escTitle, escBody, actions)
+}
@@ -1,6 +1,9 @@
 package notifier
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestXmlEscape(t *testing.T) {
 	tests := []struct {
@@ -64,3 +67,22 @@ func TestXmlEscape(t *testing.T) {
 		})
 	}
 }
+
+func TestToastXML_Links(t *testing.T) {
+	got := toastXML(SystemNotification{
+		URL:     "https://example.com/run?a=1&b=2",
+		Actions: []Action{{Label: `Say "hi"`, URL: "https://example.com/hi"}},
+	}, "title", "body")
+
+	want := `<toast activationType="protocol" launch="https://example.com/run?a=1&amp;b=2">` +
+		`<visual><binding template="ToastText02"><text id="1">title</text><text id="2">body</text></binding></visual>` +
+		`<actions><action content="Say &quot;hi&quot;" activationType="protocol" arguments="https://example.com/hi"/></actions></toast>`
+	if got != want {
+		t.Errorf("toastXML() =\n%s\nwant\n%s", got, want)
+	}
+
+	plain := toastXML(SystemNotification{Scenario: "urgent"}, "t", "b")
+	if strings.Contains(plain, "actions") || strings.Contains(plain, "launch") || !strings.Contains(plain, `<toast scenario="urgent">`) {
+		t.Errorf("toastXML() without links = %s", plain)
+	}
+}
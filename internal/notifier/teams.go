@@ -0,0 +1,171 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// maxErrorBodyBytes bounds how much of a failed response body is quoted in
+// the returned error.
+const maxErrorBodyBytes = 512
+
+type teamsEnvelope struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string            `json:"contentType"`
+	ContentURL  *string           `json:"contentUrl"`
+	Content     teamsAdaptiveCard `json:"content"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []map[string]any `json:"body"`
+	Actions []map[string]any `json:"actions,omitempty"`
+}
+
+func buildTeamsPayload(cfg config.TeamsConfig, msg Message) teamsEnvelope {
+	style := styleFor(msg.Event)
+	titleBlock := map[string]any{
+		"type":   "TextBlock",
+		"text":   msg.Title,
+		"weight": "Bolder",
+		"size":   "Medium",
+		"wrap":   true,
+	}
+	if style.emoji != "" {
+		titleBlock["text"] = style.emoji + " " + msg.Title
+	}
+	if style.teamsColor != "" {
+		titleBlock["color"] = style.teamsColor
+	}
+	body := []map[string]any{titleBlock}
+
+	var facts []map[string]string
+	if msg.Agent != "" {
+		facts = append(facts, map[string]string{"title": "Agent", "value": msg.Agent})
+	}
+	if msg.Event != "" {
+		facts = append(facts, map[string]string{"title": "Event", "value": string(msg.Event)})
+	}
+	if msg.ExitCode != nil {
+		facts = append(facts, map[string]string{"title": "Exit code", "value": strconv.Itoa(*msg.ExitCode)})
+	}
+	if where := workspaceLabel(msg); where != "" {
+		facts = append(facts, map[string]string{"title": "Repository", "value": where})
+	}
+	if msg.Hostname != "" {
+		facts = append(facts, map[string]string{"title": "Host", "value": msg.Hostname})
+	}
+	if msg.Cwd != "" {
+		facts = append(facts, map[string]string{"title": "Directory", "value": msg.Cwd})
+	}
+	if len(facts) > 0 {
+		body = append(body, map[string]any{
+			"type":  "FactSet",
+			"facts": facts,
+		})
+	}
+
+	if msg.Body != "" {
+		body = append(body, map[string]any{
+			"type": "TextBlock",
+			"text": msg.Body,
+			"wrap": true,
+		})
+	}
+
+	card := teamsAdaptiveCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    body,
+	}
+
+	for _, action := range linkButtons(msg) {
+		card.Actions = append(card.Actions, map[string]any{"type": "Action.OpenUrl", "title": action.Label, "url": action.URL})
+	}
+	if cfg.ActionURL != "" {
+		title := cfg.ActionTitle
+		if title == "" {
+			title = "Open"
+		}
+		card.Actions = append(card.Actions, map[string]any{"type": "Action.OpenUrl", "title": title, "url": cfg.ActionURL})
+	}
+
+	return teamsEnvelope{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: card},
+		},
+	}
+}
+
+func sendTeams(ctx context.Context, cfg config.TeamsConfig, msg Message) error {
+	cfg, err := resolveSecrets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	msg, err = applyTemplate("teams", cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(buildTeamsPayload(cfg, msg))
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := httpClientFor(cfg.HTTP)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if detail := readErrorBody(resp.Body); detail != "" {
+			return fmt.Errorf("teams returned status %d: %s", resp.StatusCode, detail)
+		}
+		return fmt.Errorf("teams returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// readErrorBody returns a trimmed, single-line excerpt of a failed response
+// body so misconfigured endpoints are diagnosable from the error alone.
+func readErrorBody(r io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(r, maxErrorBodyBytes+1))
+	truncated := len(data) > maxErrorBodyBytes
+	if truncated {
+		data = data[:maxErrorBodyBytes]
+	}
+
+	detail := strings.Join(strings.Fields(string(data)), " ")
+	if truncated && detail != "" {
+		detail += "…"
+	}
+	return detail
+}
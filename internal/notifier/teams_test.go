@@ -0,0 +1,111 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestSendTeams_AdaptiveCard(t *testing.T) {
+	var gotContentType string
+	var gotPayload map[string]any
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	cfg := config.TeamsConfig{WebhookURL: srv.URL, ActionURL: "https://example.com/run/1", ActionTitle: "View run"}
+	msg := Message{Title: "hello", Body: "world", Agent: "claude"}
+
+	if err := sendTeams(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if gotContentType != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", gotContentType)
+	}
+	if gotPayload["type"] != "message" {
+		t.Errorf("expected envelope type message, got %v", gotPayload["type"])
+	}
+
+	attachments, _ := gotPayload["attachments"].([]any)
+	if len(attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(attachments))
+	}
+	attachment := attachments[0].(map[string]any)
+	if attachment["contentType"] != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("unexpected contentType %v", attachment["contentType"])
+	}
+
+	card := attachment["content"].(map[string]any)
+	if card["type"] != "AdaptiveCard" {
+		t.Errorf("expected AdaptiveCard, got %v", card["type"])
+	}
+
+	body := card["body"].([]any)
+	if len(body) != 3 {
+		t.Fatalf("expected title, fact set and body blocks, got %d", len(body))
+	}
+	if title := body[0].(map[string]any)["text"]; title != "hello" {
+		t.Errorf("expected title block %q, got %v", "hello", title)
+	}
+	facts := body[1].(map[string]any)["facts"].([]any)
+	fact := facts[0].(map[string]any)
+	if fact["title"] != "Agent" || fact["value"] != "claude" {
+		t.Errorf("unexpected agent fact %v", fact)
+	}
+	if text := body[2].(map[string]any)["text"]; text != "world" {
+		t.Errorf("expected body block %q, got %v", "world", text)
+	}
+
+	actions := card["actions"].([]any)
+	action := actions[0].(map[string]any)
+	if action["type"] != "Action.OpenUrl" || action["url"] != cfg.ActionURL || action["title"] != "View run" {
+		t.Errorf("unexpected action %v", action)
+	}
+}
+
+func TestBuildTeamsPayload_OmitsOptionalBlocks(t *testing.T) {
+	payload := buildTeamsPayload(config.TeamsConfig{}, Message{Title: "only title"})
+	card := payload.Attachments[0].Content
+
+	if len(card.Body) != 1 {
+		t.Errorf("expected only the title block, got %d blocks", len(card.Body))
+	}
+	if len(card.Actions) != 0 {
+		t.Errorf("expected no actions without action_url, got %v", card.Actions)
+	}
+}
+
+func TestSendTeams_ErrorIncludesResponseBody(t *testing.T) {
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, "Webhook message delivery failed\nwith error: card schema invalid")
+	})
+
+	err := sendTeams(context.Background(), config.TeamsConfig{WebhookURL: srv.URL}, Message{Title: "t", Body: "b"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	want := "teams returned status 400: Webhook message delivery failed with error: card schema invalid"
+	if err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+}
+
+func TestReadErrorBody_Truncates(t *testing.T) {
+	got := readErrorBody(strings.NewReader(strings.Repeat("x", maxErrorBodyBytes+100)))
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("expected truncation marker, got suffix %q", got[len(got)-5:])
+	}
+	if len(got) != maxErrorBodyBytes+len("…") {
+		t.Errorf("expected %d bytes, got %d", maxErrorBodyBytes+len("…"), len(got))
+	}
+}
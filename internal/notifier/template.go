@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+// Routing tiers exposed to templates as .Tier.
+const (
+	tierFocused = "focused" // forced push while the agent terminal is focused
+	tierActive  = "active"  // user active in another window
+	tierIdle    = "idle"    // user idle past the threshold
+	tierDirect  = "direct"  // Push, which bypasses routing
+)
+
+var templateGetwd = os.Getwd
+var templateNow = time.Now
+
+// templateData exposes the message to templates. Its keys are exactly
+// config.TemplateFields, which config validation executes templates against.
+func templateData(msg Message) map[string]any {
+	hostname := msg.Hostname
+	if hostname == "" {
+		hostname, _ = hostnameFunc()
+	}
+	cwd := msg.Cwd
+	if cwd == "" {
+		cwd, _ = templateGetwd()
+	}
+
+	var exitCode any
+	if msg.ExitCode != nil {
+		exitCode = *msg.ExitCode
+	}
+	var duration any
+	if msg.DurationMs > 0 {
+		duration = msg.duration()
+	}
+
+	return map[string]any{
+		"Title":       msg.Title,
+		"Body":        msg.Body,
+		"Agent":       msg.Agent,
+		"PID":         msg.PID,
+		"RequestID":   msg.RequestID,
+		"OperationID": msg.OperationID,
+		"Session":     msg.Session,
+		"Event":       string(msg.Event),
+		"ExitCode":    exitCode,
+		"Duration":    duration,
+		"Priority":    string(msg.Priority),
+		"URL":         msg.URL,
+		"Actions":     msg.Actions,
+		"Replies":     msg.Replies,
+		"Hostname":    hostname,
+		"Cwd":         cwd,
+		"Repo":        msg.Repo,
+		"Branch":      msg.Branch,
+		"Timestamp":   templateNow(),
+		"Tier":        msg.tier,
+	}
+}
+
+func renderTemplate(field, text string, msg Message) (string, error) {
+	tmpl, err := config.ParseMessageTemplate(field, text)
+	if err != nil {
+		return "", fmt.Errorf("parse %s template: %w", field, err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, templateData(msg)); err != nil {
+		return "", fmt.Errorf("render %s template: %w", field, err)
+	}
+	return out.String(), nil
+}
+
+// applyTemplate returns msg with its title and body replaced by the
+// section's configured templates. Both render against the original message.
+func applyTemplate(section string, tmpl config.TemplateConfig, msg Message) (Message, error) {
+	out := msg
+	if tmpl.Title != "" {
+		title, err := renderTemplate(section+".template.title", tmpl.Title, msg)
+		if err != nil {
+			return msg, err
+		}
+		out.Title = title
+	}
+	if tmpl.Body != "" {
+		body, err := renderTemplate(section+".template.body", tmpl.Body, msg)
+		if err != nil {
+			return msg, err
+		}
+		out.Body = body
+	}
+	return out, nil
+}
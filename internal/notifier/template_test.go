@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func stubTemplateEnv(t *testing.T) {
+	t.Helper()
+	origHostname, origGetwd, origNow := hostnameFunc, templateGetwd, templateNow
+	t.Cleanup(func() {
+		hostnameFunc, templateGetwd, templateNow = origHostname, origGetwd, origNow
+	})
+	hostnameFunc = func() (string, error) { return "devbox", nil }
+	templateGetwd = func() (string, error) { return "/src/ding-ding", nil }
+	templateNow = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC) }
+}
+
+func TestTemplateData_KeysMatchConfigTemplateFields(t *testing.T) {
+	stubTemplateEnv(t)
+
+	var got []string
+	for key := range templateData(Message{}) {
+		got = append(got, key)
+	}
+	want := append([]string(nil), config.TemplateFields...)
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("template data keys = %v, want config.TemplateFields %v", got, want)
+	}
+}
+
+func TestApplyTemplate_RendersMessageAndEnvironment(t *testing.T) {
+	stubTemplateEnv(t)
+	exitCode := 3
+
+	msg := Message{Title: "Build", Body: "failed", Agent: "claude", Event: EventError, ExitCode: &exitCode, tier: tierIdle}
+	tmpl := config.TemplateConfig{
+		Title: "{{upper .Agent}}: {{.Title}}",
+		Body:  "{{.Body}} (exit {{.ExitCode}}) on {{.Hostname}} in {{.Cwd}} at {{.Timestamp.Format \"15:04\"}} [{{.Tier}}]",
+	}
+
+	got, err := applyTemplate("ntfy", tmpl, msg)
+	if err != nil {
+		t.Fatalf("applyTemplate() error = %v", err)
+	}
+	if got.Title != "CLAUDE: Build" {
+		t.Errorf("title = %q", got.Title)
+	}
+	if want := "failed (exit 3) on devbox in /src/ding-ding at 15:04 [idle]"; got.Body != want {
+		t.Errorf("body = %q, want %q", got.Body, want)
+	}
+}
+
+func TestApplyTemplate_RendersLinksAndReplies(t *testing.T) {
+	stubTemplateEnv(t)
+
+	msg := Message{
+		Title:   "PR ready",
+		URL:     "https://example.com/runs/1",
+		Actions: []Action{{Label: "Open PR", URL: "https://example.com/pull/1"}},
+		Replies: []string{"merge", "wait"},
+	}
+	tmpl := config.TemplateConfig{Body: "{{.URL}}{{range .Actions}} {{.Label}}={{.URL}}{{end}} {{json .Actions}} {{range .Replies}}[{{.}}]{{end}}"}
+
+	got, err := applyTemplate("ntfy", tmpl, msg)
+	if err != nil {
+		t.Fatalf("applyTemplate() error = %v", err)
+	}
+	want := `https://example.com/runs/1 Open PR=https://example.com/pull/1 [{"label":"Open PR","url":"https://example.com/pull/1"}] [merge][wait]`
+	if got.Body != want {
+		t.Errorf("body = %q, want %q", got.Body, want)
+	}
+}
+
+func TestApplyTemplate_EmptyTemplatesKeepMessage(t *testing.T) {
+	msg := Message{Title: "t", Body: "b"}
+	got, err := applyTemplate("ntfy", config.TemplateConfig{}, msg)
+	if err != nil {
+		t.Fatalf("applyTemplate() error = %v", err)
+	}
+	if got.Title != "t" || got.Body != "b" {
+		t.Fatalf("message changed without templates: %+v", got)
+	}
+}
+
+func TestSendDiscord_BodyTemplateReplacesContent(t *testing.T) {
+	stubTemplateEnv(t)
+
+	var gotPayload map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &gotPayload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cfg := config.DiscordConfig{
+		WebhookURL: srv.URL,
+		Template:   config.TemplateConfig{Body: "{{.Agent}} on {{.Hostname}}: {{.Body}}"},
+	}
+	if err := sendDiscord(context.Background(), cfg, Message{Title: "t", Body: "done", Agent: "claude", Event: EventCompleted}); err != nil {
+		t.Fatalf("sendDiscord(context.Background(), ) error = %v", err)
+	}
+
+	if got := gotPayload["content"]; got != "claude on devbox: done" {
+		t.Fatalf("content = %q, want templated content", got)
+	}
+}
+
+func TestSendWebhook_PayloadTemplate(t *testing.T) {
+	var gotBody map[string]any
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &gotBody); err != nil {
+			t.Errorf("payload is not JSON: %q", b)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.WebhookConfig{
+		URL:     srv.URL,
+		Payload: `{"text": {{json (printf "%s: %s" .Title .Body)}}, "event": {{json .Event}}}`,
+	}
+	if err := sendWebhook(context.Background(), cfg, Message{Title: `say "hi"`, Body: "done", Event: EventCompleted}); err != nil {
+		t.Fatalf("sendWebhook(context.Background(), ) error = %v", err)
+	}
+
+	if gotBody["text"] != `say "hi": done` || gotBody["event"] != "completed" {
+		t.Fatalf("payload = %v", gotBody)
+	}
+}
+
+func TestSendWebhook_PayloadTemplateMustRenderJSON(t *testing.T) {
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not be sent for an invalid payload")
+	})
+
+	cfg := config.WebhookConfig{URL: srv.URL, Payload: `{"text": {{.Body}}}`}
+	err := sendWebhook(context.Background(), cfg, Message{Title: "t", Body: "not json"})
+	if err == nil || !strings.Contains(err.Error(), "valid JSON") {
+		t.Fatalf("sendWebhook(context.Background(), ) error = %v, want invalid JSON error", err)
+	}
+}
@@ -0,0 +1,118 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+var (
+	// ErrActionTimeout is returned by WaitForAction when no action was
+	// chosen before the context deadline.
+	ErrActionTimeout = errors.New("no action chosen before the timeout")
+	// ErrActionDismissed is returned by WaitForAction when the notification
+	// was closed without choosing an action.
+	ErrActionDismissed = errors.New("notification dismissed without choosing an action")
+	// ErrWaitActionUnsupported is returned on platforms without interactive
+	// notification actions.
+	ErrWaitActionUnsupported = errors.New("waiting for notification actions requires a Linux desktop with D-Bus notifications")
+)
+
+// WaitActionFunc shows an interactive notification and waits for the chosen
+// action key. Tests replace it to simulate clicks.
+var WaitActionFunc = waitAction
+
+// ParseWaitActions parses a comma-separated action list such as "allow,deny".
+func ParseWaitActions(value string) ([]string, error) {
+	keys := strings.Split(value, ",")
+	for i := range keys {
+		keys[i] = strings.TrimSpace(keys[i])
+	}
+	if err := validateActionKeys("wait actions", keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ValidateReplies checks a message's reply options, which follow the same
+// rules as wait actions.
+func ValidateReplies(replies []string) error {
+	if len(replies) == 0 {
+		return nil
+	}
+	return validateActionKeys("replies", replies)
+}
+
+// actionKeyPattern is the charset of answer keys. They are printed and
+// published back verbatim, and ntfy's Actions header separates actions and
+// their parameters with ';', ',' and '=', so nothing else is allowed.
+var actionKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validateActionKeys checks answer keys against actionKeyPattern; "default"
+// is reserved by the freedesktop spec for clicks on the notification body.
+func validateActionKeys(what string, keys []string) error {
+	if len(keys) > maxActions {
+		return fmt.Errorf("too many %s: %d (max %d)", what, len(keys), maxActions)
+	}
+	for i, key := range keys {
+		switch {
+		case key == "":
+			return fmt.Errorf("invalid %s: empty action", what)
+		case !actionKeyPattern.MatchString(key):
+			return fmt.Errorf("invalid %s %q: only letters, digits, '_', '.' and '-' are allowed", what, key)
+		case key == "default":
+			return fmt.Errorf("invalid %s %q: reserved name", what, key)
+		case slices.Contains(keys[:i], key):
+			return fmt.Errorf("invalid %s: duplicate action %q", what, key)
+		}
+	}
+	return nil
+}
+
+// WaitForAction shows msg as a desktop notification with one button per
+// action and blocks until one is clicked, the notification is dismissed, or
+// ctx ends. It bypasses focus and idle routing: the caller is blocked on the
+// answer, so the prompt is always shown and push backends are not involved.
+func WaitForAction(ctx context.Context, cfg config.Config, msg Message, actions []string) (string, error) {
+	logger := DefaultLoggerFunc().With(messageMetadata(msg)...)
+	logger.Info("notifier.wait_action.started", "actions", strings.Join(actions, ","))
+
+	msg.tier = tierDirect
+	msg, err := applyTemplate("notification", cfg.Notification.Template, msg)
+	if err != nil {
+		return "", err
+	}
+	n := newSystemNotification(cfg.Notification, msg)
+	// The prompt stays up until answered; ctx bounds the wait instead.
+	n.ExpireTimeoutMs = 0
+	n.URL, n.Actions = "", nil
+
+	action, err := WaitActionFunc(ctx, n, actions)
+	if err != nil {
+		logger.Info("notifier.wait_action.completed", "status", "no_action", "error", err)
+		return "", err
+	}
+	logger.Info("notifier.wait_action.completed", "status", "ok", "action", action)
+	return action, nil
+}
+
+func waitAction(ctx context.Context, n SystemNotification, actions []string) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", ErrWaitActionUnsupported
+	}
+	return dbusWaitAction(ctx, n, actions)
+}
+
+// actionLabel capitalizes an action key for its button: "allow" -> "Allow".
+func actionLabel(key string) string {
+	r, size := utf8.DecodeRuneInString(key)
+	return string(unicode.ToUpper(r)) + key[size:]
+}
@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Digni/ding-ding/internal/config"
+)
+
+func TestParseWaitActions(t *testing.T) {
+	got, err := ParseWaitActions(" allow, deny ,always-allow")
+	if err != nil {
+		t.Fatalf("ParseWaitActions() error = %v", err)
+	}
+	if strings.Join(got, ",") != "allow,deny,always-allow" {
+		t.Fatalf("ParseWaitActions() = %v", got)
+	}
+
+	for input, want := range map[string]string{
+		"allow,,deny": "empty action",
+		"allow,go on": "only letters, digits",
+		"allow;view":  "only letters, digits",
+		`say"hi`:      "only letters, digits",
+		"k=v":         "only letters, digits",
+		"bell\a":      "only letters, digits",
+		"default":     "reserved name",
+		"allow,allow": "duplicate action",
+		"a,b,c,d,e,f": "too many wait actions",
+		"":            "empty action",
+	} {
+		if _, err := ParseWaitActions(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseWaitActions(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestValidateReplies_RejectsNtfyActionSeparators(t *testing.T) {
+	if err := ValidateReplies([]string{"allow", "v1.2_ok-go"}); err != nil {
+		t.Fatalf("ValidateReplies() error = %v, want nil", err)
+	}
+	for _, reply := range []string{"allow; view, Pwn, https://evil.example", "x, headers.Authorization=abc", "ok\r\n"} {
+		if err := ValidateReplies([]string{reply}); err == nil {
+			t.Errorf("ValidateReplies(%q) error = nil, want rejection", reply)
+		}
+	}
+}
+
+func TestWaitForAction_ShowsPersistentPromptWithoutLinks(t *testing.T) {
+	origWait := WaitActionFunc
+	t.Cleanup(func() { WaitActionFunc = origWait })
+
+	var got SystemNotification
+	WaitActionFunc = func(_ context.Context, n SystemNotification, actions []string) (string, error) {
+		got = n
+		return actions[1], nil
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Notification.ExpireTimeoutMs = 5000
+	msg := Message{Title: "Permission", Body: "Allow rm?", URL: "https://example.com", Actions: []Action{{Label: "x", URL: "https://example.com"}}}
+
+	action, err := WaitForAction(context.Background(), cfg, msg, []string{"allow", "deny"})
+	if err != nil {
+		t.Fatalf("WaitForAction() error = %v", err)
+	}
+	if action != "deny" {
+		t.Errorf("action = %q, want deny", action)
+	}
+	if got.Title != "Permission" || got.Body != "Allow rm?" {
+		t.Errorf("notification = %+v", got)
+	}
+	if got.ExpireTimeoutMs != 0 {
+		t.Errorf("ExpireTimeoutMs = %d, want 0 so the prompt stays until answered", got.ExpireTimeoutMs)
+	}
+	if got.URL != "" || got.Actions != nil {
+		t.Errorf("links should not be shown next to wait actions: %+v", got)
+	}
+}
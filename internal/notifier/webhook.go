@@ -2,17 +2,36 @@ package notifier
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Digni/ding-ding/internal/config"
 )
 
-func sendWebhook(cfg config.WebhookConfig, msg Message) error {
-	payload, err := json.Marshal(msg)
+func sendWebhook(ctx context.Context, cfg config.WebhookConfig, msg Message) error {
+	msg, err := applyTemplate("webhook", cfg.Template, msg)
 	if err != nil {
-		return fmt.Errorf("marshal payload: %w", err)
+		return err
+	}
+	if mapped, ok := mapPriority(cfg.PriorityMap, msg.Priority); ok {
+		msg.Priority = Priority(mapped)
+	}
+
+	payload, err := webhookPayload(cfg, msg)
+	if err != nil {
+		return err
 	}
 
 	method := cfg.Method
@@ -20,21 +39,170 @@ func sendWebhook(cfg config.WebhookConfig, msg Message) error {
 		method = "POST"
 	}
 
-	req, err := http.NewRequest(method, cfg.URL, bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, method, cfg.URL, bytes.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("create request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", webhookContentType(cfg))
+
+	for name, value := range cfg.Headers {
+		resolved, err := resolveWebhookValue(ctx, value)
+		if err != nil {
+			return fmt.Errorf("webhook header %s: %w", name, err)
+		}
+		req.Header.Set(name, resolved)
+	}
 
-	resp, err := httpClient.Do(req)
+	if cfg.Secret != "" {
+		secret, err := resolveWebhookValue(ctx, cfg.Secret)
+		if err != nil {
+			return fmt.Errorf("webhook secret: %w", err)
+		}
+		req.Header.Set(cfg.SignatureHeader, webhookSignature(secret, webhookNow(), payload))
+	}
+
+	client, err := httpClientFor(cfg.HTTP)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("send request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+	if !webhookSucceeded(cfg.SuccessStatus, resp.StatusCode) {
+		if detail := readErrorBody(resp.Body); detail != "" {
+			return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, detail)
+		}
 		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+// webhookNow is the signing clock; tests replace it.
+var webhookNow = time.Now
+
+// webhookSignature signs "<unix timestamp>.<body>" with HMAC-SHA256. The
+// timestamp is part of the signed data so receivers can reject replays.
+func webhookSignature(secret string, now time.Time, body []byte) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSucceeded reports whether status matches one of the accepted codes
+// ("202") or classes ("2xx"); with none configured any 2xx succeeds.
+func webhookSucceeded(accepted []string, status int) bool {
+	if len(accepted) == 0 {
+		return status >= 200 && status < 300
+	}
+	code := strconv.Itoa(status)
+	for _, want := range accepted {
+		if want == code || (strings.HasSuffix(want, "xx") && want[0] == code[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+var webhookEnvRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// resolveWebhookValue resolves a header or secret value: a secret reference
+// such as "env:NAME" or "cmd:pass show n8n" supplies the whole value (see
+// resolveSecret), and ${NAME} embeds an environment variable, e.g.
+// "Bearer ${N8N_TOKEN}". A "literal:" value is used verbatim. Errors name the
+// reference, never the resolved value.
+func resolveWebhookValue(ctx context.Context, value string) (string, error) {
+	if config.IsSecretReference(value) || strings.HasPrefix(value, config.LiteralPrefix) {
+		return resolveSecret(ctx, value)
+	}
+
+	var missing string
+	resolved := webhookEnvRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok && missing == "" {
+			missing = name
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("environment variable %s is not set", missing)
+	}
+	return resolved, nil
+}
+
+// webhookPayload encodes the message in the configured format: JSON (the
+// payload template when set, else the message), form fields, or the payload
+// template verbatim.
+func webhookPayload(cfg config.WebhookConfig, msg Message) ([]byte, error) {
+	switch config.WebhookFormat(cfg.Format) {
+	case "form":
+		return webhookForm(msg)
+	case "template":
+		rendered, err := renderTemplate("webhook.payload", cfg.Payload, msg)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(rendered), nil
+	}
+
+	if cfg.Payload == "" {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("marshal payload: %w", err)
+		}
+		return payload, nil
+	}
+
+	rendered, err := renderTemplate("webhook.payload", cfg.Payload, msg)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(rendered)) {
+		return nil, errors.New("webhook.payload template did not render valid JSON")
+	}
+	return []byte(rendered), nil
+}
+
+// webhookForm URL-encodes the message's JSON fields. Strings are sent as-is;
+// numbers, booleans and nested values keep their JSON encoding.
+func webhookForm(msg Message) ([]byte, error) {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	form := url.Values{}
+	for key, raw := range fields {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			text = string(raw)
+		}
+		form.Set(key, text)
+	}
+	return []byte(form.Encode()), nil
+}
+
+// webhookContentType returns content_type when configured, else the type
+// matching the body format.
+func webhookContentType(cfg config.WebhookConfig) string {
+	if cfg.ContentType != "" {
+		return cfg.ContentType
+	}
+	switch config.WebhookFormat(cfg.Format) {
+	case "form":
+		return "application/x-www-form-urlencoded"
+	case "template":
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json"
+}
@@ -1,11 +1,19 @@
 package notifier
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Digni/ding-ding/internal/config"
 )
@@ -25,7 +33,7 @@ func TestSendWebhook_Success(t *testing.T) {
 	cfg := config.WebhookConfig{URL: srv.URL, Method: "POST"}
 	msg := Message{Title: "hello", Body: "world"}
 
-	err := sendWebhook(cfg, msg)
+	err := sendWebhook(context.Background(), cfg, msg)
 	if err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
@@ -51,7 +59,7 @@ func TestSendWebhook_CustomMethod(t *testing.T) {
 	cfg := config.WebhookConfig{URL: srv.URL, Method: "PUT"}
 	msg := Message{Title: "t", Body: "b"}
 
-	if err := sendWebhook(cfg, msg); err != nil {
+	if err := sendWebhook(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	if gotMethod != "PUT" {
@@ -70,7 +78,7 @@ func TestSendWebhook_EmptyMethodDefaultsToPost(t *testing.T) {
 	cfg := config.WebhookConfig{URL: srv.URL, Method: ""}
 	msg := Message{Title: "t", Body: "b"}
 
-	if err := sendWebhook(cfg, msg); err != nil {
+	if err := sendWebhook(context.Background(), cfg, msg); err != nil {
 		t.Fatalf("expected nil error, got: %v", err)
 	}
 	if gotMethod != "POST" {
@@ -86,7 +94,7 @@ func TestSendWebhook_ServerError(t *testing.T) {
 	cfg := config.WebhookConfig{URL: srv.URL, Method: "POST"}
 	msg := Message{Title: "t", Body: "b"}
 
-	err := sendWebhook(cfg, msg)
+	err := sendWebhook(context.Background(), cfg, msg)
 	if err == nil {
 		t.Fatal("expected an error, got nil")
 	}
@@ -94,3 +102,133 @@ func TestSendWebhook_ServerError(t *testing.T) {
 		t.Errorf("expected error to contain %q, got %q", want, err.Error())
 	}
 }
+
+func TestSendWebhook_ErrorIncludesResponseBody(t *testing.T) {
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = io.WriteString(w, `{"error":"missing field",
+"field":"summary"}`)
+	})
+
+	err := sendWebhook(context.Background(), config.WebhookConfig{URL: srv.URL}, Message{Title: "t", Body: "b"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	want := `webhook returned status 422: {"error":"missing field", "field":"summary"}`
+	if err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+}
+
+func TestSendWebhook_HeadersAndSignature(t *testing.T) {
+	var got http.Header
+	var gotBody []byte
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DING_TEST_TOKEN", "tok")
+	t.Setenv("DING_TEST_TENANT", "acme")
+	origNow := webhookNow
+	webhookNow = func() time.Time { return time.Unix(1767366245, 0) }
+	t.Cleanup(func() { webhookNow = origNow })
+
+	cfg := config.WebhookConfig{
+		URL: srv.URL,
+		Headers: map[string]string{
+			"Authorization": "Bearer ${DING_TEST_TOKEN}",
+			"X-Tenant":      "env:DING_TEST_TENANT",
+		},
+		Secret:          "file:" + secretFile,
+		SignatureHeader: "X-Signature",
+	}
+	if err := sendWebhook(context.Background(), cfg, Message{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+
+	if got.Get("Authorization") != "Bearer tok" || got.Get("X-Tenant") != "acme" {
+		t.Errorf("expected resolved headers, got Authorization=%q X-Tenant=%q", got.Get("Authorization"), got.Get("X-Tenant"))
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("1767366245."))
+	mac.Write(gotBody)
+	want := "t=1767366245,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if got.Get("X-Signature") != want {
+		t.Errorf("expected signature %q, got %q", want, got.Get("X-Signature"))
+	}
+}
+
+func TestSendWebhook_MissingHeaderReference(t *testing.T) {
+	cfg := config.WebhookConfig{
+		URL:     "http://127.0.0.1:1",
+		Headers: map[string]string{"Authorization": "Bearer ${DING_TEST_UNSET_TOKEN}"},
+	}
+	err := sendWebhook(context.Background(), cfg, Message{Title: "t"})
+	if err == nil || !strings.Contains(err.Error(), "DING_TEST_UNSET_TOKEN is not set") {
+		t.Fatalf("expected missing variable error, got: %v", err)
+	}
+}
+
+func TestSendWebhook_FormAndTemplateFormats(t *testing.T) {
+	var gotContentType, gotBody string
+
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		gotContentType = r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	exitCode := 2
+	msg := Message{Title: "Build", Body: "failed & done", Agent: "claude", ExitCode: &exitCode}
+
+	if err := sendWebhook(context.Background(), config.WebhookConfig{URL: srv.URL, Format: "form"}, msg); err != nil {
+		t.Fatalf("form: expected nil error, got: %v", err)
+	}
+	if gotContentType != "application/x-www-form-urlencoded" {
+		t.Errorf("form: expected form content type, got %q", gotContentType)
+	}
+	form, err := url.ParseQuery(gotBody)
+	if err != nil {
+		t.Fatalf("form: invalid body %q: %v", gotBody, err)
+	}
+	if form.Get("body") != "failed & done" || form.Get("agent") != "claude" || form.Get("exit_code") != "2" {
+		t.Errorf("form: unexpected fields %v", form)
+	}
+
+	cfg := config.WebhookConfig{URL: srv.URL, Format: "template", ContentType: "text/markdown", Payload: "*{{.Title}}*: {{.Body}}"}
+	if err := sendWebhook(context.Background(), cfg, msg); err != nil {
+		t.Fatalf("template: expected nil error, got: %v", err)
+	}
+	if gotContentType != "text/markdown" || gotBody != "*Build*: failed & done" {
+		t.Errorf("template: got %q %q", gotContentType, gotBody)
+	}
+}
+
+func TestSendWebhook_SuccessStatus(t *testing.T) {
+	status := http.StatusAccepted
+	srv := setupHTTPTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+
+	cfg := config.WebhookConfig{URL: srv.URL, SuccessStatus: []string{"202", "3xx"}}
+	for code, wantErr := range map[int]bool{
+		http.StatusAccepted: false,
+		http.StatusFound:    false,
+		http.StatusOK:       true,
+		http.StatusConflict: true,
+	} {
+		status = code
+		err := sendWebhook(context.Background(), cfg, Message{Title: "t"})
+		if (err != nil) != wantErr {
+			t.Errorf("status %d: error = %v, want error %v", code, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/notifier"
+)
+
+// replyRetention bounds how long an operation's replies can be polled.
+const replyRetention = 24 * time.Hour
+
+type pendingReplies struct {
+	offered []string
+	replies []notifier.Reply
+	created time.Time
+}
+
+// replyStore collects ntfy replies for operations that offered reply
+// options. Replies to unknown operations, or outside the offered options,
+// are dropped: anyone who can publish to the reply topic could send them.
+type replyStore struct {
+	mu      sync.Mutex
+	pending map[string]*pendingReplies
+	now     func() time.Time
+}
+
+func newReplyStore() *replyStore {
+	return &replyStore{pending: map[string]*pendingReplies{}, now: time.Now}
+}
+
+func (s *replyStore) register(operationID string, offered []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, p := range s.pending {
+		if now.Sub(p.created) > replyRetention {
+			delete(s.pending, id)
+		}
+	}
+	s.pending[operationID] = &pendingReplies{offered: offered, created: now}
+}
+
+func (s *replyStore) add(reply notifier.Reply) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[reply.OperationID]
+	if !ok || !slices.Contains(p.offered, reply.Reply) {
+		return false
+	}
+	p.replies = append(p.replies, reply)
+	return true
+}
+
+func (s *replyStore) get(operationID string) ([]notifier.Reply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[operationID]
+	if !ok || s.now().Sub(p.created) > replyRetention {
+		return nil, false
+	}
+	return slices.Clone(p.replies), true
+}
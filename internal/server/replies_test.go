@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Digni/ding-ding/internal/config"
+	"github.com/Digni/ding-ding/internal/notifier"
+)
+
+func TestReplyStore_KeepsOfferedRepliesForKnownOperations(t *testing.T) {
+	store := newReplyStore()
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+
+	store.register("op-1", []string{"allow", "deny"})
+
+	if store.add(notifier.Reply{OperationID: "op-2", Reply: "allow"}) {
+		t.Error("reply to an unknown operation was accepted")
+	}
+	if store.add(notifier.Reply{OperationID: "op-1", Reply: "rm -rf"}) {
+		t.Error("reply outside the offered options was accepted")
+	}
+	if !store.add(notifier.Reply{OperationID: "op-1", Reply: "deny"}) {
+		t.Fatal("offered reply was dropped")
+	}
+
+	got, ok := store.get("op-1")
+	if !ok || len(got) != 1 || got[0].Reply != "deny" {
+		t.Fatalf("get() = %v, %v", got, ok)
+	}
+
+	now = now.Add(replyRetention + time.Minute)
+	if _, ok := store.get("op-1"); ok {
+		t.Error("expired operation is still pollable")
+	}
+}
+
+func TestReplies_PollAfterNotify(t *testing.T) {
+	origSystem := notifier.SystemNotifyFunc
+	origIdle := notifier.IdleDurationFunc
+	origFocused := notifier.TerminalFocusedFunc
+	t.Cleanup(func() {
+		notifier.SystemNotifyFunc = origSystem
+		notifier.IdleDurationFunc = origIdle
+		notifier.TerminalFocusedFunc = origFocused
+	})
+	// Idle user: ntfy is pushed with the reply buttons.
+	notifier.SystemNotifyFunc = func(context.Context, notifier.SystemNotification) error { return nil }
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return time.Hour, nil }
+	notifier.TerminalFocusedFunc = func() bool { return false }
+
+	var mu sync.Mutex
+	var published []string
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		published = append(published, r.Header.Get("Actions"))
+		mu.Unlock()
+	}))
+	defer ntfy.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = ntfy.URL
+	cfg.Ntfy.Topic = "agents"
+	cfg.Ntfy.ReplyTopic = "agents-replies"
+
+	store := newReplyStore()
+	ts := httptest.NewServer(newMux(cfg, slog.Default(), store))
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(ts.URL+"/notify", "application/json",
+		strings.NewReader(`{"body":"Needs input","replies":["allow","deny"]}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var notified map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&notified)
+	resp.Body.Close()
+	operationID := notified["operation_id"]
+	if resp.StatusCode != http.StatusOK || operationID == "" {
+		t.Fatalf("notify response = %d %v, want 200 with operation_id", resp.StatusCode, notified)
+	}
+
+	mu.Lock()
+	if len(published) != 1 || !strings.Contains(published[0], "body="+operationID+" allow") {
+		t.Errorf("ntfy actions = %v, want reply buttons for %s", published, operationID)
+	}
+	mu.Unlock()
+
+	poll := func() (int, map[string]json.RawMessage) {
+		t.Helper()
+		resp, err := ts.Client().Get(ts.URL + "/replies/" + operationID)
+		if err != nil {
+			t.Fatalf("poll failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	if status, body := poll(); status != http.StatusOK || string(body["replies"]) != "[]" {
+		t.Fatalf("poll before reply = %d %s, want 200 with no replies", status, body["replies"])
+	}
+
+	store.add(notifier.Reply{OperationID: operationID, Reply: "allow", Time: time.Unix(1700000000, 0).UTC()})
+	status, body := poll()
+	var replies []notifier.Reply
+	_ = json.Unmarshal(body["replies"], &replies)
+	if status != http.StatusOK || len(replies) != 1 || replies[0].Reply != "allow" {
+		t.Fatalf("poll after reply = %d %s", status, body["replies"])
+	}
+
+	resp, err = ts.Client().Get(ts.URL + "/replies/op-unknown")
+	if err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown operation status = %d, want 404", resp.StatusCode)
+	}
+}
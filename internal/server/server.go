@@ -1,11 +1,19 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Digni/ding-ding/internal/config"
@@ -30,8 +38,150 @@ func writeJSONError(w http.ResponseWriter, status int, code, message string) {
 	writeJSON(w, status, errorResponse{Code: code, Message: message})
 }
 
+// notifyResponse is the /notify answer once delivery has been attempted.
+type notifyResponse struct {
+	Status      string                  `json:"status"`
+	OperationID string                  `json:"operation_id"`
+	Report      notifier.DeliveryReport `json:"report"`
+}
+
+// deliveryFailedResponse is the error body when no push backend delivered;
+// it carries the report so callers can see which channels failed and why.
+type deliveryFailedResponse struct {
+	errorResponse
+	Report notifier.DeliveryReport `json:"report"`
+}
+
+// parseDryRun reads the dry_run query parameter. A dry run reports the
+// routing decision and the channels that would fire without sending, waiting
+// for an action or registering replies.
+func parseDryRun(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("dry_run")
+	if raw == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("dry_run must be a boolean, got %q", raw)
+	}
+	return dryRun, nil
+}
+
+// respondDelivery answers a /notify request with its delivery report: 200
+// "ok" when everything delivered, 200 "partial" when at least one push
+// backend did, 200 "remote_failed" when only the local notification did, and
+// 500 notification_delivery_failed otherwise.
+func respondDelivery(w http.ResponseWriter, report notifier.DeliveryReport, err error, logger *slog.Logger, fields []any, start time.Time) {
+	if err != nil && !report.PartialFailure && !report.RemoteFailed {
+		logger.Error("server.notify.request.completed", append(fields, "status", "error", "status_code", http.StatusInternalServerError, "duration_ms", time.Since(start).Milliseconds(), "error", err)...)
+		writeJSON(w, http.StatusInternalServerError, deliveryFailedResponse{
+			errorResponse: errorResponse{Code: "notification_delivery_failed", Message: "notification delivery failed"},
+			Report:        report,
+		})
+		return
+	}
+
+	status := "ok"
+	switch {
+	case report.PartialFailure:
+		status = "partial"
+		logger.Warn("server.notify.partial_failure", append(fields, "error", err)...)
+	case report.RemoteFailed:
+		status = "remote_failed"
+		logger.Warn("server.notify.remote_failed", append(fields, "error", err)...)
+	}
+	logger.Info("server.notify.request.completed", append(fields, "status", status, "status_code", http.StatusOK, "duration_ms", time.Since(start).Milliseconds())...)
+	writeJSON(w, http.StatusOK, notifyResponse{Status: status, OperationID: report.OperationID, Report: report})
+}
+
+const (
+	defaultWaitTimeout = 5 * time.Minute
+	maxWaitTimeout     = 30 * time.Minute
+)
+
+// waitRequest holds the long-poll fields of a /notify request: the actions
+// to offer and how many seconds to wait for one to be chosen.
+type waitRequest struct {
+	Action  string `json:"wait_action"`
+	Timeout int    `json:"wait_timeout"`
+}
+
+func (req waitRequest) parse() ([]string, time.Duration, error) {
+	if req.Action == "" {
+		return nil, 0, nil
+	}
+	actions, err := notifier.ParseWaitActions(req.Action)
+	if err != nil {
+		return nil, 0, err
+	}
+	timeout := time.Duration(req.Timeout) * time.Second
+	switch {
+	case req.Timeout == 0:
+		timeout = defaultWaitTimeout
+	case req.Timeout < 0 || timeout > maxWaitTimeout:
+		return nil, 0, fmt.Errorf("wait_timeout must be between 1 and %d seconds", int(maxWaitTimeout.Seconds()))
+	}
+	return actions, timeout, nil
+}
+
+// deliveryWriteSlack is the time left to write a delivery report once the
+// delivery deadline has passed.
+const deliveryWriteSlack = 10 * time.Second
+
+// deliveryWriteTimeout lets a response outlast a delivery that runs to its
+// deadline. No delivery deadline means no write timeout either.
+func deliveryWriteTimeout(cfg config.Config) time.Duration {
+	if cfg.Notification.DeliveryTimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.Notification.DeliveryTimeoutSeconds)*time.Second + deliveryWriteSlack
+}
+
+// deliveryWriteDeadline is deliveryWriteTimeout from now. A project config
+// can change the delivery deadline, so each request sets its own.
+func deliveryWriteDeadline(cfg config.Config) time.Time {
+	timeout := deliveryWriteTimeout(cfg)
+	if timeout == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// respondWaitAction holds the request open until an action is chosen on the
+// server's desktop, answering {"status":"ok","action":...}, or "timeout" /
+// "dismissed" when there is no answer.
+func respondWaitAction(w http.ResponseWriter, r *http.Request, cfg config.Config, msg notifier.Message, actions []string, timeout time.Duration, logger *slog.Logger, fields []any, start time.Time) {
+	// The answer can take minutes; lift the server-wide write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	action, err := notifier.WaitForAction(ctx, cfg, notifier.WithContext(msg, ""), actions)
+	switch {
+	case err == nil:
+		logger.Info("server.notify.request.completed", append(fields, "status", "ok", "status_code", http.StatusOK, "action", action, "duration_ms", time.Since(start).Milliseconds())...)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "action": action})
+	case errors.Is(err, notifier.ErrActionTimeout):
+		logger.Info("server.notify.request.completed", append(fields, "status", "timeout", "status_code", http.StatusOK, "duration_ms", time.Since(start).Milliseconds())...)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "timeout"})
+	case errors.Is(err, notifier.ErrActionDismissed):
+		logger.Info("server.notify.request.completed", append(fields, "status", "dismissed", "status_code", http.StatusOK, "duration_ms", time.Since(start).Milliseconds())...)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "dismissed"})
+	case r.Context().Err() != nil:
+		logger.Info("server.notify.request.canceled", append(fields, "status", "canceled", "duration_ms", time.Since(start).Milliseconds())...)
+	default:
+		logger.Error("server.notify.request.completed", append(fields, "status", "error", "status_code", http.StatusServiceUnavailable, "duration_ms", time.Since(start).Milliseconds(), "error", err)...)
+		writeJSONError(w, http.StatusServiceUnavailable, "wait_action_unavailable", err.Error())
+	}
+}
+
 // NewMux builds the HTTP handler for ding-ding's server endpoints.
 func NewMux(cfg config.Config, logger *slog.Logger) *http.ServeMux {
+	return newMux(cfg, logger, newReplyStore())
+}
+
+func newMux(cfg config.Config, logger *slog.Logger, replies *replyStore) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("POST /notify", func(w http.ResponseWriter, r *http.Request) {
@@ -72,18 +222,90 @@ func NewMux(cfg config.Config, logger *slog.Logger) *http.ServeMux {
 			return
 		}
 
+		event, err := notifier.ParseEvent(string(msg.Event))
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_event", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_event", err.Error())
+			return
+		}
+		msg.Event = event
+
+		priority, err := notifier.ParsePriority(string(msg.Priority))
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_priority", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_priority", err.Error())
+			return
+		}
+		msg.Priority = priority
+
+		if msg.DurationMs < 0 {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_duration", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_duration", "duration_ms must be a non-negative integer")
+			return
+		}
+
+		if err := notifier.ValidateLinks(msg); err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_link", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_link", err.Error())
+			return
+		}
+
+		// Each request runs with the caller's project config layered over the
+		// server's config.
+		cfg, err := requestConfig(cfg, msg, logger)
+		if err != nil {
+			logger.Error("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_project_config", "duration_ms", time.Since(start).Milliseconds(), "error", err)...)
+			writeJSONError(w, http.StatusInternalServerError, "invalid_project_config", err.Error())
+			return
+		}
+
+		if err := validateReplies(cfg, msg.Replies); err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_replies", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_replies", err.Error())
+			return
+		}
+
+		if err := validateNtfyOptions(msg.Ntfy); err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_ntfy_options", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_ntfy_options", err.Error())
+			return
+		}
+
+		var wait waitRequest
+		_ = json.Unmarshal(rawBody, &wait)
+		waitActions, waitTimeout, err := wait.parse()
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_wait_action", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_wait_action", err.Error())
+			return
+		}
+
 		msg.RequestID = requestID
 		msg.OperationID = operationID
 
-		if err := notifier.NotifyRemote(cfg, msg); err != nil {
-			logger.Error("server.notify.request.completed", append(payloadMeta.Fields(), "status", "error", "status_code", http.StatusInternalServerError, "duration_ms", time.Since(start).Milliseconds(), "error", err)...)
-			writeJSONError(w, http.StatusInternalServerError, "notification_delivery_failed", "notification delivery failed")
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_dry_run", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_dry_run", err.Error())
+			return
+		}
+		if dryRun {
+			report, err := notifier.NotifyRemoteWithOptionsContext(r.Context(), cfg, msg, notifier.NotifyOptions{DryRun: true})
+			respondDelivery(w, report, err, logger, payloadMeta.Fields(), start)
 			return
 		}
 
-		logger.Info("server.notify.request.completed", append(payloadMeta.Fields(), "status", "ok", "status_code", http.StatusOK, "duration_ms", time.Since(start).Milliseconds())...)
+		if waitActions != nil {
+			respondWaitAction(w, r, cfg, msg, waitActions, waitTimeout, logger, payloadMeta.Fields(), start)
+			return
+		}
+		if len(msg.Replies) > 0 {
+			replies.register(operationID, msg.Replies)
+		}
 
-		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		_ = http.NewResponseController(w).SetWriteDeadline(deliveryWriteDeadline(cfg))
+		report, err := notifier.NotifyRemoteContext(r.Context(), cfg, msg)
+		respondDelivery(w, report, err, logger, payloadMeta.Fields(), start)
 	})
 
 	// Simple GET endpoint for quick curl usage
@@ -95,13 +317,91 @@ func NewMux(cfg config.Config, logger *slog.Logger) *http.ServeMux {
 		logger.Info("server.notify.request.started")
 
 		msg := notifier.Message{
-			Title: r.URL.Query().Get("title"),
-			Body:  r.URL.Query().Get("message"),
-			Agent: r.URL.Query().Get("agent"),
+			Title:   r.URL.Query().Get("title"),
+			Body:    r.URL.Query().Get("message"),
+			Agent:   r.URL.Query().Get("agent"),
+			Session: r.URL.Query().Get("session"),
+			Cwd:     r.URL.Query().Get("cwd"),
+			URL:     r.URL.Query().Get("url"),
 		}
 		payloadMeta := logging.PayloadMetadataFromQuery(queryFieldNames(r), int64(len(r.URL.RawQuery)))
 		logger.Info("server.notify.request.payload", payloadMeta.Fields()...)
 
+		event, err := notifier.ParseEvent(r.URL.Query().Get("event"))
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_event", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_event", err.Error())
+			return
+		}
+		msg.Event = event
+
+		priority, err := notifier.ParsePriority(r.URL.Query().Get("priority"))
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_priority", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_priority", err.Error())
+			return
+		}
+		msg.Priority = priority
+
+		if raw := r.URL.Query().Get("exit_code"); raw != "" {
+			exitCode, err := strconv.Atoi(raw)
+			if err != nil {
+				logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_exit_code", "duration_ms", time.Since(start).Milliseconds())...)
+				writeJSONError(w, http.StatusBadRequest, "invalid_exit_code", "exit_code must be an integer")
+				return
+			}
+			msg.ExitCode = &exitCode
+		}
+		if raw := r.URL.Query().Get("duration_ms"); raw != "" {
+			durationMs, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || durationMs < 0 {
+				logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_duration", "duration_ms", time.Since(start).Milliseconds())...)
+				writeJSONError(w, http.StatusBadRequest, "invalid_duration", "duration_ms must be a non-negative integer")
+				return
+			}
+			msg.DurationMs = durationMs
+		}
+
+		if err := notifier.ValidateLinks(msg); err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_link", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_link", err.Error())
+			return
+		}
+
+		if raw := r.URL.Query().Get("replies"); raw != "" {
+			for _, reply := range strings.Split(raw, ",") {
+				msg.Replies = append(msg.Replies, strings.TrimSpace(reply))
+			}
+		}
+		// Each request runs with the caller's project config layered over the
+		// server's config.
+		cfg, err := requestConfig(cfg, msg, logger)
+		if err != nil {
+			logger.Error("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_project_config", "duration_ms", time.Since(start).Milliseconds(), "error", err)...)
+			writeJSONError(w, http.StatusInternalServerError, "invalid_project_config", err.Error())
+			return
+		}
+
+		if err := validateReplies(cfg, msg.Replies); err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_replies", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_replies", err.Error())
+			return
+		}
+
+		wait := waitRequest{Action: r.URL.Query().Get("wait_action")}
+		if raw := r.URL.Query().Get("wait_timeout"); raw != "" {
+			wait.Timeout, err = strconv.Atoi(raw)
+			if err != nil {
+				wait.Timeout = -1
+			}
+		}
+		waitActions, waitTimeout, err := wait.parse()
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_wait_action", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_wait_action", err.Error())
+			return
+		}
+
 		if msg.Body == "" && msg.Title == "" {
 			msg.Title = "ding ding!"
 			msg.Body = "Agent task completed"
@@ -110,15 +410,44 @@ func NewMux(cfg config.Config, logger *slog.Logger) *http.ServeMux {
 		msg.RequestID = requestID
 		msg.OperationID = operationID
 
-		if err := notifier.NotifyRemote(cfg, msg); err != nil {
-			logger.Error("server.notify.request.completed", append(payloadMeta.Fields(), "status", "error", "status_code", http.StatusInternalServerError, "duration_ms", time.Since(start).Milliseconds(), "error", err)...)
-			writeJSONError(w, http.StatusInternalServerError, "notification_delivery_failed", "notification delivery failed")
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			logger.Warn("server.notify.request.rejected", append(payloadMeta.Fields(), "status", "error", "error_code", "invalid_dry_run", "duration_ms", time.Since(start).Milliseconds())...)
+			writeJSONError(w, http.StatusBadRequest, "invalid_dry_run", err.Error())
+			return
+		}
+		if dryRun {
+			report, err := notifier.NotifyRemoteWithOptionsContext(r.Context(), cfg, msg, notifier.NotifyOptions{DryRun: true})
+			respondDelivery(w, report, err, logger, payloadMeta.Fields(), start)
 			return
 		}
 
-		logger.Info("server.notify.request.completed", append(payloadMeta.Fields(), "status", "ok", "status_code", http.StatusOK, "duration_ms", time.Since(start).Milliseconds())...)
+		if waitActions != nil {
+			respondWaitAction(w, r, cfg, msg, waitActions, waitTimeout, logger, payloadMeta.Fields(), start)
+			return
+		}
+		if len(msg.Replies) > 0 {
+			replies.register(operationID, msg.Replies)
+		}
 
-		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		_ = http.NewResponseController(w).SetWriteDeadline(deliveryWriteDeadline(cfg))
+		report, err := notifier.NotifyRemoteContext(r.Context(), cfg, msg)
+		respondDelivery(w, report, err, logger, payloadMeta.Fields(), start)
+	})
+
+	// Replies collected from ntfy.reply_topic for a notification that
+	// offered reply options; hooks poll until one arrives.
+	mux.HandleFunc("GET /replies/{operation_id}", func(w http.ResponseWriter, r *http.Request) {
+		operationID := r.PathValue("operation_id")
+		got, ok := replies.get(operationID)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "unknown_operation", "no notification with replies for this operation")
+			return
+		}
+		if got == nil {
+			got = []notifier.Reply{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"operation_id": operationID, "replies": got})
 	})
 
 	// Health check
@@ -129,18 +458,97 @@ func NewMux(cfg config.Config, logger *slog.Logger) *http.ServeMux {
 	return mux
 }
 
-// Start launches the HTTP server that agents can POST to.
+// validateNtfyOptions checks per-message ntfy options. File uploads read
+// from the server's disk, so remote callers may only attach URLs.
+func validateNtfyOptions(opts *notifier.NtfyOptions) error {
+	if opts != nil && opts.File != "" {
+		return errors.New("ntfy.file uploads are only available from the CLI; use ntfy.attach with a URL")
+	}
+	return notifier.ValidateNtfyOptions(opts)
+}
+
+// validateReplies checks reply options, which are only collectable when
+// ntfy publishes reply buttons to a reply topic.
+func validateReplies(cfg config.Config, replies []string) error {
+	if len(replies) == 0 {
+		return nil
+	}
+	if !cfg.Ntfy.Enabled || cfg.Ntfy.ReplyTopic == "" {
+		return errors.New("replies require ntfy.enabled and ntfy.reply_topic")
+	}
+	return notifier.ValidateReplies(replies)
+}
+
+// requestConfig layers the project config found from the working directory
+// of the caller's agent PID over cfg. The cwd in the request body is only
+// informational: trusting it would let any local caller load a project
+// config from any directory.
+func requestConfig(cfg config.Config, msg notifier.Message, logger *slog.Logger) (config.Config, error) {
+	if msg.PID <= 0 {
+		return cfg, nil
+	}
+	cwd, err := notifier.ProcessCwd(msg.PID)
+	if err != nil {
+		logger.Debug("server.notify.project_config_unavailable", "pid", msg.PID, "error", err)
+		return cfg, nil
+	}
+	result, err := config.LoadResult{Config: cfg}.WithProject(cwd, nil)
+	if err != nil {
+		return cfg, err
+	}
+	for _, source := range result.Sources {
+		logger.Info("server.notify.project_config", "path", source.Path)
+	}
+	return result.Config, nil
+}
+
+// Start launches the HTTP server that agents can POST to. SIGINT or SIGTERM
+// cancels in-flight deliveries and shuts the server down.
 func Start(cfg config.Config) error {
-	mux := NewMux(cfg, slog.Default())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	replies := newReplyStore()
+	mux := newMux(cfg, slog.Default(), replies)
+
+	if cfg.Ntfy.Enabled && cfg.Ntfy.ReplyTopic != "" {
+		go notifier.SubscribeNtfyReplies(ctx, cfg.WithBackendHTTP().Ntfy, func(reply notifier.Reply) {
+			if !replies.add(reply) {
+				slog.Warn("server.replies.dropped", "operation_id", reply.OperationID)
+				return
+			}
+			slog.Info("server.replies.received", "operation_id", reply.OperationID, "reply", reply.Reply)
+		})
+	}
+
+	// The server is long-lived, so connection-oriented backends keep their
+	// connection open and replace-in-place state lives in memory.
+	notifier.KeepConnectionsOpen()
+	defer notifier.CloseConnections()
+	notifier.TrackGroupsInMemory()
+
 	slog.Info("server.started", "address", cfg.Server.Address)
 	srv := &http.Server{
 		Addr:         cfg.Server.Address,
 		Handler:      mux,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: deliveryWriteTimeout(cfg),
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
 	}
-	return srv.ListenAndServe()
+	slog.Info("server.stopping", "address", cfg.Server.Address)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
 }
 
 func queryFieldNames(r *http.Request) []string {
@@ -2,11 +2,16 @@ package server_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -18,8 +23,15 @@ import (
 )
 
 type errorPayload struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string                   `json:"code"`
+	Message string                   `json:"message"`
+	Report  *notifier.DeliveryReport `json:"report"`
+}
+
+type notifyPayload struct {
+	Status      string                  `json:"status"`
+	OperationID string                  `json:"operation_id"`
+	Report      notifier.DeliveryReport `json:"report"`
 }
 
 func captureServerLogs(t *testing.T) (*bytes.Buffer, *slog.Logger) {
@@ -94,10 +106,10 @@ func setupTestServer(t *testing.T, logger *slog.Logger) *httptest.Server {
 	})
 
 	// User is active + unfocused → Tier 2 (system notify only, no push)
-	notifier.IdleDurationFunc = func() (time.Duration, error) { return 0, nil }
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return 0, nil }
 	notifier.TerminalFocusedFunc = func() bool { return false }
 	notifier.ProcessInFocusedTerminalFunc = func(pid int) bool { return false }
-	notifier.SystemNotifyFunc = func(title, body string) error { return nil }
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error { return nil }
 
 	mux := server.NewMux(cfg, logger)
 	return httptest.NewServer(mux)
@@ -191,6 +203,158 @@ func TestPostNotify_EmptyTitleAndBody(t *testing.T) {
 	}
 }
 
+func TestPostNotify_EventReachesSystemNotification(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	var got notifier.SystemNotification
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error {
+		got = n
+		return nil
+	}
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"title":"build","body":"failed","event":"error","exit_code":2}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got.Urgency != "critical" {
+		t.Errorf("expected critical urgency for error event, got %q", got.Urgency)
+	}
+}
+
+func TestPostNotify_InvalidEvent(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"body":"done","event":"finished"}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+
+	payload := decodeErrorPayload(t, resp)
+	if payload.Code != "invalid_event" {
+		t.Errorf("expected invalid_event code, got %q", payload.Code)
+	}
+}
+
+func TestPostNotify_PriorityOverridesEventUrgency(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	var got notifier.SystemNotification
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error {
+		got = n
+		return nil
+	}
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"body":"approve deploy?","event":"progress","priority":"urgent"}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got.Urgency != "critical" {
+		t.Errorf("expected critical urgency for urgent priority, got %q", got.Urgency)
+	}
+}
+
+func TestPostNotify_InvalidPriority(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"body":"done","priority":"p1"}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+
+	payload := decodeErrorPayload(t, resp)
+	if payload.Code != "invalid_priority" {
+		t.Errorf("expected invalid_priority code, got %q", payload.Code)
+	}
+}
+
+func TestPostNotify_InvalidLink(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"body":"done","actions":[{"label":"Run","url":"file:///bin/sh"}]}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+
+	payload := decodeErrorPayload(t, resp)
+	if payload.Code != "invalid_link" {
+		t.Errorf("expected invalid_link code, got %q", payload.Code)
+	}
+}
+
+func TestPostNotify_RejectsNtfyFileUpload(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"body":"done","ntfy":{"file":"/etc/passwd"}}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+
+	payload := decodeErrorPayload(t, resp)
+	if payload.Code != "invalid_ntfy_options" {
+		t.Errorf("expected invalid_ntfy_options code, got %q", payload.Code)
+	}
+}
+
 func TestPostNotify_InvalidJSON(t *testing.T) {
 	ts := setupTestServer(t, slog.Default())
 	defer ts.Close()
@@ -265,12 +429,14 @@ func TestPostNotify_DeliveryFailureStructuredJSON(t *testing.T) {
 		notifier.SystemNotifyFunc = origSystem
 	})
 
-	notifier.IdleDurationFunc = func() (time.Duration, error) { return 10 * time.Second, nil }
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return 10 * time.Second, nil }
 	notifier.TerminalFocusedFunc = func() bool { return false }
 	notifier.ProcessInFocusedTerminalFunc = func(pid int) bool { return false }
-	notifier.TerminalFocusStateFunc = func() focus.State { return focus.State{Focused: false, Known: true} }
-	notifier.ProcessFocusStateFunc = func(pid int) focus.State { return focus.State{Focused: false, Known: true} }
-	notifier.SystemNotifyFunc = func(title, body string) error { return nil }
+	notifier.TerminalFocusStateFunc = func(context.Context) focus.State { return focus.State{Focused: false, Known: true} }
+	notifier.ProcessFocusStateFunc = func(_ context.Context, pid int) focus.State { return focus.State{Focused: false, Known: true} }
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error {
+		return errors.New("no notification daemon")
+	}
 
 	ts := httptest.NewServer(server.NewMux(cfg, slog.Default()))
 	defer ts.Close()
@@ -293,6 +459,132 @@ func TestPostNotify_DeliveryFailureStructuredJSON(t *testing.T) {
 	if payload.Code != "notification_delivery_failed" {
 		t.Errorf("expected notification_delivery_failed code, got %q", payload.Code)
 	}
+	if payload.Report == nil || len(payload.Report.Backends) != 1 || payload.Report.Backends[0].OK {
+		t.Fatalf("expected report with the failed ntfy backend, got %+v", payload.Report)
+	}
+	if payload.Report.Tier != "idle" || payload.Report.LocalSent || payload.Report.LocalError == "" {
+		t.Errorf("expected idle tier with a failed local notification, got %+v", payload.Report)
+	}
+
+	// The local notification alone is a delivery, but every push failing
+	// is reported apart from a partial failure.
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error { return nil }
+	resp, err = ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"world"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 when the local notification was delivered, got %d", resp.StatusCode)
+	}
+	var partial notifyPayload
+	if err := json.NewDecoder(resp.Body).Decode(&partial); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if partial.Status != "remote_failed" || !partial.Report.RemoteFailed || partial.Report.PartialFailure || !partial.Report.LocalSent {
+		t.Errorf("expected remote_failed status with the local notification sent, got %+v", partial)
+	}
+}
+
+func TestPostNotify_PartialFailureReturnsReport(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Idle.ThresholdSeconds = 1
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = "http://127.0.0.1:1"
+	cfg.Ntfy.Topic = "test"
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.URL = webhook.URL
+
+	origIdle := notifier.IdleDurationFunc
+	origSystem := notifier.SystemNotifyFunc
+	t.Cleanup(func() {
+		notifier.IdleDurationFunc = origIdle
+		notifier.SystemNotifyFunc = origSystem
+	})
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return 10 * time.Second, nil }
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error { return nil }
+
+	ts := httptest.NewServer(server.NewMux(cfg, slog.Default()))
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"world"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for a partial failure, got %d", resp.StatusCode)
+	}
+	var payload notifyPayload
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Status != "partial" || payload.OperationID == "" || payload.Report.OperationID != payload.OperationID {
+		t.Errorf("unexpected response envelope: %+v", payload)
+	}
+	if !payload.Report.PartialFailure {
+		t.Error("expected partial_failure in report")
+	}
+	results := map[string]bool{}
+	for _, backend := range payload.Report.Backends {
+		results[backend.Backend] = backend.OK
+	}
+	if ok, found := results["ntfy"]; !found || ok {
+		t.Errorf("expected failed ntfy result, got %+v", payload.Report.Backends)
+	}
+	if !results["webhook"] {
+		t.Errorf("expected delivered webhook result, got %+v", payload.Report.Backends)
+	}
+}
+
+func TestPostNotify_SlowDeliveryOutlastsServerWriteTimeout(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Idle.ThresholdSeconds = 1
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.URL = webhook.URL
+	cfg.Notification.DeliveryTimeoutSeconds = 5
+
+	origIdle := notifier.IdleDurationFunc
+	origSystem := notifier.SystemNotifyFunc
+	t.Cleanup(func() {
+		notifier.IdleDurationFunc = origIdle
+		notifier.SystemNotifyFunc = origSystem
+	})
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return 10 * time.Second, nil }
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error { return nil }
+
+	// The write timeout is shorter than the delivery; the handler must extend
+	// it to the delivery deadline or the client sees the connection drop.
+	ts := httptest.NewUnstartedServer(server.NewMux(cfg, slog.Default()))
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"world"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var payload notifyPayload
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || payload.Status != "ok" {
+		t.Errorf("expected delivered response, got %d %+v", resp.StatusCode, payload)
+	}
 }
 
 func TestPostNotify_LogsCorrelationAndRedactsPayload(t *testing.T) {
@@ -388,6 +680,57 @@ func TestGetNotify_WithParams(t *testing.T) {
 	}
 }
 
+func TestGetNotify_EventAndExitCode(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	var got notifier.SystemNotification
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error {
+		got = n
+		return nil
+	}
+
+	resp, err := ts.Client().Get(ts.URL + "/notify?message=tests&exit_code=1")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got.Icon != "dialog-error" {
+		t.Errorf("expected non-zero exit code to derive the error icon, got %q", got.Icon)
+	}
+}
+
+func TestGetNotify_InvalidQueryValues(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	for query, wantCode := range map[string]string{
+		"event=finished":          "invalid_event",
+		"exit_code=one":           "invalid_exit_code",
+		"priority=asap":           "invalid_priority",
+		"url=javascript:alert(1)": "invalid_link",
+		"duration_ms=soon":        "invalid_duration",
+	} {
+		resp, err := ts.Client().Get(ts.URL + "/notify?" + query)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.StatusCode)
+		}
+		payload := decodeErrorPayload(t, resp)
+		resp.Body.Close()
+		if payload.Code != wantCode {
+			t.Errorf("%s: expected %s code, got %q", query, wantCode, payload.Code)
+		}
+	}
+}
+
 func TestGetNotify_NoParams(t *testing.T) {
 	ts := setupTestServer(t, slog.Default())
 	defer ts.Close()
@@ -496,3 +839,289 @@ func TestRouting_WrongMethod_Health(t *testing.T) {
 		t.Errorf("expected 405, got %d", resp.StatusCode)
 	}
 }
+
+// --- wait_action long-poll ---
+
+func stubWaitAction(t *testing.T, fn func(ctx context.Context, n notifier.SystemNotification, actions []string) (string, error)) {
+	t.Helper()
+	orig := notifier.WaitActionFunc
+	t.Cleanup(func() { notifier.WaitActionFunc = orig })
+	notifier.WaitActionFunc = fn
+}
+
+func TestPostNotify_WaitActionReturnsChoice(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	var gotActions []string
+	var gotDeadline time.Duration
+	stubWaitAction(t, func(ctx context.Context, n notifier.SystemNotification, actions []string) (string, error) {
+		gotActions = actions
+		if deadline, ok := ctx.Deadline(); ok {
+			gotDeadline = time.Until(deadline)
+		}
+		return "deny", nil
+	})
+
+	resp, err := ts.Client().Post(
+		ts.URL+"/notify",
+		"application/json",
+		strings.NewReader(`{"body":"Allow rm?","wait_action":"allow,deny","wait_timeout":60}`),
+	)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || got["status"] != "ok" || got["action"] != "deny" {
+		t.Fatalf("response = %d %v, want 200 with action deny", resp.StatusCode, got)
+	}
+	if strings.Join(gotActions, ",") != "allow,deny" {
+		t.Errorf("actions = %v", gotActions)
+	}
+	if gotDeadline <= 50*time.Second || gotDeadline > 60*time.Second {
+		t.Errorf("wait deadline = %v, want about 60s", gotDeadline)
+	}
+}
+
+func TestGetNotify_WaitActionNoAnswer(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	for wantStatus, err := range map[string]error{
+		"timeout":   notifier.ErrActionTimeout,
+		"dismissed": notifier.ErrActionDismissed,
+	} {
+		stubWaitAction(t, func(context.Context, notifier.SystemNotification, []string) (string, error) {
+			return "", err
+		})
+
+		resp, reqErr := ts.Client().Get(ts.URL + "/notify?message=Allow&wait_action=allow,deny&wait_timeout=5")
+		if reqErr != nil {
+			t.Fatalf("request failed: %v", reqErr)
+		}
+		var got map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || got["status"] != wantStatus {
+			t.Errorf("response = %d %v, want 200 with status %s", resp.StatusCode, got, wantStatus)
+		}
+	}
+}
+
+func TestPostNotify_WaitActionUnavailable(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	stubWaitAction(t, func(context.Context, notifier.SystemNotification, []string) (string, error) {
+		return "", notifier.ErrWaitActionUnsupported
+	})
+
+	resp, err := ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"x","wait_action":"ok"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", resp.StatusCode)
+	}
+	if payload := decodeErrorPayload(t, resp); payload.Code != "wait_action_unavailable" {
+		t.Errorf("expected wait_action_unavailable code, got %q", payload.Code)
+	}
+}
+
+func TestNotify_InvalidWaitAction(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	stubWaitAction(t, func(context.Context, notifier.SystemNotification, []string) (string, error) {
+		t.Error("invalid wait requests must not show a prompt")
+		return "", nil
+	})
+
+	requests := []func() (*http.Response, error){
+		func() (*http.Response, error) {
+			return ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"x","wait_action":"allow,allow"}`))
+		},
+		func() (*http.Response, error) {
+			return ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"x","wait_action":"ok","wait_timeout":-1}`))
+		},
+		func() (*http.Response, error) {
+			return ts.Client().Get(ts.URL + "/notify?wait_action=ok&wait_timeout=soon")
+		},
+	}
+	for i, request := range requests {
+		resp, err := request()
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("request %d: expected 400, got %d", i, resp.StatusCode)
+		}
+		payload := decodeErrorPayload(t, resp)
+		resp.Body.Close()
+		if payload.Code != "invalid_wait_action" {
+			t.Errorf("request %d: expected invalid_wait_action code, got %q", i, payload.Code)
+		}
+	}
+}
+
+func TestNotify_RepliesRequireReplyTopic(t *testing.T) {
+	ts := setupTestServer(t, slog.Default())
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(ts.URL+"/notify", "application/json", strings.NewReader(`{"body":"x","replies":["allow","deny"]}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+	payload := decodeErrorPayload(t, resp)
+	if payload.Code != "invalid_replies" || !strings.Contains(payload.Message, "ntfy.reply_topic") {
+		t.Errorf("expected invalid_replies mentioning ntfy.reply_topic, got %+v", payload)
+	}
+}
+
+func TestPostNotify_DryRunSendsNothing(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Idle.ThresholdSeconds = 1
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Server = "http://127.0.0.1:1"
+	cfg.Ntfy.Topic = "test"
+
+	origIdle := notifier.IdleDurationFunc
+	origSystem := notifier.SystemNotifyFunc
+	t.Cleanup(func() {
+		notifier.IdleDurationFunc = origIdle
+		notifier.SystemNotifyFunc = origSystem
+	})
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return 10 * time.Second, nil }
+	systemCalls := 0
+	notifier.SystemNotifyFunc = func(_ context.Context, n notifier.SystemNotification) error {
+		systemCalls++
+		return nil
+	}
+
+	ts := httptest.NewServer(server.NewMux(cfg, slog.Default()))
+	defer ts.Close()
+
+	resp, err := ts.Client().Post(ts.URL+"/notify?dry_run=1", "application/json", strings.NewReader(`{"body":"world"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var payload notifyPayload
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if systemCalls != 0 || len(payload.Report.Backends) != 0 {
+		t.Fatalf("dry run sent something: %d system notifications, backends %+v", systemCalls, payload.Report.Backends)
+	}
+	plan := payload.Report.Plan
+	if plan == nil || !plan.Local || len(plan.Backends) != 1 || plan.Backends[0] != "ntfy" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if payload.Report.Tier != "idle" || len(payload.Report.Explain) == 0 {
+		t.Errorf("expected idle tier with reasons, got %+v", payload.Report)
+	}
+
+	resp2, err := ts.Client().Get(ts.URL + "/notify?message=x&dry_run=maybe")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid dry_run, got %d", resp2.StatusCode)
+	}
+	if got := decodeErrorPayload(t, resp2).Code; got != "invalid_dry_run" {
+		t.Errorf("expected invalid_dry_run code, got %q", got)
+	}
+}
+
+func TestPostNotify_LayersCallerProjectConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Idle.ThresholdSeconds = 1
+	cfg.Ntfy.Enabled = true
+	cfg.Ntfy.Topic = "test"
+
+	origIdle := notifier.IdleDurationFunc
+	origProcessState := notifier.ProcessFocusStateFunc
+	t.Cleanup(func() {
+		notifier.IdleDurationFunc = origIdle
+		notifier.ProcessFocusStateFunc = origProcessState
+	})
+	notifier.IdleDurationFunc = func(context.Context) (time.Duration, error) { return 10 * time.Second, nil }
+	notifier.ProcessFocusStateFunc = func(_ context.Context, pid int) focus.State { return focus.State{Focused: false, Known: true} }
+
+	client := t.TempDir()
+	project := "ntfy:\n  enabled: false\nteams:\n  enabled: true\n  webhook_url: https://example.com/workflow\n"
+	if err := os.WriteFile(filepath.Join(client, config.ProjectConfigName), []byte(project), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+	hostile := t.TempDir()
+	if err := os.WriteFile(filepath.Join(hostile, config.ProjectConfigName), []byte("ntfy:\n  token: \"cmd:curl evil\"\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+
+	ts := httptest.NewServer(server.NewMux(cfg, slog.Default()))
+	defer ts.Close()
+
+	// The test process stands in for the agent: its PID's working directory
+	// selects the project config.
+	post := func(request map[string]any) *http.Response {
+		t.Helper()
+		body, _ := json.Marshal(request)
+		resp, err := ts.Client().Post(ts.URL+"/notify?dry_run=1", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	planOf := func(resp *http.Response) []string {
+		t.Helper()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var payload notifyPayload
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if payload.Report.Plan == nil {
+			t.Fatal("expected a dry-run plan")
+		}
+		return payload.Report.Plan.Backends
+	}
+
+	t.Chdir(client)
+	if plan := planOf(post(map[string]any{"body": "world", "pid": os.Getpid()})); !slices.Equal(plan, []string{"teams"}) {
+		t.Fatalf("expected the project config to route to teams only, got %v", plan)
+	}
+
+	// A cwd in the body does not select a project config.
+	if plan := planOf(post(map[string]any{"body": "world", "cwd": hostile})); !slices.Equal(plan, []string{"ntfy"}) {
+		t.Fatalf("expected the user config without a pid, got %v", plan)
+	}
+
+	t.Chdir(hostile)
+	resp := post(map[string]any{"body": "world", "pid": os.Getpid()})
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for a rejected project config, got %d", resp.StatusCode)
+	}
+	if got := decodeErrorPayload(t, resp).Code; got != "invalid_project_config" {
+		t.Errorf("expected invalid_project_config code, got %q", got)
+	}
+}